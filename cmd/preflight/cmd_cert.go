@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/certcheck"
+)
+
+func newCertCmd(a *app) *cobra.Command {
+	var (
+		notExpired  bool
+		minDays     int
+		verifyChain bool
+		caFile      string
+		hostname    string
+		keyFile     string
+		issuer      string
+		subject     string
+		passwordEnv string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cert <file>",
+		Short: "Check a certificate file's expiry, names, key and chain",
+		Long: `Check a certificate file: PEM, DER or a PKCS#12 bundle. No openssl needed.
+
+The subject, SANs, issuer and days left are always listed.
+
+Examples:
+  preflight cert /certs/tls.crt --not-expired            # openssl x509 -checkend 0
+  preflight cert /certs/tls.crt --min-days 30            # renewed in time
+  preflight cert /certs/tls.crt --key-file /certs/tls.key --hostname api.example.com
+  preflight cert /certs/tls.crt --verify-chain --ca-file /certs/ca.crt
+  preflight cert /certs/keystore.p12 --password-env KEYSTORE_PASSWORD --not-expired`,
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().BoolVar(&notExpired, "not-expired", false, "certificate must be valid now")
+	cmd.Flags().IntVar(&minDays, "min-days", 0, "certificate must stay valid at least this many more days")
+	cmd.Flags().BoolVar(&verifyChain, "verify-chain", false, "certificate must chain to a trusted root")
+	cmd.Flags().StringVar(&caFile, "ca-file", "", "roots to trust for --verify-chain (default: the system's)")
+	cmd.Flags().StringVar(&hostname, "hostname", "", "certificate must be valid for this hostname or IP")
+	cmd.Flags().StringVar(&keyFile, "key-file", "", "private key that must match the certificate")
+	cmd.Flags().StringVar(&issuer, "issuer", "", "regex matched against the issuer's name")
+	cmd.Flags().StringVar(&subject, "subject", "", "regex matched against the subject's name")
+	cmd.Flags().StringVar(&passwordEnv, "password-env", "", "environment variable holding the PKCS#12 password")
+
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		if caFile != "" && !verifyChain {
+			return nil, errors.New("--ca-file requires --verify-chain")
+		}
+		if minDays < 0 {
+			return nil, fmt.Errorf("--min-days must not be negative, got %d", minDays)
+		}
+		if err := validateRegex("--issuer", issuer); err != nil {
+			return nil, err
+		}
+		if err := validateRegex("--subject", subject); err != nil {
+			return nil, err
+		}
+
+		return &certcheck.Check{
+			File:        args[0],
+			NotExpired:  notExpired,
+			MinDays:     minDays,
+			VerifyChain: verifyChain,
+			CAFile:      caFile,
+			Hostname:    hostname,
+			KeyFile:     keyFile,
+			Issuer:      issuer,
+			Subject:     subject,
+			// A password given as a flag would show in ps and in the shell's
+			// history, so it is only taken from the environment.
+			Password: os.Getenv(passwordEnv),
+			FS:       &certcheck.RealFileSystem{},
+		}, nil
+	})
+}
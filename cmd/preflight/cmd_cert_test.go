@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertCommand_Flags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no file", []string{"cert"}, "accepts 1 arg(s)"},
+		{"ca file without verify", []string{"cert", "tls.crt", "--ca-file", "ca.crt"}, "--ca-file requires --verify-chain"},
+		{"negative days", []string{"cert", "tls.crt", "--min-days", "-1"}, "--min-days must not be negative"},
+		{"bad regex", []string{"cert", "tls.crt", "--subject", "(["}, "invalid --subject regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeCommand(tt.args...)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCertCommand(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(0, 0, 10),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	certFile := writeTempFile(t, "tls.crt", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+
+	_, err = executeCommand("cert", certFile, "--not-expired", "--hostname", "localhost")
+	require.NoError(t, err)
+
+	_, err = executeCommand("cert", certFile, "--min-days", "30")
+	assert.ErrorIs(t, err, ErrCheckFailed)
+}
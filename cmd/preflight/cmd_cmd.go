@@ -11,61 +11,63 @@ import (
 	"github.com/vertti/preflight/pkg/version"
 )
 
-var (
-	minVersion     string
-	maxVersion     string
-	exactVersion   string
-	versionRange   string
-	matchPattern   string
-	versionCmd     string
-	cmdTimeout     time.Duration
-	versionPattern string
-)
+func newCmdCmd(a *app) *cobra.Command {
+	var (
+		minVersion     string
+		maxVersion     string
+		exactVersion   string
+		versionRange   string
+		matchPattern   string
+		versionCmd     string
+		timeout        time.Duration
+		versionPattern string
+	)
 
-var cmdCmd = &cobra.Command{
-	Use:   "cmd <command>",
-	Short: "Check that a command exists and can run",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runCmdCheck,
-}
+	cmd := &cobra.Command{
+		Use:   "cmd <command>",
+		Short: "Check that a command exists and can run",
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&minVersion, "min", "", "minimum version required (inclusive)")
+	cmd.Flags().StringVar(&maxVersion, "max", "", "maximum version allowed (exclusive)")
+	cmd.Flags().StringVar(&exactVersion, "exact", "", "exact version required")
+	cmd.Flags().StringVar(&versionRange, "range", "", "semver constraint (e.g., \">=1.0, <2.0\", \"^1.5\", \"~1.5\")")
+	cmd.Flags().StringVar(&matchPattern, "match", "", "regex pattern to match against version output")
+	cmd.Flags().StringVar(&versionPattern, "version-regex", "", "regex with capture group to extract version")
+	cmd.Flags().StringVar(&versionCmd, "version-cmd", "--version", "command to get version")
+	cmd.Flags().DurationVar(&timeout, "timeout", cmdcheck.DefaultTimeout, "timeout for version command")
 
-func init() {
-	cmdCmd.Flags().StringVar(&minVersion, "min", "", "minimum version required (inclusive)")
-	cmdCmd.Flags().StringVar(&maxVersion, "max", "", "maximum version allowed (exclusive)")
-	cmdCmd.Flags().StringVar(&exactVersion, "exact", "", "exact version required")
-	cmdCmd.Flags().StringVar(&versionRange, "range", "", "semver constraint (e.g., \">=1.0, <2.0\", \"^1.5\", \"~1.5\")")
-	cmdCmd.Flags().StringVar(&matchPattern, "match", "", "regex pattern to match against version output")
-	cmdCmd.Flags().StringVar(&versionPattern, "version-regex", "", "regex with capture group to extract version")
-	cmdCmd.Flags().StringVar(&versionCmd, "version-cmd", "--version", "command to get version")
-	cmdCmd.Flags().DurationVar(&cmdTimeout, "timeout", cmdcheck.DefaultTimeout, "timeout for version command")
-	rootCmd.AddCommand(cmdCmd)
-}
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		if err := validateRegex("--match", matchPattern); err != nil {
+			return nil, err
+		}
+		if err := validateRegex("--version-regex", versionPattern); err != nil {
+			return nil, err
+		}
 
-func runCmdCheck(_ *cobra.Command, args []string) error {
-	commandName := args[0]
+		c := &cmdcheck.Check{
+			Name:           args[0],
+			VersionArgs:    parseVersionArgs(versionCmd),
+			VersionRange:   versionRange,
+			MatchPattern:   matchPattern,
+			VersionPattern: versionPattern,
+			Timeout:        timeout,
+			Runner:         &cmdcheck.RealCmdRunner{},
+		}
 
-	c := &cmdcheck.Check{
-		Name:           commandName,
-		VersionArgs:    parseVersionArgs(versionCmd),
-		VersionRange:   versionRange,
-		MatchPattern:   matchPattern,
-		VersionPattern: versionPattern,
-		Timeout:        cmdTimeout,
-		Runner:         &cmdcheck.RealCmdRunner{},
-	}
-
-	var err error
-	if c.MinVersion, err = version.ParseOptional(minVersion); err != nil {
-		return fmt.Errorf("invalid --min version: %w", err)
-	}
-	if c.MaxVersion, err = version.ParseOptional(maxVersion); err != nil {
-		return fmt.Errorf("invalid --max version: %w", err)
-	}
-	if c.ExactVersion, err = version.ParseOptional(exactVersion); err != nil {
-		return fmt.Errorf("invalid --exact version: %w", err)
-	}
+		var err error
+		if c.MinVersion, err = version.ParseOptional(minVersion); err != nil {
+			return nil, fmt.Errorf("invalid --min version: %w", err)
+		}
+		if c.MaxVersion, err = version.ParseOptional(maxVersion); err != nil {
+			return nil, fmt.Errorf("invalid --max version: %w", err)
+		}
+		if c.ExactVersion, err = version.ParseOptional(exactVersion); err != nil {
+			return nil, fmt.Errorf("invalid --exact version: %w", err)
+		}
 
-	return runCheck(c)
+		return c, nil
+	})
 }
 
 func parseVersionArgs(s string) []string {
@@ -6,100 +6,99 @@ import (
 	"github.com/vertti/preflight/pkg/envcheck"
 )
 
-var (
-	envNotSet     bool
-	envAllowEmpty bool
-	envMatch      string
-	envExact      string
-	envOneOf      []string
-	envHideValue  bool
-	envMaskValue  bool
-	envStartsWith string
-	envEndsWith   string
-	envContains   string
-	envIsNumeric  bool
-	envIsPort     bool
-	envIsURL      bool
-	envIsJSON     bool
-	envIsBool     bool
-	envIsFile     bool
-	envIsDir      bool
-	envMinLen     int
-	envMaxLen     int
-	envMinValue   float64
-	envMaxValue   float64
-)
+func newEnvCmd(a *app) *cobra.Command {
+	var (
+		notSet     bool
+		allowEmpty bool
+		match      string
+		exact      string
+		oneOf      []string
+		hideValue  bool
+		maskValue  bool
+		startsWith string
+		endsWith   string
+		contains   string
+		isNumeric  bool
+		isPort     bool
+		isURL      bool
+		isJSON     bool
+		isBool     bool
+		isFile     bool
+		isDir      bool
+		minLen     int
+		maxLen     int
+		minValue   float64
+		maxValue   float64
+	)
 
-var envCmd = &cobra.Command{
-	Use:   "env <variable>",
-	Short: "Check that an environment variable is set",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runEnvCheck,
-}
+	cmd := &cobra.Command{
+		Use:   "env <variable>",
+		Short: "Check that an environment variable is set",
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.Flags().BoolVar(&notSet, "not-set", false, "verify variable is NOT defined")
+	cmd.Flags().BoolVar(&allowEmpty, "allow-empty", false, "pass if defined but empty")
+	cmd.Flags().StringVar(&match, "match", "", "regex pattern to match value")
+	cmd.Flags().StringVar(&exact, "exact", "", "exact value required")
+	cmd.Flags().StringSliceVar(&oneOf, "one-of", nil, "value must be one of these (comma-separated)")
+	cmd.Flags().BoolVar(&hideValue, "hide-value", false, "don't show value in output")
+	cmd.Flags().BoolVar(&maskValue, "mask-value", false, "show masked value (first/last 3 chars)")
+	cmd.Flags().StringVar(&startsWith, "starts-with", "", "value must start with this string")
+	cmd.Flags().StringVar(&endsWith, "ends-with", "", "value must end with this string")
+	cmd.Flags().StringVar(&contains, "contains", "", "value must contain this string")
+	cmd.Flags().BoolVar(&isNumeric, "is-numeric", false, "value must be a valid number")
+	cmd.Flags().BoolVar(&isPort, "is-port", false, "value must be valid TCP port (1-65535)")
+	cmd.Flags().BoolVar(&isURL, "is-url", false, "value must be valid URL")
+	cmd.Flags().BoolVar(&isJSON, "is-json", false, "value must be valid JSON")
+	cmd.Flags().BoolVar(&isBool, "is-bool", false, "value must be boolean (true/false/1/0/yes/no/on/off)")
+	cmd.Flags().BoolVar(&isFile, "is-file", false, "value must be path to existing file")
+	cmd.Flags().BoolVar(&isDir, "is-dir", false, "value must be path to existing directory")
+	cmd.Flags().IntVar(&minLen, "min-len", 0, "minimum string length")
+	cmd.Flags().IntVar(&maxLen, "max-len", 0, "maximum string length")
+	cmd.Flags().Float64Var(&minValue, "min-value", 0, "minimum numeric value (use with --is-numeric)")
+	cmd.Flags().Float64Var(&maxValue, "max-value", 0, "maximum numeric value (use with --is-numeric)")
 
-func init() {
-	envCmd.Flags().BoolVar(&envNotSet, "not-set", false, "verify variable is NOT defined")
-	envCmd.Flags().BoolVar(&envAllowEmpty, "allow-empty", false, "pass if defined but empty")
-	envCmd.Flags().StringVar(&envMatch, "match", "", "regex pattern to match value")
-	envCmd.Flags().StringVar(&envExact, "exact", "", "exact value required")
-	envCmd.Flags().StringSliceVar(&envOneOf, "one-of", nil, "value must be one of these (comma-separated)")
-	envCmd.Flags().BoolVar(&envHideValue, "hide-value", false, "don't show value in output")
-	envCmd.Flags().BoolVar(&envMaskValue, "mask-value", false, "show masked value (first/last 3 chars)")
-	envCmd.Flags().StringVar(&envStartsWith, "starts-with", "", "value must start with this string")
-	envCmd.Flags().StringVar(&envEndsWith, "ends-with", "", "value must end with this string")
-	envCmd.Flags().StringVar(&envContains, "contains", "", "value must contain this string")
-	envCmd.Flags().BoolVar(&envIsNumeric, "is-numeric", false, "value must be a valid number")
-	envCmd.Flags().BoolVar(&envIsPort, "is-port", false, "value must be valid TCP port (1-65535)")
-	envCmd.Flags().BoolVar(&envIsURL, "is-url", false, "value must be valid URL")
-	envCmd.Flags().BoolVar(&envIsJSON, "is-json", false, "value must be valid JSON")
-	envCmd.Flags().BoolVar(&envIsBool, "is-bool", false, "value must be boolean (true/false/1/0/yes/no/on/off)")
-	envCmd.Flags().BoolVar(&envIsFile, "is-file", false, "value must be path to existing file")
-	envCmd.Flags().BoolVar(&envIsDir, "is-dir", false, "value must be path to existing directory")
-	envCmd.Flags().IntVar(&envMinLen, "min-len", 0, "minimum string length")
-	envCmd.Flags().IntVar(&envMaxLen, "max-len", 0, "maximum string length")
-	envCmd.Flags().Float64Var(&envMinValue, "min-value", 0, "minimum numeric value (use with --is-numeric)")
-	envCmd.Flags().Float64Var(&envMaxValue, "max-value", 0, "maximum numeric value (use with --is-numeric)")
-	rootCmd.AddCommand(envCmd)
-}
+	return a.checkCommand(cmd, func(cmd *cobra.Command, args []string) (Checker, error) {
+		if err := validateRegex("--match", match); err != nil {
+			return nil, err
+		}
 
-func runEnvCheck(cmd *cobra.Command, args []string) error {
-	varName := args[0]
+		c := &envcheck.Check{
+			Name:       args[0],
+			NotSet:     notSet,
+			AllowEmpty: allowEmpty,
+			Match:      match,
+			OneOf:      oneOf,
+			HideValue:  hideValue,
+			MaskValue:  maskValue,
+			StartsWith: startsWith,
+			EndsWith:   endsWith,
+			Contains:   contains,
+			IsNumeric:  isNumeric,
+			IsPort:     isPort,
+			IsURL:      isURL,
+			IsJSON:     isJSON,
+			IsBool:     isBool,
+			IsFile:     isFile,
+			IsDir:      isDir,
+			MinLen:     minLen,
+			MaxLen:     maxLen,
+			Getter:     &envcheck.RealEnvGetter{},
+			Stater:     &envcheck.RealFileStater{},
+		}
 
-	c := &envcheck.Check{
-		Name:       varName,
-		NotSet:     envNotSet,
-		AllowEmpty: envAllowEmpty,
-		Match:      envMatch,
-		OneOf:      envOneOf,
-		HideValue:  envHideValue,
-		MaskValue:  envMaskValue,
-		StartsWith: envStartsWith,
-		EndsWith:   envEndsWith,
-		Contains:   envContains,
-		IsNumeric:  envIsNumeric,
-		IsPort:     envIsPort,
-		IsURL:      envIsURL,
-		IsJSON:     envIsJSON,
-		IsBool:     envIsBool,
-		IsFile:     envIsFile,
-		IsDir:      envIsDir,
-		MinLen:     envMinLen,
-		MaxLen:     envMaxLen,
-		Getter:     &envcheck.RealEnvGetter{},
-		Stater:     &envcheck.RealFileStater{},
-	}
-
-	// Only set these if the flags were explicitly provided. For --exact that is
-	// what makes `--exact ""` mean "must be empty" rather than "not given".
-	if cmd.Flags().Changed("exact") {
-		c.Exact = &envExact
-	}
-	if cmd.Flags().Changed("min-value") {
-		c.MinValue = &envMinValue
-	}
-	if cmd.Flags().Changed("max-value") {
-		c.MaxValue = &envMaxValue
-	}
+		// Only set these if the flags were explicitly provided. For --exact that is
+		// what makes `--exact ""` mean "must be empty" rather than "not given".
+		if cmd.Flags().Changed("exact") {
+			c.Exact = &exact
+		}
+		if cmd.Flags().Changed("min-value") {
+			c.MinValue = &minValue
+		}
+		if cmd.Flags().Changed("max-value") {
+			c.MaxValue = &maxValue
+		}
 
-	return runCheck(c)
+		return c, nil
+	})
 }
@@ -1,77 +1,84 @@
 package main
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 
 	"github.com/vertti/preflight/pkg/filecheck"
 )
 
-var (
-	fileDir           bool
-	fileSocket        bool
-	fileSymlink       bool
-	fileSymlinkTarget string
-	fileWritable      bool
-	fileExecutable    bool
-	fileNotEmpty      bool
-	fileMinSize       int64
-	fileMaxSize       int64
-	fileMatch         string
-	fileContains      string
-	fileHead          int64
-	fileMode          string
-	fileModeExact     string
-	fileOwner         int
-)
-
-var fileCmd = &cobra.Command{
-	Use:   "file <path>",
-	Short: "Check that a file or directory exists and meets requirements",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runFileCheck,
-}
-
-func init() {
-	fileCmd.Flags().BoolVar(&fileDir, "dir", false, "expect a directory")
-	fileCmd.Flags().BoolVar(&fileSocket, "socket", false, "expect a Unix socket")
-	fileCmd.Flags().BoolVar(&fileSymlink, "symlink", false, "expect a symbolic link")
-	fileCmd.Flags().StringVar(&fileSymlinkTarget, "symlink-target", "", "expected symlink target path")
-	fileCmd.Flags().BoolVar(&fileWritable, "writable", false, "check write permission")
-	fileCmd.Flags().BoolVar(&fileExecutable, "executable", false, "check execute permission")
-	fileCmd.Flags().BoolVar(&fileNotEmpty, "not-empty", false, "file must have size > 0")
-	fileCmd.Flags().Int64Var(&fileMinSize, "min-size", 0, "minimum file size in bytes")
-	fileCmd.Flags().Int64Var(&fileMaxSize, "max-size", 0, "maximum file size in bytes")
-	fileCmd.Flags().StringVar(&fileMatch, "match", "", "regex pattern to match content")
-	fileCmd.Flags().StringVar(&fileContains, "contains", "", "literal string to search in content")
-	fileCmd.Flags().Int64Var(&fileHead, "head", 0, "limit content read to first N bytes")
-	fileCmd.Flags().StringVar(&fileMode, "mode", "", "minimum permissions (e.g., 0644)")
-	fileCmd.Flags().StringVar(&fileModeExact, "mode-exact", "", "exact permissions required")
-	fileCmd.Flags().IntVar(&fileOwner, "owner", -1, "expected owner UID")
-	rootCmd.AddCommand(fileCmd)
-}
-
-func runFileCheck(_ *cobra.Command, args []string) error {
-	path := args[0]
+func newFileCmd(a *app) *cobra.Command {
+	var (
+		dir           bool
+		socket        bool
+		symlink       bool
+		symlinkTarget string
+		writable      bool
+		executable    bool
+		notEmpty      bool
+		minSize       int64
+		maxSize       int64
+		match         string
+		contains      string
+		head          int64
+		mode          string
+		modeExact     string
+		owner         int
+	)
 
-	c := &filecheck.Check{
-		Path:          path,
-		ExpectDir:     fileDir,
-		ExpectSocket:  fileSocket,
-		ExpectSymlink: fileSymlink,
-		SymlinkTarget: fileSymlinkTarget,
-		Writable:      fileWritable,
-		Executable:    fileExecutable,
-		NotEmpty:      fileNotEmpty,
-		MinSize:       fileMinSize,
-		MaxSize:       fileMaxSize,
-		Match:         fileMatch,
-		Contains:      fileContains,
-		Head:          fileHead,
-		Mode:          fileMode,
-		ModeExact:     fileModeExact,
-		Owner:         fileOwner,
-		FS:            &filecheck.RealFileSystem{},
+	cmd := &cobra.Command{
+		Use:   "file <path>",
+		Short: "Check that a file or directory exists and meets requirements",
+		Args:  cobra.ExactArgs(1),
 	}
+	cmd.Flags().BoolVar(&dir, "dir", false, "expect a directory")
+	cmd.Flags().BoolVar(&socket, "socket", false, "expect a Unix socket")
+	cmd.Flags().BoolVar(&symlink, "symlink", false, "expect a symbolic link")
+	cmd.Flags().StringVar(&symlinkTarget, "symlink-target", "", "expected symlink target path")
+	cmd.Flags().BoolVar(&writable, "writable", false, "check write permission")
+	cmd.Flags().BoolVar(&executable, "executable", false, "check execute permission")
+	cmd.Flags().BoolVar(&notEmpty, "not-empty", false, "file must have size > 0")
+	cmd.Flags().Int64Var(&minSize, "min-size", 0, "minimum file size in bytes")
+	cmd.Flags().Int64Var(&maxSize, "max-size", 0, "maximum file size in bytes")
+	cmd.Flags().StringVar(&match, "match", "", "regex pattern to match content")
+	cmd.Flags().StringVar(&contains, "contains", "", "literal string to search in content")
+	cmd.Flags().Int64Var(&head, "head", 0, "limit content read to first N bytes")
+	cmd.Flags().StringVar(&mode, "mode", "", "minimum permissions (e.g., 0644)")
+	cmd.Flags().StringVar(&modeExact, "mode-exact", "", "exact permissions required")
+	cmd.Flags().IntVar(&owner, "owner", -1, "expected owner UID")
+
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		if err := validateRegex("--match", match); err != nil {
+			return nil, err
+		}
+		for _, f := range []flagValue{{"--mode", mode}, {"--mode-exact", modeExact}} {
+			if f.value == "" {
+				continue
+			}
+			if _, err := filecheck.ParseOctalMode(f.value); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.name, err)
+			}
+		}
 
-	return runCheck(c)
+		return &filecheck.Check{
+			Path:          args[0],
+			ExpectDir:     dir,
+			ExpectSocket:  socket,
+			ExpectSymlink: symlink,
+			SymlinkTarget: symlinkTarget,
+			Writable:      writable,
+			Executable:    executable,
+			NotEmpty:      notEmpty,
+			MinSize:       minSize,
+			MaxSize:       maxSize,
+			Match:         match,
+			Contains:      contains,
+			Head:          head,
+			Mode:          mode,
+			ModeExact:     modeExact,
+			Owner:         owner,
+			FS:            &filecheck.RealFileSystem{},
+		}, nil
+	})
 }
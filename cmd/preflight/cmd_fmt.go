@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/preflightfile"
+)
+
+func newFmtCmd(a *app) *cobra.Command {
+	var (
+		file  string
+		check bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fmt",
+		Short: "Rewrite a .preflight file in canonical form",
+		Long: `Rewrite a .preflight file in canonical form: lines trimmed, arguments quoted
+one way, and the "preflight" a line may start with left out.
+
+Only the spelling changes; a line is rewritten only if it reads back as the
+same arguments. A directory, such as .preflight.d, has each of its files
+formatted. Files named by include are formatted by naming them with --file.
+
+Examples:
+  preflight fmt                           # the .preflight file run would find
+  preflight fmt --file deploy.preflight   # a file of your choosing
+  preflight fmt --check                   # in CI: fail if it needs formatting`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return a.fmtFile(cmd.OutOrStdout(), file, check)
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "path to .preflight file (default: search up from current directory)")
+	cmd.Flags().BoolVar(&check, "check", false, "list the files that need formatting and fail, without rewriting them")
+	return cmd
+}
+
+// fmtFile formats the .preflight file at file, or the one run would find, and
+// lists the files it changed. With check it only lists them, and fails if
+// there are any.
+func (a *app) fmtFile(w io.Writer, file string, check bool) error {
+	if a.collect != nil {
+		return errors.New("fmt cannot be used inside a .preflight file")
+	}
+
+	preflightPath, err := findPreflightFile(file)
+	if err != nil {
+		return err
+	}
+	changed, err := formatPreflightFile(preflightPath, !check)
+	if err != nil {
+		return err
+	}
+	for _, path := range changed {
+		if check {
+			_, _ = fmt.Fprintf(w, "%s needs formatting\n", path)
+		} else {
+			_, _ = fmt.Fprintf(w, "formatted %s\n", path)
+		}
+	}
+	if check && len(changed) > 0 {
+		return ErrCheckFailed
+	}
+	return nil
+}
+
+// formatPreflightFile formats path, or every file in it if it is a directory,
+// and returns the files whose formatting changed. They are rewritten only if
+// write is set, keeping their permissions.
+func formatPreflightFile(path string, write bool) (changed []string, err error) {
+	files := []string{path}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preflight directory: %w", err)
+		}
+		files = files[:0]
+		for _, e := range entries {
+			// The same files ParseLines reads from a directory.
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") || strings.HasSuffix(e.Name(), "~") {
+				continue
+			}
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return changed, fmt.Errorf("failed to read preflight file: %w", err)
+		}
+		data, err := os.ReadFile(file) //nolint:gosec // intentional: formatting the user's .preflight file
+		if err != nil {
+			return changed, fmt.Errorf("failed to read preflight file: %w", err)
+		}
+		formatted := preflightfile.Format(data)
+		if bytes.Equal(data, formatted) {
+			continue
+		}
+		changed = append(changed, file)
+		if !write {
+			continue
+		}
+		if err := os.WriteFile(file, formatted, info.Mode().Perm()); err != nil {
+			return changed, fmt.Errorf("failed to write preflight file: %w", err)
+		}
+	}
+	return changed, nil
+}
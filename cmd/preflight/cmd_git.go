@@ -6,18 +6,19 @@ import (
 	"github.com/vertti/preflight/pkg/gitcheck"
 )
 
-var (
-	gitClean         bool
-	gitNoUncommitted bool
-	gitNoUntracked   bool
-	gitBranch        string
-	gitTagMatch      string
-)
-
-var gitCmd = &cobra.Command{
-	Use:   "git",
-	Short: "Check git repository state (clean, branch, tags)",
-	Long: `Check git repository state.
+func newGitCmd(a *app) *cobra.Command {
+	var (
+		clean         bool
+		noUncommitted bool
+		noUntracked   bool
+		branch        string
+		tagMatch      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "git",
+		Short: "Check git repository state (clean, branch, tags)",
+		Long: `Check git repository state.
 
 Examples:
   preflight git --clean                    # No uncommitted or untracked files
@@ -25,43 +26,37 @@ Examples:
   preflight git --branch main              # Must be on 'main' branch
   preflight git --tag-match "v*"           # HEAD must have tag starting with 'v'
   preflight git --clean --branch release   # Combined checks`,
-	RunE: runGitCheck,
-}
-
-func init() {
-	gitCmd.Flags().BoolVar(&gitClean, "clean", false,
+	}
+	cmd.Flags().BoolVar(&clean, "clean", false,
 		"working directory must be clean (no uncommitted or untracked)")
-	gitCmd.Flags().BoolVar(&gitNoUncommitted, "no-uncommitted", false,
+	cmd.Flags().BoolVar(&noUncommitted, "no-uncommitted", false,
 		"no uncommitted (staged/modified) changes allowed")
-	gitCmd.Flags().BoolVar(&gitNoUntracked, "no-untracked", false,
+	cmd.Flags().BoolVar(&noUntracked, "no-untracked", false,
 		"no untracked files allowed")
-	gitCmd.Flags().StringVar(&gitBranch, "branch", "",
+	cmd.Flags().StringVar(&branch, "branch", "",
 		"must be on specified branch")
-	gitCmd.Flags().StringVar(&gitTagMatch, "tag-match", "",
+	cmd.Flags().StringVar(&tagMatch, "tag-match", "",
 		"HEAD must have tag matching glob pattern (e.g., 'v*')")
-	rootCmd.AddCommand(gitCmd)
-}
-
-func runGitCheck(_ *cobra.Command, _ []string) error {
-	// Require at least one check flag
-	if err := requireAtLeastOne(
-		flagSet{"--clean", gitClean},
-		flagSet{"--no-uncommitted", gitNoUncommitted},
-		flagSet{"--no-untracked", gitNoUntracked},
-		flagSet{"--branch", gitBranch != ""},
-		flagSet{"--tag-match", gitTagMatch != ""},
-	); err != nil {
-		return err
-	}
-
-	c := &gitcheck.Check{
-		Clean:         gitClean,
-		NoUncommitted: gitNoUncommitted,
-		NoUntracked:   gitNoUntracked,
-		Branch:        gitBranch,
-		TagMatch:      gitTagMatch,
-		Runner:        &gitcheck.RealGitRunner{},
-	}
 
-	return runCheck(c)
+	return a.checkCommand(cmd, func(*cobra.Command, []string) (Checker, error) {
+		// Require at least one check flag
+		if err := requireAtLeastOne(
+			flagSet{"--clean", clean},
+			flagSet{"--no-uncommitted", noUncommitted},
+			flagSet{"--no-untracked", noUntracked},
+			flagSet{"--branch", branch != ""},
+			flagSet{"--tag-match", tagMatch != ""},
+		); err != nil {
+			return nil, err
+		}
+
+		return &gitcheck.Check{
+			Clean:         clean,
+			NoUncommitted: noUncommitted,
+			NoUntracked:   noUntracked,
+			Branch:        branch,
+			TagMatch:      tagMatch,
+			Runner:        &gitcheck.RealGitRunner{},
+		}, nil
+	})
 }
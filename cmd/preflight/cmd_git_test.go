@@ -1,24 +1,18 @@
 package main
 
 import (
+	"strings"
 	"testing"
 )
 
 func TestRunGitCheck_RequiresFlag(t *testing.T) {
-	// Reset all flags to defaults
-	gitClean = false
-	gitNoUncommitted = false
-	gitNoUntracked = false
-	gitBranch = ""
-	gitTagMatch = ""
-
-	err := runGitCheck(nil, nil)
+	_, err := executeCommand("git")
 	if err == nil {
-		t.Error("expected error when no flags provided")
+		t.Fatal("expected error when no flags provided")
 	}
 
 	expectedPrefix := "at least one of"
-	if err != nil && len(err.Error()) >= len(expectedPrefix) && err.Error()[:len(expectedPrefix)] != expectedPrefix {
+	if !strings.HasPrefix(err.Error(), expectedPrefix) {
 		t.Errorf("error = %q, want prefix %q", err.Error(), expectedPrefix)
 	}
 }
@@ -6,20 +6,21 @@ import (
 	"github.com/vertti/preflight/pkg/hashcheck"
 )
 
-var (
-	hashSHA256       string
-	hashSHA384       string
-	hashSHA512       string
-	hashSHA1         string
-	hashMD5          string
-	hashAuto         string
-	hashChecksumFile string
-)
+func newHashCmd(a *app) *cobra.Command {
+	var (
+		sha256       string
+		sha384       string
+		sha512       string
+		sha1         string
+		md5          string
+		auto         string
+		checksumFile string
+	)
 
-var hashCmd = &cobra.Command{
-	Use:   "hash <file>",
-	Short: "Verify file checksum",
-	Long: `Verify a file's checksum against expected hash.
+	cmd := &cobra.Command{
+		Use:   "hash <file>",
+		Short: "Verify file checksum",
+		Long: `Verify a file's checksum against expected hash.
 
 Supported algorithms: SHA256, SHA384, SHA512, SHA1, MD5
 
@@ -29,71 +30,62 @@ Examples:
   preflight hash --auto abc123... /path/to/file      # auto-detect by length
   preflight hash --sha1 da39a3e...b3e /path/to/file  # legacy, use with caution
   preflight hash --checksum-file SHASUMS256.txt node-v20.tar.gz`,
-	Args: cobra.ExactArgs(1),
-	RunE: runHashCheck,
-}
-
-func init() {
-	hashCmd.Flags().StringVar(&hashSHA256, "sha256", "", "expected SHA256 hash")
-	hashCmd.Flags().StringVar(&hashSHA384, "sha384", "", "expected SHA384 hash")
-	hashCmd.Flags().StringVar(&hashSHA512, "sha512", "", "expected SHA512 hash")
-	hashCmd.Flags().StringVar(&hashSHA1, "sha1", "", "expected SHA1 hash (legacy, weak)")
-	hashCmd.Flags().StringVar(&hashMD5, "md5", "", "expected MD5 hash (legacy, weak)")
-	hashCmd.Flags().StringVar(&hashAuto, "auto", "", "expected hash (auto-detect algorithm by length)")
-	hashCmd.Flags().StringVar(&hashChecksumFile, "checksum-file", "", "verify against checksum file (GNU or BSD format)")
-
-	rootCmd.AddCommand(hashCmd)
-}
-
-func runHashCheck(_ *cobra.Command, args []string) error {
-	file := args[0]
-
-	// Validate exactly one hash flag is set
-	if err := requireExactlyOne(
-		flagValue{"--sha256", hashSHA256},
-		flagValue{"--sha384", hashSHA384},
-		flagValue{"--sha512", hashSHA512},
-		flagValue{"--sha1", hashSHA1},
-		flagValue{"--md5", hashMD5},
-		flagValue{"--auto", hashAuto},
-		flagValue{"--checksum-file", hashChecksumFile},
-	); err != nil {
-		return err
+		Args: cobra.ExactArgs(1),
 	}
+	cmd.Flags().StringVar(&sha256, "sha256", "", "expected SHA256 hash")
+	cmd.Flags().StringVar(&sha384, "sha384", "", "expected SHA384 hash")
+	cmd.Flags().StringVar(&sha512, "sha512", "", "expected SHA512 hash")
+	cmd.Flags().StringVar(&sha1, "sha1", "", "expected SHA1 hash (legacy, weak)")
+	cmd.Flags().StringVar(&md5, "md5", "", "expected MD5 hash (legacy, weak)")
+	cmd.Flags().StringVar(&auto, "auto", "", "expected hash (auto-detect algorithm by length)")
+	cmd.Flags().StringVar(&checksumFile, "checksum-file", "", "verify against checksum file (GNU or BSD format)")
 
-	// Determine algorithm and expected hash from flags
-	var algorithm hashcheck.HashAlgorithm
-	var expectedHash string
-	var autoDetect bool
-	switch {
-	case hashSHA256 != "":
-		algorithm = hashcheck.AlgorithmSHA256
-		expectedHash = hashSHA256
-	case hashSHA384 != "":
-		algorithm = hashcheck.AlgorithmSHA384
-		expectedHash = hashSHA384
-	case hashSHA512 != "":
-		algorithm = hashcheck.AlgorithmSHA512
-		expectedHash = hashSHA512
-	case hashSHA1 != "":
-		algorithm = hashcheck.AlgorithmSHA1
-		expectedHash = hashSHA1
-	case hashMD5 != "":
-		algorithm = hashcheck.AlgorithmMD5
-		expectedHash = hashMD5
-	case hashAuto != "":
-		autoDetect = true
-		expectedHash = hashAuto
-	}
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		// Validate exactly one hash flag is set
+		if err := requireExactlyOne(
+			flagValue{"--sha256", sha256},
+			flagValue{"--sha384", sha384},
+			flagValue{"--sha512", sha512},
+			flagValue{"--sha1", sha1},
+			flagValue{"--md5", md5},
+			flagValue{"--auto", auto},
+			flagValue{"--checksum-file", checksumFile},
+		); err != nil {
+			return nil, err
+		}
 
-	c := &hashcheck.Check{
-		File:         file,
-		ExpectedHash: expectedHash,
-		Algorithm:    algorithm,
-		AutoDetect:   autoDetect,
-		ChecksumFile: hashChecksumFile,
-		Opener:       &hashcheck.RealHashFileOpener{},
-	}
+		// Determine algorithm and expected hash from flags
+		var algorithm hashcheck.HashAlgorithm
+		var expectedHash string
+		var autoDetect bool
+		switch {
+		case sha256 != "":
+			algorithm = hashcheck.AlgorithmSHA256
+			expectedHash = sha256
+		case sha384 != "":
+			algorithm = hashcheck.AlgorithmSHA384
+			expectedHash = sha384
+		case sha512 != "":
+			algorithm = hashcheck.AlgorithmSHA512
+			expectedHash = sha512
+		case sha1 != "":
+			algorithm = hashcheck.AlgorithmSHA1
+			expectedHash = sha1
+		case md5 != "":
+			algorithm = hashcheck.AlgorithmMD5
+			expectedHash = md5
+		case auto != "":
+			autoDetect = true
+			expectedHash = auto
+		}
 
-	return runCheck(c)
+		return &hashcheck.Check{
+			File:         args[0],
+			ExpectedHash: expectedHash,
+			Algorithm:    algorithm,
+			AutoDetect:   autoDetect,
+			ChecksumFile: checksumFile,
+			Opener:       &hashcheck.RealHashFileOpener{},
+		}, nil
+	})
 }
@@ -10,71 +10,56 @@ import (
 	"github.com/vertti/preflight/pkg/httpclient"
 )
 
-var (
-	httpStatus          int
-	httpTimeout         time.Duration
-	httpMethod          string
-	httpHeaders         []string
-	httpInsecure        bool
-	httpRetry           int
-	httpRetryDelay      time.Duration
-	httpBody            string
-	httpBodyFile        string
-	httpContains        string
-	httpFollowRedirects bool
-	httpJSONPath        string
-)
+func newHTTPCmd(a *app) *cobra.Command {
+	var (
+		status          int
+		timeout         time.Duration
+		method          string
+		headers         []string
+		insecure        bool
+		body            string
+		bodyFile        string
+		contains        string
+		followRedirects bool
+		jsonPath        string
+	)
 
-var httpCmd = &cobra.Command{
-	Use:   "http <url>",
-	Short: "Check HTTP endpoint health",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runHTTPCheck,
-}
+	cmd := &cobra.Command{
+		Use:   "http <url>",
+		Short: "Check HTTP endpoint health",
+		Args:  cobra.ExactArgs(1),
+	}
 
-func init() {
 	// Must-have flags
-	httpCmd.Flags().IntVar(&httpStatus, "status", 200, "expected HTTP status code")
-	httpCmd.Flags().DurationVar(&httpTimeout, "timeout", 5*time.Second, "request timeout")
+	cmd.Flags().IntVar(&status, "status", 200, "expected HTTP status code")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "request timeout")
 
 	// Optional flags
-	httpCmd.Flags().IntVar(&httpRetry, "retry", 0, "retry count on failure")
-	httpCmd.Flags().DurationVar(&httpRetryDelay, "retry-delay", 1*time.Second, "delay between retries")
-	httpCmd.Flags().StringVar(&httpMethod, "method", "GET", "HTTP method (GET, POST, PUT, etc.)")
-	httpCmd.Flags().StringSliceVar(&httpHeaders, "header", nil, "custom header (key:value), can be repeated")
-	httpCmd.Flags().BoolVar(&httpInsecure, "insecure", false, "skip TLS certificate verification")
-	httpCmd.Flags().StringVar(&httpBody, "body", "", "request body string")
-	httpCmd.Flags().StringVar(&httpBodyFile, "body-file", "", "path to file containing request body")
-	httpCmd.Flags().StringVar(&httpContains, "contains", "", "response body must contain this string")
-	httpCmd.Flags().BoolVar(&httpFollowRedirects, "follow-redirects", false, "follow HTTP redirects (3xx)")
-	httpCmd.Flags().StringVar(&httpJSONPath, "json-path", "", "JSON path assertion (path or path=value)")
-
-	rootCmd.AddCommand(httpCmd)
-}
-
-func runHTTPCheck(_ *cobra.Command, args []string) error {
-	url := args[0]
-
-	headers := parseHeaders(httpHeaders)
-
-	c := &httpcheck.Check{
-		URL:             url,
-		ExpectedStatus:  httpStatus,
-		Timeout:         httpTimeout,
-		Method:          httpMethod,
-		Headers:         headers,
-		Insecure:        httpInsecure,
-		Retry:           httpRetry,
-		RetryDelay:      httpRetryDelay,
-		Body:            httpBody,
-		BodyFile:        httpBodyFile,
-		Contains:        httpContains,
-		FollowRedirects: httpFollowRedirects,
-		JSONPath:        httpJSONPath,
-		Client:          &httpclient.Real{Timeout: httpTimeout, Insecure: httpInsecure, FollowRedirects: httpFollowRedirects},
-	}
+	cmd.Flags().StringVar(&method, "method", "GET", "HTTP method (GET, POST, PUT, etc.)")
+	cmd.Flags().StringSliceVar(&headers, "header", nil, "custom header (key:value), can be repeated")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "skip TLS certificate verification")
+	cmd.Flags().StringVar(&body, "body", "", "request body string")
+	cmd.Flags().StringVar(&bodyFile, "body-file", "", "path to file containing request body")
+	cmd.Flags().StringVar(&contains, "contains", "", "response body must contain this string")
+	cmd.Flags().BoolVar(&followRedirects, "follow-redirects", false, "follow HTTP redirects (3xx)")
+	cmd.Flags().StringVar(&jsonPath, "json-path", "", "JSON path assertion (path or path=value)")
 
-	return runCheck(c)
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		return &httpcheck.Check{
+			URL:             args[0],
+			ExpectedStatus:  status,
+			Timeout:         timeout,
+			Method:          method,
+			Headers:         parseHeaders(headers),
+			Insecure:        insecure,
+			Body:            body,
+			BodyFile:        bodyFile,
+			Contains:        contains,
+			FollowRedirects: followRedirects,
+			JSONPath:        jsonPath,
+			Client:          &httpclient.Real{Timeout: timeout, Insecure: insecure, FollowRedirects: followRedirects},
+		}, nil
+	})
 }
 
 // parseHeaders converts ["key:value", ...] to map[string]string
@@ -8,50 +8,43 @@ import (
 	"github.com/vertti/preflight/pkg/jsoncheck"
 )
 
-var (
-	jsonHasKey string
-	jsonKey    string
-	jsonExact  string
-	jsonMatch  string
-)
-
-var jsonCmd = &cobra.Command{
-	Use:   "json <file>",
-	Short: "Validate JSON file and check values",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runJSONCheck,
-}
-
-func init() {
-	jsonCmd.Flags().StringVar(&jsonHasKey, "has-key", "", "check that key exists (dot notation for nested)")
-	jsonCmd.Flags().StringVar(&jsonKey, "key", "", "key to check value of (dot notation for nested)")
-	jsonCmd.Flags().StringVar(&jsonExact, "exact", "", "exact value required (requires --key)")
-	jsonCmd.Flags().StringVar(&jsonMatch, "match", "", "regex pattern for value (requires --key)")
-	rootCmd.AddCommand(jsonCmd)
-}
-
-func runJSONCheck(cmd *cobra.Command, args []string) error {
-	file := args[0]
+func newJSONCmd(a *app) *cobra.Command {
+	var hasKey, key, exact, match string
 
-	// Validate flag combinations
-	exactGiven := cmd.Flags().Changed("exact")
-	if (exactGiven || jsonMatch != "") && jsonKey == "" {
-		return errors.New("--exact and --match require --key to be set")
+	cmd := &cobra.Command{
+		Use:   "json <file>",
+		Short: "Validate JSON file and check values",
+		Args:  cobra.ExactArgs(1),
 	}
-
-	c := &jsoncheck.Check{
-		File:   file,
-		HasKey: jsonHasKey,
-		Key:    jsonKey,
-		Match:  jsonMatch,
-		FS:     &jsoncheck.RealFileSystem{},
-	}
-
-	// Only set if given, so `--exact ""` asserts the value is the empty string
-	// rather than reading as "no --exact".
-	if exactGiven {
-		c.Exact = &jsonExact
-	}
-
-	return runCheck(c)
+	cmd.Flags().StringVar(&hasKey, "has-key", "", "check that key exists (dot notation for nested)")
+	cmd.Flags().StringVar(&key, "key", "", "key to check value of (dot notation for nested)")
+	cmd.Flags().StringVar(&exact, "exact", "", "exact value required (requires --key)")
+	cmd.Flags().StringVar(&match, "match", "", "regex pattern for value (requires --key)")
+
+	return a.checkCommand(cmd, func(cmd *cobra.Command, args []string) (Checker, error) {
+		// Validate flag combinations
+		exactGiven := cmd.Flags().Changed("exact")
+		if (exactGiven || match != "") && key == "" {
+			return nil, errors.New("--exact and --match require --key to be set")
+		}
+		if err := validateRegex("--match", match); err != nil {
+			return nil, err
+		}
+
+		c := &jsoncheck.Check{
+			File:   args[0],
+			HasKey: hasKey,
+			Key:    key,
+			Match:  match,
+			FS:     &jsoncheck.RealFileSystem{},
+		}
+
+		// Only set if given, so `--exact ""` asserts the value is the empty string
+		// rather than reading as "no --exact".
+		if exactGiven {
+			c.Exact = &exact
+		}
+
+		return c, nil
+	})
 }
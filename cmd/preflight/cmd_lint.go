@@ -0,0 +1,162 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/preflightfile"
+)
+
+// lintOptions are the flags of `preflight lint`.
+type lintOptions struct {
+	file string // explicit .preflight path; empty means search for one
+	fix  bool   // format the file before checking it
+
+	interpolate bool     // expand ${VAR} even without the file's header
+	set         []string // key=value variables for interpolation
+	args        []string // positional parameters, as run would be given them
+}
+
+func newLintCmd(a *app) *cobra.Command {
+	var opts lintOptions
+
+	cmd := &cobra.Command{
+		Use:   "lint [args...]",
+		Short: "Check a .preflight file for mistakes without running it",
+		Long: `Check every line of a .preflight file, and the files it includes, without
+running any check.
+
+Each line is parsed by the command it names, exactly as run would parse it, so
+an unknown command or flag, a bad flag value, a missing or conflicting flag, or
+an unterminated quote is reported with the file and line it is on.
+
+Examples:
+  preflight lint                          # the .preflight file run would find
+  preflight lint --file deploy.preflight  # a file of your choosing
+  preflight lint --fix                    # format it first, as fmt does
+  preflight lint --file checks.pf staging # with ${1} set, as run would have it`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.args = args
+			return a.lint(cmd.OutOrStdout(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.file, "file", "", "path to .preflight file (default: search up from current directory)")
+	cmd.Flags().BoolVar(&opts.fix, "fix", false, "format the file in place before checking it")
+	cmd.Flags().BoolVar(&opts.interpolate, "interpolate", false, "expand ${VAR} in the file (also enabled by a '"+preflightfile.InterpolateHeader+"' line)")
+	cmd.Flags().StringArrayVar(&opts.set, "set", nil, "set a variable for interpolation (key=value), can be repeated")
+	return cmd
+}
+
+// lint reports every line of a .preflight file that run would reject.
+//
+// A typo on line 40 used to surface only when line 40 ran, which for a
+// startup gate means in production. Every line is now parsed the way run
+// parses it, into the check it describes, and the check is thrown away.
+func (a *app) lint(w io.Writer, opts lintOptions) error {
+	if a.collect != nil {
+		return errors.New("lint cannot be used inside a .preflight file")
+	}
+
+	preflightPath, err := findPreflightFile(opts.file)
+	if err != nil {
+		return err
+	}
+	if opts.fix {
+		changed, err := formatPreflightFile(preflightPath, true)
+		if err != nil {
+			return err
+		}
+		for _, path := range changed {
+			_, _ = fmt.Fprintf(w, "formatted %s\n", path)
+		}
+	}
+
+	vars, err := parseSetFlags(opts.set)
+	if err != nil {
+		return err
+	}
+	var problems []*preflightfile.LineError
+	lines, err := preflightfile.ParseLines(preflightPath, preflightfile.Options{
+		Interpolate: opts.interpolate,
+		Vars:        vars,
+		Args:        opts.args,
+		OnLineError: func(le *preflightfile.LineError) { problems = append(problems, le) },
+	})
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if err := lintCommand(l.Command); err != nil {
+			problems = append(problems, &preflightfile.LineError{File: l.File, Number: l.Number, Err: err})
+		}
+	}
+
+	if len(problems) == 0 {
+		_, _ = fmt.Fprintf(w, "%s: no problems\n", preflightPath)
+		return nil
+	}
+	slices.SortStableFunc(problems, func(x, y *preflightfile.LineError) int {
+		return cmp.Or(cmp.Compare(x.File, y.File), cmp.Compare(x.Number, y.Number))
+	})
+	for _, p := range problems {
+		_, _ = fmt.Fprintf(w, "%s:%d: %s\n", p.File, p.Number, oneLine(p.Err))
+	}
+	_, _ = fmt.Fprintf(w, "\n%d %s in %s\n", len(problems), plural(len(problems), "problem", "problems"), preflightPath)
+	// Every problem has been listed.
+	return ErrCheckFailed
+}
+
+// lintCommand parses a command from a .preflight file into its check, and
+// returns what run would have reported instead of running it.
+func lintCommand(command string) error {
+	// ParseLines has already split this line, so this cannot fail.
+	parts, err := preflightfile.Fields(command)
+	if err != nil {
+		return err
+	}
+	args := parts[1:]
+	if len(args) > 0 && args[0] == preflightfile.WarnModifier {
+		args = args[1:]
+	}
+
+	parsed, err := parseLine(args, io.Discard)
+	if err != nil {
+		return err
+	}
+	if parsed.checker == nil {
+		// run counts such a line as passing, which is never what it was for.
+		return errors.New("runs no check")
+	}
+	return nil
+}
+
+// oneLine returns err's message on a single line, so each problem is one line
+// of output. cobra puts its "Did you mean this?" suggestions on lines of their
+// own; they go in parentheses after the rest.
+func oneLine(err error) string {
+	var parts []string
+	for line := range strings.Lines(err.Error()) {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	if len(parts) < 2 {
+		return strings.Join(parts, "")
+	}
+	return parts[0] + " (" + strings.Join(parts[1:], " ") + ")"
+}
+
+// plural returns one or many, as n calls for.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	t.Run("a clean file", func(t *testing.T) {
+		path := writeTempFile(t, ".preflight", "env HOME\n[db]\n@slow warn tcp db:5432 --retry 3\nfile /etc/hosts --mode 0644\n")
+		out, err := executeCommand("lint", "--file", path)
+		require.NoError(t, err)
+		assert.Equal(t, path+": no problems\n", out)
+	})
+
+	t.Run("every problem is reported with its line", func(t *testing.T) {
+		path := writeTempFile(t, ".preflight", `env HOME
+tpc db:5432
+env HOME --bogus
+env X --match '(['
+file /etc/hosts --mode 0o600
+hash f.tgz --sha256 abc --md5 def
+resource --min-disk 10Q
+env X --exact "hello
+run
+--help
+`)
+		out, err := executeCommand("lint", "--file", path)
+		require.ErrorIs(t, err, ErrCheckFailed)
+		for _, want := range []string{
+			path + `:2: unknown command "tpc" for "preflight" (Did you mean this? tcp tls)`,
+			path + ":3: unknown flag: --bogus",
+			path + ":4: invalid --match regex: ",
+			path + `:5: --mode: invalid octal mode "0o600"`,
+			path + ":6: only one of --sha256,",
+			path + `:7: invalid --min-disk value: invalid size format: "10Q"`,
+			path + ":8: unterminated double quote",
+			path + ":9: run cannot be used inside a .preflight file",
+			path + ":10: runs no check",
+			"9 problems in " + path,
+		} {
+			assert.Contains(t, out, want)
+		}
+		assert.NotContains(t, out, ":1:")
+	})
+
+	t.Run("nothing runs", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "rendered")
+		path := writeTempFile(t, ".preflight", "template "+writeTempFile(t, "in.tmpl", "x")+" "+marker+"\n")
+		_, err := executeCommand("lint", "--file", path)
+		require.NoError(t, err)
+		assert.NoFileExists(t, marker)
+	})
+
+	t.Run("variables", func(t *testing.T) {
+		path := writeTempFile(t, ".preflight", "# preflight: interpolate\ntcp ${LINT_TEST_HOST}:5432\n")
+		out, err := executeCommand("lint", "--file", path)
+		require.ErrorIs(t, err, ErrCheckFailed)
+		assert.Contains(t, out, path+":2: LINT_TEST_HOST is not set")
+
+		_, err = executeCommand("lint", "--file", path, "--set", "LINT_TEST_HOST=db")
+		require.NoError(t, err)
+	})
+
+	t.Run("positional arguments", func(t *testing.T) {
+		path := writeTempFile(t, ".preflight", "# preflight: interpolate\ntcp ${1}:5432\n")
+		out, err := executeCommand("lint", "--file", path)
+		require.ErrorIs(t, err, ErrCheckFailed)
+		assert.Contains(t, out, path+":2: 1 is not set")
+
+		out, err = executeCommand("lint", "--file", path, "db")
+		require.NoError(t, err)
+		assert.Equal(t, path+": no problems\n", out)
+	})
+
+	t.Run("--fix formats first", func(t *testing.T) {
+		path := writeTempFile(t, ".preflight", "preflight env   \"HOME\"\n")
+		out, err := executeCommand("lint", "--fix", "--file", path)
+		require.NoError(t, err)
+		assert.Contains(t, out, "formatted "+path)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "env HOME\n", string(data))
+	})
+}
+
+func TestFmt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".preflight")
+	require.NoError(t, os.WriteFile(path, []byte("preflight tcp \"db:5432\"\n"), 0o640))
+
+	out, err := executeCommand("fmt", "--check", "--file", path)
+	require.ErrorIs(t, err, ErrCheckFailed)
+	assert.Equal(t, path+" needs formatting\n", out)
+
+	out, err = executeCommand("fmt", "--file", path)
+	require.NoError(t, err)
+	assert.Equal(t, "formatted "+path+"\n", out)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "tcp db:5432\n", string(data))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm(), "permissions are kept")
+
+	out, err = executeCommand("fmt", "--check", "--file", path)
+	require.NoError(t, err)
+	assert.Empty(t, out)
+
+	t.Run("a directory has each file formatted", func(t *testing.T) {
+		dropIn := filepath.Join(t.TempDir(), ".preflight.d")
+		require.NoError(t, os.MkdirAll(dropIn, 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dropIn, "10-base"), []byte("  env HOME\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dropIn, ".hidden"), []byte("  env HOME\n"), 0o600))
+
+		out, err := executeCommand("fmt", "--file", dropIn)
+		require.NoError(t, err)
+		assert.Equal(t, "formatted "+filepath.Join(dropIn, "10-base")+"\n", out)
+	})
+}
+
+func TestOneLine(t *testing.T) {
+	assert.Equal(t, "unknown flag: --bogus", oneLine(errors.New("unknown flag: --bogus")))
+	assert.Equal(t, "a (b c)", oneLine(errors.New("a\n\nb\n\tc\n")))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/pkgcheck"
+)
+
+func newPkgCmd(a *app) *cobra.Command {
+	var (
+		manager      string
+		autoDetect   bool
+		minVersion   string
+		maxVersion   string
+		exactVersion string
+		versionRange string
+		path         string
+		root         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "pkg <name>",
+		Short: "Check that a package is installed",
+		Long: `Check that a package is installed, by reading the package manager's database.
+No shell, dpkg, apk, pip or npm needed.
+
+apt and apk versions are compared as dpkg and apk compare them, epoch,
+revision and -rN release included, so --min and --exact can name a build.
+pip and npm versions are compared by their major, minor and patch numbers.
+
+Examples:
+  preflight pkg nginx --manager apt                 # dpkg -s nginx
+  preflight pkg curl --auto-detect --min 7.88       # apt or apk, from /etc/os-release
+  preflight pkg openssl --manager apt --min 3.0.15-1~deb12u1
+  preflight pkg libc6:arm64 --manager apt           # one architecture
+  preflight pkg requests --manager pip --range '>=2.28, <3'
+  preflight pkg django --manager pip --path /opt/venv/lib/python3.12/site-packages
+  preflight pkg express --manager npm --path ./node_modules`,
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&manager, "manager", "", "package manager: "+strings.Join(pkgcheck.Managers, ", "))
+	cmd.Flags().BoolVar(&autoDetect, "auto-detect", false, "detect apt or apk from /etc/os-release")
+	cmd.Flags().StringVar(&minVersion, "min", "", "minimum version required (inclusive)")
+	cmd.Flags().StringVar(&maxVersion, "max", "", "maximum version allowed (exclusive)")
+	cmd.Flags().StringVar(&exactVersion, "exact", "", "exact version required")
+	cmd.Flags().StringVar(&versionRange, "range", "", "pip and npm: semver constraint (e.g., \">=1.0, <2.0\", \"^1.5\", \"~1.5\")")
+	cmd.Flags().StringVar(&path, "path", "", "pip: site-packages directory, npm: node_modules directory (default: the usual places)")
+	cmd.Flags().StringVar(&root, "root", "/", "directory the system's files are under, e.g. a mounted image")
+
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		switch {
+		case manager == "" && !autoDetect:
+			return nil, errors.New("one of --manager, --auto-detect is required")
+		case manager != "" && autoDetect:
+			return nil, errors.New("only one of --manager, --auto-detect can be specified")
+		case manager != "" && !slices.Contains(pkgcheck.Managers, manager):
+			return nil, fmt.Errorf("invalid --manager %q: expected one of %s", manager, strings.Join(pkgcheck.Managers, ", "))
+		}
+		languagePkg := manager == pkgcheck.ManagerPip || manager == pkgcheck.ManagerNpm
+		if path != "" && !languagePkg {
+			return nil, errors.New("--path can only be used with --manager pip or npm")
+		}
+		if versionRange != "" && !languagePkg {
+			return nil, errors.New("--range can only be used with --manager pip or npm; use --min and --max")
+		}
+		for _, f := range []flagValue{{"--min", minVersion}, {"--max", maxVersion}, {"--exact", exactVersion}} {
+			if f.value == "" {
+				continue
+			}
+			if err := pkgcheck.ValidateVersion(manager, f.value); err != nil {
+				return nil, fmt.Errorf("invalid %s version: %w", f.name, err)
+			}
+		}
+
+		return &pkgcheck.Check{
+			Name:         args[0],
+			Manager:      manager,
+			Path:         path,
+			Root:         root,
+			MinVersion:   minVersion,
+			MaxVersion:   maxVersion,
+			ExactVersion: exactVersion,
+			VersionRange: versionRange,
+		}, nil
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPkgCommand_Flags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no manager", []string{"pkg", "git"}, "one of --manager, --auto-detect is required"},
+		{"both", []string{"pkg", "git", "--manager", "apt", "--auto-detect"}, "only one of --manager, --auto-detect can be specified"},
+		{"unknown manager", []string{"pkg", "git", "--manager", "yum"}, `invalid --manager "yum": expected one of apt, apk, pip, npm`},
+		{"path for a system package", []string{"pkg", "git", "--manager", "apt", "--path", "/x"}, "--path can only be used with --manager pip or npm"},
+		{"bad version", []string{"pkg", "git", "--manager", "apt", "--min", "two"}, "invalid --min version"},
+		{"bad pip version", []string{"pkg", "requests", "--manager", "pip", "--exact", "2.28-1"}, "invalid --exact version"},
+		{"range for a system package", []string{"pkg", "git", "--auto-detect", "--range", "^2"}, "--range can only be used with --manager pip or npm"},
+		{"no name", []string{"pkg", "--manager", "apt"}, "accepts 1 arg(s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeCommand(tt.args...)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPkgCommand(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"etc/os-release":       "ID=alpine\n",
+		"lib/apk/db/installed": "P:curl\nV:8.11.1-r0\n",
+	} {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	_, err := executeCommand("pkg", "curl", "--auto-detect", "--root", root, "--min", "8.0")
+	require.NoError(t, err)
+
+	_, err = executeCommand("pkg", "curl", "--manager", "apk", "--root", root, "--min", "9")
+	require.ErrorIs(t, err, ErrCheckFailed)
+
+	_, err = executeCommand("pkg", "curl", "--manager", "apk", "--root", root, "--exact", "8.11.1-r0")
+	require.NoError(t, err, "the release is part of the version")
+
+	_, err = executeCommand("pkg", "curl", "--manager", "apk", "--root", root, "--min", "8.11.1-r1")
+	require.ErrorIs(t, err, ErrCheckFailed)
+
+	_, err = executeCommand("pkg", "wget", "--manager", "apk", "--root", root)
+	assert.ErrorIs(t, err, ErrCheckFailed)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/vertti/preflight/pkg/cmdcheck"
+	"github.com/vertti/preflight/pkg/plugincheck"
+)
+
+// pluginPathAnnotation holds the executable a plugin's command runs.
+const pluginPathAnnotation = "preflight.plugin.path"
+
+// lookPlugin finds a plugin's executable, can be overridden for testing.
+var lookPlugin = func(name string) (string, error) {
+	return plugincheck.Find(name, (&cmdcheck.RealCmdRunner{}).LookPath)
+}
+
+// newPluginCmd builds the command a plugin runs as. It is named once the
+// plugin is found, and gets the same retry flags and --warn as any check.
+func newPluginCmd(a *app) *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Short: "Run a check provided by a plugin",
+		Args:  cobra.ArbitraryArgs,
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", plugincheck.DefaultTimeout, "how long the plugin may run")
+
+	return a.checkCommand(cmd, func(cmd *cobra.Command, args []string) (Checker, error) {
+		return &plugincheck.Check{
+			Name:    cmd.Name(),
+			Path:    cmd.Annotations[pluginPathAnnotation],
+			Args:    args,
+			Timeout: timeout,
+			Runner:  &cmdcheck.RealCmdRunner{},
+		}, nil
+	})
+}
+
+// addPlugin registers a command for the plugin args name, if they name one,
+// and returns the args to execute in their place.
+//
+// Like git and kubectl, `preflight foo` runs preflight-foo from PATH when foo
+// is not a command of preflight's own; a plugin cannot shadow one. Everything
+// after the plugin's name is the plugin's, flags included, and preflight's own
+// flags, such as --warn and --retry, go before it. The name is moved to the
+// front, where cobra looks for it even past flags the root does not know, and
+// a "--" keeps cobra from parsing the plugin's arguments.
+func (a *app) addPlugin(args []string) []string {
+	cmd := newPluginCmd(a)
+	i := commandIndex(args, a.root.PersistentFlags(), cmd.Flags())
+	if i < 0 || isBuiltinCommand(a.root, args[i]) {
+		return args
+	}
+	path, err := lookPlugin(args[i])
+	if err != nil {
+		// Left for cobra to report as an unknown command.
+		return args
+	}
+
+	cmd.Use = args[i] + " [args...]"
+	cmd.Annotations = map[string]string{pluginPathAnnotation: path}
+	a.root.AddCommand(cmd)
+	return slices.Concat(args[i:i+1], args[:i], []string{"--"}, args[i+1:])
+}
+
+// commandIndex returns the index of the first argument that is neither a flag
+// nor a flag's value, which is where a command's name goes, or -1 if there is
+// none. flagSets say which flags take a value; an unknown flag is taken not to.
+func commandIndex(args []string, flagSets ...*pflag.FlagSet) int {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return -1
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			return i
+		case strings.Contains(arg, "="):
+			continue
+		}
+		if takesValue(arg, flagSets) {
+			i++
+		}
+	}
+	return -1
+}
+
+// takesValue reports whether the flag arg names is followed by its value.
+func takesValue(arg string, flagSets []*pflag.FlagSet) bool {
+	for _, fs := range flagSets {
+		var f *pflag.Flag
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			f = fs.Lookup(arg[2:])
+		case len(arg) == 2:
+			f = fs.ShorthandLookup(arg[1:])
+		}
+		if f != nil {
+			return f.NoOptDefVal == ""
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// installPlugin puts a preflight-<name> shell script on PATH for the test.
+func installPlugin(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins in these tests are shell scripts")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "preflight-"+name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755)) //nolint:gosec // the test plugin must be executable
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// licensePlugin passes unless given --fail, which preflight must not take for
+// a flag of its own.
+const licensePlugin = `if [ "$1" = "--fail" ]; then
+  echo '{"name": "license: acme", "status": "FAIL", "details": ["no seats left"]}'
+else
+  echo '{"name": "license: acme", "status": "OK", "details": ["args: '"$*"'"]}'
+fi
+`
+
+func TestPluginCommand(t *testing.T) {
+	installPlugin(t, "license", licensePlugin)
+
+	t.Run("runs the plugin", func(t *testing.T) {
+		_, err := executeCommand("license", "--seats", "10")
+		require.NoError(t, err)
+	})
+
+	t.Run("flags after the name are the plugin's", func(t *testing.T) {
+		_, err := executeCommand("license", "--fail")
+		require.ErrorIs(t, err, ErrCheckFailed)
+	})
+
+	t.Run("preflight's flags go before the name", func(t *testing.T) {
+		_, err := executeCommand("--output", "json", "--warn", "license", "--fail")
+		require.NoError(t, err)
+		_, err = executeCommand("--retry", "1", "--retry-delay", "1ms", "license", "--fail")
+		require.ErrorIs(t, err, ErrCheckFailed)
+	})
+
+	t.Run("a .preflight line can name it", func(t *testing.T) {
+		rec, reported := runOneLine("license", "--seats", "10")
+		assert.True(t, reported)
+		assert.Equal(t, check.StatusOK, rec.Status)
+		assert.Equal(t, "license: acme", rec.Name)
+		assert.Equal(t, "license", rec.Type)
+		assert.Equal(t, []string{"args: --seats 10"}, rec.Details)
+
+		rec, _ = runOneLine("warn", "license", "--fail")
+		assert.Equal(t, check.StatusWarn, rec.Status)
+	})
+
+	t.Run("an unknown command is still an error", func(t *testing.T) {
+		_, err := executeCommand("no-such-plugin")
+		require.ErrorContains(t, err, "unknown command")
+	})
+}
+
+func TestPluginCommand_CannotShadowABuiltin(t *testing.T) {
+	installPlugin(t, "env", `echo '{"status": "FAIL"}'`)
+
+	_, err := executeCommand("env", "PATH")
+	require.NoError(t, err)
+}
+
+func TestIsKnownSubcommand_Plugins(t *testing.T) {
+	installPlugin(t, "license", licensePlugin)
+
+	assert.True(t, isKnownSubcommand("license"), "a file named license must not be run as a script")
+	assert.False(t, isKnownSubcommand("no-such-plugin"))
+	assert.Equal(t, []string{"preflight", "license"}, transformArgsForHashbang([]string{"preflight", "license"}, func(string) bool { return true }))
+}
+
+func TestCommandIndex(t *testing.T) {
+	a := newApp()
+	flags := newPluginCmd(a).Flags()
+	for _, tc := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"license", "--seats", "10"}, 0},
+		{[]string{"--output", "json", "license"}, 2},
+		{[]string{"--output=json", "-v", "license"}, 2},
+		{[]string{"--warn", "--retry", "3", "license"}, 3},
+		{[]string{"--verbose"}, -1},
+		{nil, -1},
+	} {
+		assert.Equal(t, tc.want, commandIndex(tc.args, a.root.PersistentFlags(), flags), "%q", tc.args)
+	}
+}
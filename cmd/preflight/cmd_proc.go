@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/proccheck"
+	"github.com/vertti/preflight/pkg/usercheck"
+)
+
+func newProcCmd(a *app) *cobra.Command {
+	var (
+		running   string
+		pidFile   string
+		user      string
+		minCount  int
+		maxCount  int
+		listening int
+		procRoot  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "proc",
+		Short: "Check that a process is running",
+		Long: `Check that a process is running, by reading /proc. No ps or pgrep needed.
+
+--running matches the process name and its full command line. preflight's own
+process, and the shell running it, never match, so no '[f]ilebeat' trick is
+needed.
+
+Examples:
+  preflight proc --running filebeat                 # ps aux | grep '[f]ilebeat'
+  preflight proc --pid-file /var/run/nginx.pid      # PID file exists and is not stale
+  preflight proc --running nginx --listening 80     # nginx holds the listening socket
+  preflight proc --running 'nginx: worker' --min-count 4
+  preflight proc --running cron --max-count 0       # nothing left running`,
+		Args: cobra.NoArgs,
+	}
+	cmd.Flags().StringVar(&running, "running", "", "regex matched against the process name and command line")
+	cmd.Flags().StringVar(&pidFile, "pid-file", "", "file holding the PID of a process that must be running")
+	cmd.Flags().StringVar(&user, "user", "", "process must run as this user, by name or uid")
+	cmd.Flags().IntVar(&minCount, "min-count", 1, "at least this many processes must match")
+	cmd.Flags().IntVar(&maxCount, "max-count", 0, "at most this many processes may match (default: no limit)")
+	cmd.Flags().IntVar(&listening, "listening", 0, "a matching process must listen on this TCP port")
+	cmd.Flags().StringVar(&procRoot, "proc-root", proccheck.DefaultRoot, "where the process filesystem is mounted")
+
+	return a.checkCommand(cmd, func(cmd *cobra.Command, _ []string) (Checker, error) {
+		if err := requireAtLeastOne(
+			flagSet{"--running", running != ""},
+			flagSet{"--pid-file", pidFile != ""},
+			flagSet{"--user", user != ""},
+			flagSet{"--listening", cmd.Flags().Changed("listening")},
+		); err != nil {
+			return nil, err
+		}
+		if err := validateRegex("--running", running); err != nil {
+			return nil, err
+		}
+		if cmd.Flags().Changed("listening") && (listening < 1 || listening > 65535) {
+			return nil, fmt.Errorf("--listening must be a port between 1 and 65535, got %d", listening)
+		}
+
+		c := &proccheck.Check{
+			Running:   running,
+			PIDFile:   pidFile,
+			User:      user,
+			Listening: listening,
+			Proc:      &proccheck.RealProcFS{Root: procRoot},
+			Users:     &usercheck.RealUserLookup{},
+		}
+		// Only set if given, so --max-count 0 can mean "none" and lower the
+		// default --min-count with it.
+		if cmd.Flags().Changed("min-count") {
+			if minCount < 0 {
+				return nil, fmt.Errorf("--min-count must not be negative, got %d", minCount)
+			}
+			c.MinCount = &minCount
+		}
+		if cmd.Flags().Changed("max-count") {
+			if maxCount < 0 {
+				return nil, fmt.Errorf("--max-count must not be negative, got %d", maxCount)
+			}
+			c.MaxCount = &maxCount
+		}
+		if c.MinCount != nil && c.MaxCount != nil && minCount > maxCount {
+			return nil, fmt.Errorf("--min-count %d is more than --max-count %d", minCount, maxCount)
+		}
+		return c, nil
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcCommand_Flags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no criteria", []string{"proc"}, "at least one of --running, --pid-file, --user, --listening is required"},
+		{"bad regex", []string{"proc", "--running", "(["}, "invalid --running regex"},
+		{"port out of range", []string{"proc", "--listening", "70000"}, "--listening must be a port between 1 and 65535"},
+		{"port zero", []string{"proc", "--listening", "0"}, "--listening must be a port"},
+		{"negative count", []string{"proc", "--running", "x", "--max-count", "-1"}, "--max-count must not be negative"},
+		{"min above max", []string{"proc", "--running", "x", "--min-count", "3", "--max-count", "2"}, "--min-count 3 is more than --max-count 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeCommand(tt.args...)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestProcCommand(t *testing.T) {
+	if _, err := os.Stat("/proc/self/status"); err != nil {
+		t.Skip("no /proc on this system")
+	}
+	pidFile := filepath.Join(t.TempDir(), "self.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())), 0o600))
+
+	_, err := executeCommand("proc", "--pid-file", pidFile)
+	require.NoError(t, err)
+
+	// After proc, --user is the process's user rather than exec mode's.
+	_, err = executeCommand("proc", "--pid-file", pidFile, "--user", strconv.Itoa(os.Geteuid()))
+	require.NoError(t, err)
+
+	_, err = executeCommand("proc", "--pid-file", pidFile, "--user", strconv.Itoa(os.Geteuid()+1))
+	assert.ErrorIs(t, err, ErrCheckFailed)
+
+	_, err = executeCommand("proc", "--running", "^no-such-process-anywhere$", "--max-count", "0")
+	require.NoError(t, err, "--max-count 0 asserts that nothing runs")
+
+	_, err = executeCommand("proc", "--running", "^no-such-process-anywhere$")
+	assert.ErrorIs(t, err, ErrCheckFailed)
+}
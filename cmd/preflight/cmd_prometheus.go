@@ -9,82 +9,60 @@ import (
 	"github.com/vertti/preflight/pkg/promcheck"
 )
 
-var (
-	promQuery      string
-	promMin        float64
-	promMax        float64
-	promExact      float64
-	promMinSet     bool
-	promMaxSet     bool
-	promExactSet   bool
-	promTimeout    time.Duration
-	promRetry      int
-	promRetryDelay time.Duration
-	promInsecure   bool
-	promHeaders    []string
-)
-
-var prometheusCmd = &cobra.Command{
-	Use:   "prometheus <url>",
-	Short: "Check Prometheus metric value",
-	Long: `Query a Prometheus server and validate metric values against thresholds.
+func newPrometheusCmd(a *app) *cobra.Command {
+	var (
+		query    string
+		minValue float64
+		maxValue float64
+		exact    float64
+		timeout  time.Duration
+		insecure bool
+		headers  []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prometheus <url>",
+		Short: "Check Prometheus metric value",
+		Long: `Query a Prometheus server and validate metric values against thresholds.
 
 Examples:
   preflight prometheus http://prometheus:9090 --query 'up{job="myapp"}' --exact 1
   preflight prometheus http://prometheus:9090 --query 'error_rate' --max 0.05
   preflight prometheus http://prometheus:9090 --query 'request_count' --min 100`,
-	Args: cobra.ExactArgs(1),
-	RunE: runPrometheusCheck,
-}
-
-func init() {
-	prometheusCmd.Flags().StringVar(&promQuery, "query", "", "PromQL query (required)")
-	_ = prometheusCmd.MarkFlagRequired("query")
-
-	prometheusCmd.Flags().Float64Var(&promMin, "min", 0, "minimum value (fail if below)")
-	prometheusCmd.Flags().Float64Var(&promMax, "max", 0, "maximum value (fail if above)")
-	prometheusCmd.Flags().Float64Var(&promExact, "exact", 0, "exact value match")
-
-	prometheusCmd.Flags().DurationVar(&promTimeout, "timeout", 5*time.Second, "request timeout")
-	prometheusCmd.Flags().IntVar(&promRetry, "retry", 0, "retry count on failure")
-	prometheusCmd.Flags().DurationVar(&promRetryDelay, "retry-delay", 1*time.Second, "delay between retries")
-	prometheusCmd.Flags().BoolVar(&promInsecure, "insecure", false, "skip TLS certificate verification")
-	prometheusCmd.Flags().StringSliceVar(&promHeaders, "header", nil, "custom header (key:value), can be repeated")
-
-	rootCmd.AddCommand(prometheusCmd)
-}
-
-func runPrometheusCheck(cmd *cobra.Command, args []string) error {
-	url := args[0]
-
-	// Track which flags were explicitly set
-	promMinSet = cmd.Flags().Changed("min")
-	promMaxSet = cmd.Flags().Changed("max")
-	promExactSet = cmd.Flags().Changed("exact")
-
-	headers := parseHeaders(promHeaders)
-
-	c := &promcheck.Check{
-		URL:        url,
-		Query:      promQuery,
-		Timeout:    promTimeout,
-		Retry:      promRetry,
-		RetryDelay: promRetryDelay,
-		Insecure:   promInsecure,
-		Headers:    headers,
-		Client:     &httpclient.Real{Timeout: promTimeout, Insecure: promInsecure},
-	}
-
-	// Only set threshold pointers if flags were explicitly provided
-	if promMinSet {
-		c.Min = &promMin
-	}
-	if promMaxSet {
-		c.Max = &promMax
+		Args: cobra.ExactArgs(1),
 	}
-	if promExactSet {
-		c.Exact = &promExact
-	}
-
-	return runCheck(c)
+	cmd.Flags().StringVar(&query, "query", "", "PromQL query (required)")
+	_ = cmd.MarkFlagRequired("query")
+
+	cmd.Flags().Float64Var(&minValue, "min", 0, "minimum value (fail if below)")
+	cmd.Flags().Float64Var(&maxValue, "max", 0, "maximum value (fail if above)")
+	cmd.Flags().Float64Var(&exact, "exact", 0, "exact value match")
+
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "request timeout")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "skip TLS certificate verification")
+	cmd.Flags().StringSliceVar(&headers, "header", nil, "custom header (key:value), can be repeated")
+
+	return a.checkCommand(cmd, func(cmd *cobra.Command, args []string) (Checker, error) {
+		c := &promcheck.Check{
+			URL:      args[0],
+			Query:    query,
+			Timeout:  timeout,
+			Insecure: insecure,
+			Headers:  parseHeaders(headers),
+			Client:   &httpclient.Real{Timeout: timeout, Insecure: insecure},
+		}
+
+		// Only set threshold pointers if flags were explicitly provided
+		if cmd.Flags().Changed("min") {
+			c.Min = &minValue
+		}
+		if cmd.Flags().Changed("max") {
+			c.Max = &maxValue
+		}
+		if cmd.Flags().Changed("exact") {
+			c.Exact = &exact
+		}
+
+		return c, nil
+	})
 }
@@ -8,17 +8,18 @@ import (
 	"github.com/vertti/preflight/pkg/resourcecheck"
 )
 
-var (
-	resourceMinDisk   string
-	resourceMinMemory string
-	resourceMinCPUs   int
-	resourcePath      string
-)
+func newResourceCmd(a *app) *cobra.Command {
+	var (
+		minDisk   string
+		minMemory string
+		minCPUs   int
+		path      string
+	)
 
-var resourceCmd = &cobra.Command{
-	Use:   "resource",
-	Short: "Check system resources (disk, memory, CPU)",
-	Long: `Check system resources meet minimum requirements.
+	cmd := &cobra.Command{
+		Use:   "resource",
+		Short: "Check system resources (disk, memory, CPU)",
+		Long: `Check system resources meet minimum requirements.
 
 Examples:
   preflight resource --min-disk 10G                    # At least 10GB free disk
@@ -26,54 +27,50 @@ Examples:
   preflight resource --min-memory 2G                   # At least 2GB memory
   preflight resource --min-cpus 4                      # At least 4 CPU cores
   preflight resource --min-disk 10G --min-memory 2G   # Combined checks`,
-	RunE: runResourceCheck,
-}
-
-func init() {
-	resourceCmd.Flags().StringVar(&resourceMinDisk, "min-disk", "",
+	}
+	cmd.Flags().StringVar(&minDisk, "min-disk", "",
 		"minimum free disk space (e.g., 10G, 500M)")
-	resourceCmd.Flags().StringVar(&resourceMinMemory, "min-memory", "",
+	cmd.Flags().StringVar(&minMemory, "min-memory", "",
 		"minimum available memory (e.g., 2G, 512M)")
-	resourceCmd.Flags().IntVar(&resourceMinCPUs, "min-cpus", 0,
+	cmd.Flags().IntVar(&minCPUs, "min-cpus", 0,
 		"minimum number of CPU cores")
-	resourceCmd.Flags().StringVar(&resourcePath, "path", "",
+	cmd.Flags().StringVar(&path, "path", "",
 		"path for disk space check (default: current directory)")
-	rootCmd.AddCommand(resourceCmd)
-}
 
-func runResourceCheck(_ *cobra.Command, _ []string) error {
-	// Require at least one check flag
-	if err := requireAtLeastOne(
-		flagSet{"--min-disk", resourceMinDisk != ""},
-		flagSet{"--min-memory", resourceMinMemory != ""},
-		flagSet{"--min-cpus", resourceMinCPUs != 0},
-	); err != nil {
-		return err
-	}
+	return a.checkCommand(cmd, func(*cobra.Command, []string) (Checker, error) {
+		// Require at least one check flag
+		if err := requireAtLeastOne(
+			flagSet{"--min-disk", minDisk != ""},
+			flagSet{"--min-memory", minMemory != ""},
+			flagSet{"--min-cpus", minCPUs != 0},
+		); err != nil {
+			return nil, err
+		}
 
-	c := &resourcecheck.Check{
-		Path:    resourcePath,
-		MinCPUs: resourceMinCPUs,
-		Checker: &resourcecheck.RealResourceChecker{},
-	}
+		c := &resourcecheck.Check{
+			Path:    path,
+			MinCPUs: minCPUs,
+			Checker: &resourcecheck.RealResourceChecker{},
+		}
 
-	// Parse disk size
-	if resourceMinDisk != "" {
-		size, err := resourcecheck.ParseSize(resourceMinDisk)
-		if err != nil {
-			return fmt.Errorf("invalid --min-disk value: %w", err)
+		// Parse disk size
+		if minDisk != "" {
+			size, err := resourcecheck.ParseSize(minDisk)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --min-disk value: %w", err)
+			}
+			c.MinDisk = size
 		}
-		c.MinDisk = size
-	}
 
-	// Parse memory size
-	if resourceMinMemory != "" {
-		size, err := resourcecheck.ParseSize(resourceMinMemory)
-		if err != nil {
-			return fmt.Errorf("invalid --min-memory value: %w", err)
+		// Parse memory size
+		if minMemory != "" {
+			size, err := resourcecheck.ParseSize(minMemory)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --min-memory value: %w", err)
+			}
+			c.MinMemory = size
 		}
-		c.MinMemory = size
-	}
 
-	return runCheck(c)
+		return c, nil
+	})
 }
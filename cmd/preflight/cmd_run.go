@@ -1,115 +1,440 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/output"
 	"github.com/vertti/preflight/pkg/preflightfile"
 )
 
-var runFile string
+// runOptions are the flags of `preflight run`.
+type runOptions struct {
+	file  string // explicit .preflight path; empty means search for one
+	junit string // where to write a JUnit report, if anywhere
 
-var runCmd = &cobra.Command{
-	Use:   "run",
-	Short: "Run checks from a .preflight file",
-	Args:  cobra.NoArgs,
-	RunE:  runPreflightFile,
+	metricsFile string // where to write Prometheus metrics, if anywhere
+	jobs        int    // how many checks may run at once; below 1 means 1
+
+	tags     []string // run only lines carrying one of these
+	skipTags []string // and none of these
+
+	interpolate bool     // expand ${VAR} even without the file's header
+	set         []string // key=value variables for interpolation
+	args        []string // positional parameters, from a hashbang script's command line
 }
 
-func init() {
-	runCmd.Flags().StringVar(&runFile, "file", "", "path to .preflight file (default: search up from current directory)")
-	rootCmd.AddCommand(runCmd)
+func newRunCmd(a *app) *cobra.Command {
+	var opts runOptions
+
+	cmd := &cobra.Command{
+		Use:   "run [args...]",
+		Short: "Run checks from a .preflight file",
+		Args:  cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.jobs < 1 {
+				return fmt.Errorf("--jobs must be at least 1, got %d", opts.jobs)
+			}
+			opts.args = args
+			return a.runPreflightFile(cmd.Context(), opts)
+		},
+	}
+	addFileFlags(cmd, &opts)
+	cmd.Flags().StringVar(&opts.junit, "junit", "", "also write results as a JUnit XML report to this path")
+	cmd.Flags().StringVar(&opts.metricsFile, "metrics-file", "", "also write results as Prometheus metrics to this path, for a node_exporter textfile collector")
+	return cmd
 }
 
-func runPreflightFile(cmd *cobra.Command, args []string) error {
-	wd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
+// addFileFlags adds the flags that pick a .preflight file, the lines in it, and
+// how they run. run and serve share them.
+func addFileFlags(cmd *cobra.Command, opts *runOptions) {
+	cmd.Flags().StringVar(&opts.file, "file", "", "path to .preflight file (default: search up from current directory)")
+	cmd.Flags().IntVarP(&opts.jobs, "jobs", "j", 1, "number of checks to run at once")
+	cmd.Flags().StringSliceVar(&opts.tags, "tag", nil, "run only lines in this section or with this @tag, can be repeated")
+	cmd.Flags().StringSliceVar(&opts.skipTags, "skip-tag", nil, "skip lines in this section or with this @tag, can be repeated")
+	cmd.Flags().BoolVar(&opts.interpolate, "interpolate", false, "expand ${VAR} in the file (also enabled by a '"+preflightfile.InterpolateHeader+"' line)")
+	cmd.Flags().StringArrayVar(&opts.set, "set", nil, "set a variable for interpolation (key=value), can be repeated")
+}
+
+func (a *app) runPreflightFile(ctx context.Context, opts runOptions) error {
+	// A line is turned into a check by running its command in collect mode,
+	// and run is not a check. Running it would mean a file that names itself
+	// recursing until the stack ran out.
+	if a.collect != nil {
+		return errors.New("run cannot be used inside a .preflight file")
 	}
 
-	preflightPath, err := preflightfile.FindFile(wd, runFile)
+	preflightPath, commands, err := loadPreflightFile(opts)
 	if err != nil {
 		return err
 	}
 
-	commands, err := preflightfile.ParseFile(preflightPath)
+	exitCode, err := a.runCommands(ctx, preflightPath, commands, opts)
 	if err != nil {
 		return err
 	}
+	if exitCode != 0 {
+		// The summary has already said what failed.
+		return ErrCheckFailed
+	}
+	return nil
+}
 
-	executable, err := os.Executable()
+// loadPreflightFile finds the file opts names, or searches for one, and
+// returns the commands on the lines opts selects.
+func loadPreflightFile(opts runOptions) (preflightPath string, commands []string, err error) {
+	preflightPath, err = findPreflightFile(opts.file)
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+		return "", nil, err
 	}
 
-	exitCode, err := runCommands(commands, executable, spawn)
+	vars, err := parseSetFlags(opts.set)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
-	if exitCode != 0 {
-		os.Exit(exitCode)
+	lines, err := preflightfile.ParseLines(preflightPath, preflightfile.Options{
+		Interpolate: opts.interpolate,
+		Vars:        vars,
+		Args:        opts.args,
+	})
+	if err != nil {
+		return "", nil, err
 	}
-	return nil
+	lines, err = preflightfile.Select(lines, opts.tags, opts.skipTags)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", preflightPath, err)
+	}
+	commands = make([]string, 0, len(lines))
+	for _, l := range lines {
+		commands = append(commands, l.Command)
+	}
+	return preflightPath, commands, nil
 }
 
-// spawn runs one command with the parent's streams attached.
-func spawn(name string, args []string) error {
-	cmd := exec.Command(name, args...) //nolint:gosec // intentional: executing commands from .preflight file
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	return cmd.Run()
+// findPreflightFile returns file, or searches up from the working directory
+// for a .preflight file when file is empty.
+func findPreflightFile(file string) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return preflightfile.FindFile(wd, file)
+}
+
+// parseSetFlags converts ["key=value", ...] to a map. Unlike --header, a
+// malformed entry is an error: a variable that silently went unset would fail
+// the parse anyway, further from the typo.
+func parseSetFlags(set []string) (map[string]string, error) {
+	vars := make(map[string]string, len(set))
+	for _, kv := range set {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("--set %q: want key=value", kv)
+		}
+		vars[key] = value
+	}
+	return vars, nil
 }
 
 // runCommands executes every .preflight command, returning the exit code to
-// propagate. run is injected so the loop — including the substitution below —
-// can be tested without spawning processes or calling os.Exit.
+// propagate.
 //
 // A failing check no longer ends the run. The point of a preflight is a single
 // pass that reports everything wrong with an environment; stopping at the first
 // failure turned that into fix one, rerun, find the next.
-func runCommands(commands []string, executable string, run func(name string, args []string) error) (exitCode int, err error) {
-	ran, failed := 0, 0
+//
+// Every line runs in this process. It used to re-execute preflight once per
+// line, which cost a fork/exec and a cobra startup each time; a line now
+// becomes a Checker on a command tree of its own and runs here, with the same
+// output and the same exit code the child would have produced.
+//
+// With opts.jobs above 1, up to that many checks run at once. A startup gate
+// waiting on eight services serially took the sum of every timeout; now it
+// takes the longest. Lines are handed out in file order and their output is
+// written from this goroutine alone, also in file order, so concurrent checks
+// can never interleave on the terminal.
+//
+// Records are kept for every line. JSON prints them together as one report once
+// the file is done, JUnit writes them to a file, and text output renders each
+// as it arrives.
+func (a *app) runCommands(ctx context.Context, preflightPath string, commands []string, opts runOptions) (exitCode int, err error) {
+	jsonOutput := a.format == output.FormatJSON
+
+	lines, err := prepareLines(commands)
+	if err != nil {
+		return 0, err
+	}
+	startLines(ctx, lines, max(opts.jobs, 1))
+
+	records := []output.Record{}
+	for _, l := range lines {
+		<-l.done
+		a.checkRan = true
+
+		rec, reported := l.record()
+		records = append(records, rec)
+
+		// Help for a line belongs to the reader, but not in the middle of a
+		// JSON document.
+		if jsonOutput {
+			_, _ = os.Stderr.Write(l.out.Bytes())
+		} else {
+			_, _ = os.Stdout.Write(l.out.Bytes())
+		}
+		_, _ = os.Stderr.Write(l.errOut.Bytes())
+		if !jsonOutput && reported {
+			a.printResult(rec.Result())
+		}
+	}
+
+	if opts.junit != "" {
+		if err := writeJUnitReport(opts.junit, preflightPath, records); err != nil {
+			return 0, err
+		}
+	}
+
+	if opts.metricsFile != "" {
+		if err := writeMetricsFile(opts.metricsFile, records, time.Now()); err != nil {
+			return 0, err
+		}
+	}
+
+	report := output.NewReport(preflightPath, records)
+	if jsonOutput {
+		if err := output.PrintJSON(report); err != nil {
+			return 0, err
+		}
+	}
+
+	if !jsonOutput {
+		output.PrintTable(records)
+		printSummary(report.Ran, report.Failed, report.Warned)
+	}
+	if report.Failed > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// printSummary ends a text run with the counts, when there is anything to
+// count. A warning alone leaves the exit code at 0 but still deserves a line.
+func printSummary(ran, failed, warned int) {
+	switch {
+	case failed > 0 && warned > 0:
+		fmt.Printf("\n%d of %d checks failed, %d warned\n", failed, ran, warned)
+	case failed > 0:
+		fmt.Printf("\n%d of %d checks failed\n", failed, ran)
+	case warned > 0:
+		fmt.Printf("\n%d of %d checks warned\n", warned, ran)
+	}
+}
+
+// lineRun is one .preflight line on its way through a run. Everything a line
+// would print is held here until its turn comes, which is what keeps output in
+// file order when checks finish out of it.
+type lineRun struct {
+	args   []string
+	warn   bool // the line began with the warn modifier
+	parsed parsedLine
+	err    error        // a usage mistake; the line ran no check
+	out    bytes.Buffer // what parsing printed for the reader, such as --help
+	errOut bytes.Buffer // usage diagnostics
 
+	result check.Result
+	done   chan struct{} // closed once the line has nothing left to do
+}
+
+// prepareLines parses every command into a line ready to start.
+func prepareLines(commands []string) ([]*lineRun, error) {
+	var lines []*lineRun
 	for _, command := range commands {
 		// Quote-aware, so an argument may contain a space. ParseFile has already
 		// accepted these lines, so a failure here means the two disagree.
 		parts, err := preflightfile.Fields(command)
 		if err != nil {
-			return 0, fmt.Errorf("failed to parse command %q: %w", command, err)
+			return nil, fmt.Errorf("failed to parse command %q: %w", command, err)
 		}
 		if len(parts) == 0 {
 			continue
 		}
 
-		// ParseFile guarantees the first token is exactly "preflight", so this
-		// always fires. Substituting unconditionally means a parser change can
-		// never turn a .preflight line into a path to some other binary.
-		parts[0] = executable
-
-		ran++
-		if err := run(parts[0], parts[1:]); err != nil {
-			// Failing to spawn is not a check result. The remaining lines would
-			// fail the same way, so stop rather than repeat the same error.
-			var exitError *exec.ExitError
-			if !errors.As(err, &exitError) {
-				return 0, fmt.Errorf("failed to execute command %q: %w", command, err)
+		// ParseFile guarantees the first token is exactly "preflight". It is
+		// dropped rather than looked at: a line can only ever name one of
+		// preflight's own commands, so no parser change can turn it into a
+		// path to some other binary.
+		lines = append(lines, prepareLine(parts[1:]))
+	}
+	return lines, nil
+}
+
+// prepareLine parses a line into its check. Parsing runs cobra, which is not
+// safe to share, so this happens one line at a time before anything runs.
+//
+// A line starting with "warn" is the same check with its failure downgraded,
+// as --warn would do. A usage mistake on such a line still fails: the file is
+// broken, not the environment.
+func prepareLine(args []string) *lineRun {
+	l := &lineRun{args: args, done: make(chan struct{})}
+	if len(args) > 0 && args[0] == preflightfile.WarnModifier {
+		l.warn, args = true, args[1:]
+	}
+	l.parsed, l.err = parseLine(args, &l.out)
+	if l.err != nil {
+		reportExecuteError(l.parsed.cmd, l.err, &l.errOut)
+	}
+	if l.warn && l.parsed.checker != nil {
+		l.parsed.checker = check.Advisory{Checker: l.parsed.checker}
+	}
+	return l
+}
+
+// startLines runs every line's check on at most jobs goroutines. Lines are
+// handed out in file order, so with one job they run exactly in sequence.
+//
+// Once ctx is done, the lines still waiting are not started. Each fails as not
+// run, so the report still accounts for every line in the file.
+func startLines(ctx context.Context, lines []*lineRun, jobs int) {
+	queue := make(chan *lineRun)
+	go func() {
+		defer close(queue)
+		for _, l := range lines {
+			if l.parsed.checker == nil {
+				close(l.done)
+				continue
 			}
-			failed++
+			queue <- l
 		}
-		checkRan = true
+	}()
+
+	for range jobs {
+		go func() {
+			for l := range queue {
+				if ctx.Err() != nil {
+					l.result = check.NotRun(preflightfile.Join(l.args), context.Cause(ctx))
+				} else {
+					l.result = check.Run(ctx, l.parsed.checker)
+				}
+				close(l.done)
+			}
+		}()
 	}
+}
 
-	if failed > 0 {
-		fmt.Printf("\n%d of %d checks failed\n", failed, ran)
-		// The child is always preflight itself, which only ever exits 0 or 1,
-		// so there is no other code worth forwarding.
-		return 1, nil
+// record returns the line's record. reported says whether it came from a
+// check; a line that never reached one still needs an entry, or it would
+// vanish from the report and the counts.
+func (l *lineRun) record() (rec output.Record, reported bool) {
+	name := preflightfile.Join(l.args)
+	switch {
+	case l.err != nil:
+		return output.Record{
+			Name:    name,
+			Type:    lineType(l.parsed.cmd),
+			Status:  check.StatusFail,
+			Details: []string{},
+			Error:   l.err.Error(),
+		}, false
+	case l.parsed.checker == nil:
+		return output.Record{Name: name, Type: lineType(l.parsed.cmd), Status: check.StatusOK, Details: []string{}}, false
 	}
+	return output.NewRecord(l.result, l.parsed.cmd.Name()), true
+}
 
-	return 0, nil
+// parsedLine is a .preflight line turned into the check it describes.
+type parsedLine struct {
+	cmd     *cobra.Command // the command the line named, for usage on error
+	checker Checker        // nil when the line ran no check, such as --help
+}
+
+// parseLine builds the check a .preflight line describes without running it.
+// args are the line's arguments after "preflight". Every line gets a command
+// tree of its own, so no flag one line sets can leak into the next.
+func parseLine(args []string, helpOut io.Writer) (parsedLine, error) {
+	line := newApp()
+	var parsed parsedLine
+	line.collect = func(cmd *cobra.Command, c Checker) {
+		parsed.cmd, parsed.checker = cmd, c
+	}
+
+	line.root.SetOut(helpOut)
+	cmd, err := line.execute(context.Background(), args)
+	if parsed.cmd == nil {
+		parsed.cmd = cmd
+	}
+	return parsed, err
+}
+
+// lineType names the command a line reached, or nothing if it named none.
+func lineType(cmd *cobra.Command) string {
+	if cmd == nil || !cmd.HasParent() {
+		return ""
+	}
+	return cmd.Name()
+}
+
+// writeJUnitReport writes records to path as a JUnit suite named after the
+// .preflight file they came from.
+func writeJUnitReport(path, preflightPath string, records []output.Record) error {
+	var buf bytes.Buffer
+	if err := output.WriteJUnit(&buf, preflightPath, records); err != nil {
+		return fmt.Errorf("failed to render JUnit report: %w", err)
+	}
+	// CI picks the report up as an artifact, so it has to be readable by more
+	// than the user preflight ran as.
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { //nolint:gosec // a test report holds no secrets
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
+
+// writeMetricsFile writes records to path in the Prometheus text format.
+//
+// A check that fails keeps the last-success time the previous run wrote, so
+// "has not passed in an hour" can be alerted on across runs. The file is
+// replaced by renaming a complete one over it, because the textfile collector
+// may read it at any moment and would report a half-written file as an error.
+func writeMetricsFile(path string, records []output.Record, at time.Time) error {
+	var metrics output.Metrics
+	if previous, err := os.Open(path); err == nil { //nolint:gosec // the path is the user's own --metrics-file
+		// A file that cannot be read back only costs the last-success times.
+		_ = metrics.ReadLastSuccess(previous)
+		_ = previous.Close()
+	}
+	metrics.Observe(records, at)
+
+	var buf bytes.Buffer
+	if _, err := metrics.WriteTo(&buf); err != nil {
+		return fmt.Errorf("failed to render metrics: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	// node_exporter usually runs as a user of its own.
+	if err := tmp.Chmod(0o644); err != nil { //nolint:gosec // metrics hold no secrets
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
 }
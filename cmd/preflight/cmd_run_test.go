@@ -1,163 +1,461 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/output"
 )
 
 // The loop that executes .preflight lines was untestable in-process because it
 // called os.Exit directly, so the substitution that keeps a line from naming
 // some other binary had no test at all.
 func TestRunCommands(t *testing.T) {
-	const exe = "/path/to/preflight"
+	t.Setenv("PREFLIGHT_RUN_TEST_SET", "yes")
 
-	t.Run("always runs the preflight binary, never a path from the file", func(t *testing.T) {
-		var gotName string
-		var gotArgs []string
-		_, err := runCommands([]string{"preflight env HOME"}, exe, func(name string, args []string) error {
-			gotName, gotArgs = name, args
-			return nil
-		})
+	t.Run("a passing file exits 0", func(t *testing.T) {
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{"preflight env PREFLIGHT_RUN_TEST_SET"}, runOptions{})
 		require.NoError(t, err)
-		assert.Equal(t, exe, gotName)
-		assert.Equal(t, []string{"env", "HOME"}, gotArgs)
+		assert.Equal(t, 0, code)
 	})
 
-	t.Run("substitutes even when the first token is a path", func(t *testing.T) {
-		// ParseFile should never produce this, so the assertion is that the
-		// second layer holds independently of the first.
-		var gotName string
-		_, err := runCommands([]string{"preflight/../evil.sh --pwn"}, exe, func(name string, _ []string) error {
-			gotName = name
-			return nil
-		})
+	// The first token is dropped rather than executed, so a line can only ever
+	// reach preflight's own commands. ParseFile should never produce this; the
+	// assertion is that the second layer holds independently of the first.
+	t.Run("a path as the first token is never run", func(t *testing.T) {
+		rec, reported := runOneLine("--pwn")
+		assert.False(t, reported)
+		assert.Equal(t, check.StatusFail, rec.Status)
+
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{"preflight/../evil.sh --pwn"}, runOptions{})
 		require.NoError(t, err)
-		assert.Equal(t, exe, gotName, "a path in the file must not become the program")
+		assert.Equal(t, 1, code, "a line that names no check is a failure")
 	})
 
 	t.Run("a failing check exits 1", func(t *testing.T) {
-		failing := exec.Command("sh", "-c", "exit 42")
-		exitErr := failing.Run()
-		require.Error(t, exitErr)
-
-		code, err := runCommands([]string{"preflight env NOPE"}, exe, func(string, []string) error {
-			return exitErr
-		})
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{"preflight env PREFLIGHT_RUN_TEST_UNSET"}, runOptions{})
 		require.NoError(t, err)
-		// The child is always preflight itself, which only ever exits 0 or 1,
-		// so there is no other code worth forwarding.
+		// A check only ever passes or fails, so there is no other code worth
+		// forwarding.
 		assert.Equal(t, 1, code)
 	})
 
-	t.Run("a non-exit failure is an error, not an exit code", func(t *testing.T) {
-		code, err := runCommands([]string{"preflight env HOME"}, exe, func(string, []string) error {
-			return errors.New("fork failed")
-		})
-		require.Error(t, err)
-		assert.Equal(t, 0, code)
-		assert.Contains(t, err.Error(), "preflight env HOME")
-	})
-
 	// The point of a preflight is one pass that reports everything wrong, not a
 	// fix-one-rerun loop, so a failing check must not hide the ones after it.
 	t.Run("runs every command even after one fails", func(t *testing.T) {
-		calls := 0
-		failing := exec.Command("sh", "-c", "exit 3")
-		exitErr := failing.Run()
-
-		code, err := runCommands([]string{"preflight env A", "preflight env B", "preflight env C"}, exe,
-			func(string, []string) error {
-				calls++
-				if calls == 2 {
-					return exitErr
-				}
-				return nil
-			})
+		junit := filepath.Join(t.TempDir(), "report.xml")
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{
+			"preflight env PREFLIGHT_RUN_TEST_SET",
+			"preflight env PREFLIGHT_RUN_TEST_UNSET",
+			"preflight env PREFLIGHT_RUN_TEST_SET",
+		}, runOptions{junit: junit})
 		require.NoError(t, err)
 		assert.Equal(t, 1, code)
-		assert.Equal(t, 3, calls, "a failure must not stop the checks after it")
-	})
 
-	t.Run("several failures still run everything and exit 1", func(t *testing.T) {
-		calls := 0
-		exitErr := exec.Command("sh", "-c", "exit 1").Run()
+		data, err := os.ReadFile(junit) //nolint:gosec // test-controlled path
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `tests="3" failures="1"`, "a failure must not stop the checks after it")
+	})
 
-		code, err := runCommands([]string{"preflight env A", "preflight env B", "preflight env C"}, exe,
-			func(string, []string) error {
-				calls++
-				return exitErr
-			})
+	// A usage mistake on one line is reported and counted like a failed check;
+	// it does not stop the lines after it.
+	t.Run("a usage error fails its line only", func(t *testing.T) {
+		junit := filepath.Join(t.TempDir(), "report.xml")
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{
+			"preflight env",
+			"preflight env PREFLIGHT_RUN_TEST_SET",
+		}, runOptions{junit: junit})
 		require.NoError(t, err)
 		assert.Equal(t, 1, code)
-		assert.Equal(t, 3, calls)
-	})
-
-	// A failure to spawn is not a check result, so it still stops the run: the
-	// remaining lines would fail the same way and bury the real cause.
-	t.Run("a non-exit failure stops the run", func(t *testing.T) {
-		calls := 0
-		_, err := runCommands([]string{"preflight env A", "preflight env B"}, exe,
-			func(string, []string) error {
-				calls++
-				return errors.New("fork failed")
-			})
-		require.Error(t, err)
-		assert.Equal(t, 1, calls)
+
+		data, err := os.ReadFile(junit) //nolint:gosec // test-controlled path
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `tests="2" failures="1"`)
 	})
 
 	t.Run("blank commands are skipped without running anything", func(t *testing.T) {
-		calls := 0
-		code, err := runCommands([]string{"", "   "}, exe, func(string, []string) error {
-			calls++
-			return nil
-		})
+		a := newApp()
+		code, err := a.runCommands(t.Context(), ".preflight", []string{"", "   "}, runOptions{})
 		require.NoError(t, err)
 		assert.Equal(t, 0, code)
-		assert.Equal(t, 0, calls)
+		assert.False(t, a.checkRan)
 	})
 
 	t.Run("records that a check ran, but not for an empty file", func(t *testing.T) {
-		original := checkRan
-		defer func() { checkRan = original }()
+		a := newApp()
+		_, err := a.runCommands(t.Context(), ".preflight", nil, runOptions{})
+		require.NoError(t, err)
+		assert.False(t, a.checkRan, "an empty .preflight ran no check, so exec must still be refused")
 
-		checkRan = false
-		_, err := runCommands(nil, exe, func(string, []string) error { return nil })
+		a = newApp()
+		_, err = a.runCommands(t.Context(), ".preflight", []string{"preflight env PREFLIGHT_RUN_TEST_SET"}, runOptions{})
 		require.NoError(t, err)
-		assert.False(t, checkRan, "an empty .preflight ran no check, so exec must still be refused")
+		assert.True(t, a.checkRan)
+	})
+}
+
+// Flags used to be package-level variables, so a slice flag set by one line was
+// still set for the next. Each line now gets a command tree of its own.
+func TestParseLine_LinesDoNotShareFlags(t *testing.T) {
+	first, err := parseLine([]string{"env", "X", "--one-of", "a,b"}, os.Stdout)
+	require.NoError(t, err)
+	second, err := parseLine([]string{"env", "Y"}, os.Stdout)
+	require.NoError(t, err)
 
-		checkRan = false
-		_, err = runCommands([]string{"preflight env HOME"}, exe, func(string, []string) error { return nil })
+	require.NotNil(t, first.checker)
+	require.NotNil(t, second.checker)
+	assert.Equal(t, "env", second.cmd.Name())
+	assert.NotEqual(t, first.checker, second.checker)
+
+	rec, reported := runOneLine("env", "PATH")
+	assert.True(t, reported)
+	assert.Equal(t, check.StatusOK, rec.Status, "--one-of from an earlier line must not apply here")
+}
+
+func TestParseLine(t *testing.T) {
+	t.Run("builds the check without running it", func(t *testing.T) {
+		parsed, err := parseLine([]string{"tcp", "127.0.0.1:1", "--timeout", "1ms"}, os.Stdout)
 		require.NoError(t, err)
-		assert.True(t, checkRan)
+		assert.Equal(t, "tcp", parsed.cmd.Name())
+		assert.NotNil(t, parsed.checker)
+	})
+
+	t.Run("a usage error names the command for its usage", func(t *testing.T) {
+		parsed, err := parseLine([]string{"resource", "--min-disk", "lots"}, os.Stdout)
+		require.Error(t, err)
+		assert.Equal(t, "resource", parsed.cmd.Name())
+		assert.Nil(t, parsed.checker)
+	})
+
+	t.Run("help builds no check", func(t *testing.T) {
+		parsed, err := parseLine([]string{"env", "--help"}, io.Discard)
+		require.NoError(t, err)
+		assert.Nil(t, parsed.checker)
+	})
+
+	// A file that ran itself would recurse until the stack ran out.
+	t.Run("run cannot be nested", func(t *testing.T) {
+		_, err := parseLine([]string{"run"}, os.Stdout)
+		require.Error(t, err)
 	})
 }
 
+func TestLineRun_TypeOfAFailedLine(t *testing.T) {
+	rec, reported := runOneLine("env")
+	assert.False(t, reported)
+	assert.Equal(t, check.StatusFail, rec.Status)
+	assert.Equal(t, "env", rec.Type)
+	assert.NotEmpty(t, rec.Error)
+}
+
+// The name of a line that did not parse is the line as written, quotes and all.
+func TestLineRun_NameOfAFailedLineKeepsQuoting(t *testing.T) {
+	rec, _ := runOneLine("env", "FOO", "--match", "a (b")
+	assert.Equal(t, check.StatusFail, rec.Status)
+	assert.Equal(t, "env FOO --match 'a (b'", rec.Name)
+}
+
 // Reaches the wiring in runPreflightFile that the "nonexistent file" case returns before:
-// discovery, parsing, resolving the executable, and the zero-exit path. An
-// empty file runs no commands, so nothing is spawned.
+// discovery, parsing, and the zero-exit path. An empty file runs no commands.
 func TestRunPreflightFile_EmptyFileIsASuccessfulNoOp(t *testing.T) {
-	originalFile, originalRan := runFile, checkRan
-	defer func() { runFile, checkRan = originalFile, originalRan }()
-	runFile, checkRan = "", false
-
 	dir := t.TempDir()
 	require.NoError(t, os.WriteFile(filepath.Join(dir, ".preflight"), []byte("# only a comment\n\n"), 0o600))
 	t.Chdir(dir)
 
-	require.NoError(t, runPreflightFile(nil, nil))
-	assert.False(t, checkRan, "no check ran, so exec mode must still refuse")
+	a := newApp()
+	require.NoError(t, a.runPreflightFile(t.Context(), runOptions{}))
+	assert.False(t, a.checkRan, "no check ran, so exec mode must still refuse")
 }
 
 func TestRunPreflightFile_ReportsAMissingFile(t *testing.T) {
-	originalFile := runFile
-	defer func() { runFile = originalFile }()
-	runFile = filepath.Join(t.TempDir(), "absent")
+	require.Error(t, newApp().runPreflightFile(t.Context(), runOptions{file: filepath.Join(t.TempDir(), "absent")}))
+}
+
+// A failed line is reported through the summary, and through the exit code via
+// ErrCheckFailed, which main prints nothing extra for.
+func TestRunPreflightFile_FailureIsACheckFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.preflight")
+	require.NoError(t, os.WriteFile(path, []byte("env PREFLIGHT_RUN_TEST_UNSET\n"), 0o600))
+
+	err := newApp().runPreflightFile(t.Context(), runOptions{file: path})
+	require.ErrorIs(t, err, ErrCheckFailed)
+}
+
+// runOneLine takes a single line through the same steps runCommands does.
+func runOneLine(args ...string) (output.Record, bool) {
+	l := prepareLine(args)
+	startLines(context.Background(), []*lineRun{l}, 1)
+	<-l.done
+	return l.record()
+}
+
+// funcChecker is a Checker whose run is whatever the test needs it to be.
+type funcChecker func() check.Result
+
+func (f funcChecker) RunContext(context.Context) check.Result { return f() }
+
+func checkerLine(name string, run func() check.Result) *lineRun {
+	return &lineRun{
+		args:   []string{name},
+		parsed: parsedLine{cmd: &cobra.Command{Use: name}, checker: funcChecker(run)},
+		done:   make(chan struct{}),
+	}
+}
+
+// A startup gate waiting on several services should take as long as the
+// slowest, not the sum of them all.
+func TestStartLines_RunsChecksConcurrently(t *testing.T) {
+	secondRan := make(chan struct{})
+	first := checkerLine("first", func() check.Result {
+		select {
+		case <-secondRan:
+			return check.Result{Name: "first", Status: check.StatusOK}
+		case <-time.After(5 * time.Second):
+			return check.Result{Name: "first", Status: check.StatusFail}
+		}
+	})
+	second := checkerLine("second", func() check.Result {
+		close(secondRan)
+		return check.Result{Name: "second", Status: check.StatusOK}
+	})
+
+	startLines(t.Context(), []*lineRun{first, second}, 2)
+	<-first.done
+	<-second.done
+	assert.Equal(t, check.StatusOK, first.result.Status, "the first check waited on the second, so they must overlap")
+}
+
+func TestStartLines_TimesEveryCheck(t *testing.T) {
+	l := checkerLine("slow", func() check.Result {
+		time.Sleep(20 * time.Millisecond)
+		return check.Result{Name: "slow", Status: check.StatusOK}
+	})
+
+	startLines(t.Context(), []*lineRun{l}, 1)
+	<-l.done
+	assert.GreaterOrEqual(t, l.result.Duration, 20*time.Millisecond)
+	rec, _ := l.record()
+	assert.GreaterOrEqual(t, rec.DurationMS, 20.0)
+}
+
+func TestStartLines_OneJobRunsInFileOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	var lines []*lineRun
+	for _, name := range []string{"a", "b", "c", "d"} {
+		lines = append(lines, checkerLine(name, func() check.Result {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return check.Result{Name: name, Status: check.StatusOK}
+		}))
+	}
+
+	startLines(t.Context(), lines, 1)
+	for _, l := range lines {
+		<-l.done
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, order)
+}
+
+// Lines a run never reached are still in the report, saying why.
+func TestStartLines_NotRunOnceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(t.Context())
+	cancel(errors.New("received SIGTERM"))
+	ran := false
+	l := checkerLine("late", func() check.Result {
+		ran = true
+		return check.Result{Name: "late", Status: check.StatusOK}
+	})
 
-	require.Error(t, runPreflightFile(nil, nil))
+	startLines(ctx, []*lineRun{l}, 1)
+	<-l.done
+	assert.False(t, ran)
+	rec, reported := l.record()
+	assert.True(t, reported)
+	assert.Equal(t, check.StatusFail, rec.Status)
+	assert.Equal(t, []string{"not run: received SIGTERM"}, rec.Details)
+}
+
+// Checks finish in whatever order they finish in; the report must not.
+func TestRunCommands_JobsKeepFileOrder(t *testing.T) {
+	t.Setenv("PREFLIGHT_RUN_TEST_SET", "yes")
+
+	junit := filepath.Join(t.TempDir(), "report.xml")
+	code, err := newApp().runCommands(t.Context(), ".preflight", []string{
+		"preflight env PREFLIGHT_RUN_TEST_SET",
+		"preflight env PREFLIGHT_RUN_TEST_UNSET",
+		"preflight env",
+		"preflight env PATH",
+	}, runOptions{junit: junit, jobs: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 1, code)
+
+	data, err := os.ReadFile(junit) //nolint:gosec // test-controlled path
+	require.NoError(t, err)
+	report := string(data)
+	assert.Contains(t, report, `tests="4" failures="2"`)
+
+	set := strings.Index(report, `<testcase name="env: PREFLIGHT_RUN_TEST_SET"`)
+	unset := strings.Index(report, `<testcase name="env: PREFLIGHT_RUN_TEST_UNSET"`)
+	usage := strings.Index(report, `<testcase name="env"`)
+	path := strings.Index(report, `<testcase name="env: PATH"`)
+	assert.True(t, set < unset && unset < usage && usage < path, "records must follow the file:\n%s", report)
+}
+
+func TestRunCmd_RejectsZeroJobs(t *testing.T) {
+	_, err := executeCommand("run", "--jobs", "0", "--file", filepath.Join(t.TempDir(), "absent"))
+	require.ErrorContains(t, err, "--jobs must be at least 1")
+}
+
+// A warned check is reported and counted but leaves the exit code at 0.
+func TestRunCommands_Warnings(t *testing.T) {
+	t.Setenv("PREFLIGHT_RUN_TEST_SET", "yes")
+
+	t.Run("a failing warn line does not fail the run", func(t *testing.T) {
+		junit := filepath.Join(t.TempDir(), "report.xml")
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{
+			"preflight env PREFLIGHT_RUN_TEST_SET",
+			"preflight warn env PREFLIGHT_RUN_TEST_UNSET",
+		}, runOptions{junit: junit})
+		require.NoError(t, err)
+		assert.Equal(t, 0, code)
+
+		data, err := os.ReadFile(junit) //nolint:gosec // test-controlled path
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `tests="2" failures="0"`)
+		assert.Contains(t, string(data), "WARN: not set")
+	})
+
+	t.Run("a --warn flag does the same", func(t *testing.T) {
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{
+			"preflight env PREFLIGHT_RUN_TEST_UNSET --warn",
+		}, runOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 0, code)
+	})
+
+	// The file is broken, not the environment.
+	t.Run("a usage error on a warn line still fails", func(t *testing.T) {
+		code, err := newApp().runCommands(t.Context(), ".preflight", []string{"preflight warn env"}, runOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, code)
+	})
+
+	t.Run("a warn line that passes is OK", func(t *testing.T) {
+		rec, reported := runOneLine("warn", "env", "PREFLIGHT_RUN_TEST_SET")
+		assert.True(t, reported)
+		assert.Equal(t, check.StatusOK, rec.Status)
+	})
+}
+
+func TestRunPreflightFile_Tags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.preflight")
+	require.NoError(t, os.WriteFile(path, []byte(`env PATH
+
+[startup]
+env PREFLIGHT_RUN_TEST_UNSET
+
+[build]
+@slow env PREFLIGHT_RUN_TEST_UNSET
+env PATH
+`), 0o600))
+
+	t.Run("only the selected section runs", func(t *testing.T) {
+		require.NoError(t, newApp().runPreflightFile(t.Context(), runOptions{file: path, tags: []string{"build"}, skipTags: []string{"slow"}}))
+	})
+
+	t.Run("the whole file runs without a selection", func(t *testing.T) {
+		require.ErrorIs(t, newApp().runPreflightFile(t.Context(), runOptions{file: path}), ErrCheckFailed)
+	})
+
+	t.Run("an unknown tag names the file", func(t *testing.T) {
+		err := newApp().runPreflightFile(t.Context(), runOptions{file: path, tags: []string{"deploy"}})
+		require.ErrorContains(t, err, path)
+		require.ErrorContains(t, err, `no line is tagged "deploy"`)
+	})
+}
+
+func TestRunPreflightFile_Interpolation(t *testing.T) {
+	t.Setenv("PREFLIGHT_RUN_TEST_SET", "yes")
+	path := filepath.Join(t.TempDir(), "checks.preflight")
+	require.NoError(t, os.WriteFile(path, []byte("# preflight: interpolate\nenv PREFLIGHT_RUN_TEST_SET --exact ${expected:-no}\n"), 0o600))
+
+	t.Run("--set reaches the file", func(t *testing.T) {
+		require.NoError(t, newApp().runPreflightFile(t.Context(), runOptions{file: path, set: []string{"expected=yes"}}))
+	})
+
+	t.Run("the default applies without it", func(t *testing.T) {
+		require.ErrorIs(t, newApp().runPreflightFile(t.Context(), runOptions{file: path}), ErrCheckFailed)
+	})
+
+	t.Run("a malformed --set is an error", func(t *testing.T) {
+		require.ErrorContains(t, newApp().runPreflightFile(t.Context(), runOptions{file: path, set: []string{"expected"}}), "want key=value")
+	})
+
+	// ./checks.pf staging arrives as run --file checks.pf staging.
+	t.Run("positional args from a hashbang script", func(t *testing.T) {
+		script := filepath.Join(t.TempDir(), "checks.pf")
+		require.NoError(t, os.WriteFile(script, []byte("#!/usr/bin/env preflight\n# preflight: interpolate\nenv PREFLIGHT_RUN_TEST_SET --exact ${1}\n"), 0o600))
+
+		_, err := executeCommand(transformArgsForHashbang([]string{"preflight", script, "yes"}, realFileChecker)[1:]...)
+		require.NoError(t, err)
+		_, err = executeCommand(transformArgsForHashbang([]string{"preflight", script, "no"}, realFileChecker)[1:]...)
+		require.ErrorIs(t, err, ErrCheckFailed)
+	})
+
+	t.Run("args without interpolation are an error", func(t *testing.T) {
+		plain := filepath.Join(t.TempDir(), "plain.preflight")
+		require.NoError(t, os.WriteFile(plain, []byte("env PATH\n"), 0o600))
+		_, err := executeCommand("run", "--file", plain, "staging")
+		require.ErrorContains(t, err, "does not interpolate")
+	})
+}
+
+func TestRunPreflightFile_MetricsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "checks.preflight")
+	metrics := filepath.Join(dir, "preflight.prom")
+	require.NoError(t, os.WriteFile(path, []byte("env PATH\nenv PREFLIGHT_RUN_TEST_UNSET\n"), 0o600))
+
+	require.ErrorIs(t, newApp().runPreflightFile(t.Context(), runOptions{file: path, metricsFile: metrics}), ErrCheckFailed)
+
+	data, err := os.ReadFile(metrics)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `preflight_check_up{name="env: PATH",type="env"} 1`)
+	assert.Contains(t, string(data), `preflight_check_up{name="env: PREFLIGHT_RUN_TEST_UNSET",type="env"} 0`)
+	assert.NotContains(t, string(data), `preflight_check_last_success_timestamp{name="env: PREFLIGHT_RUN_TEST_UNSET"`)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no temporary file is left behind")
+
+	t.Run("a failure keeps the last success of the previous run", func(t *testing.T) {
+		before := string(data)
+		i := strings.Index(before, `preflight_check_last_success_timestamp{name="env: PATH",type="env"} `)
+		require.GreaterOrEqual(t, i, 0)
+		line, _, _ := strings.Cut(before[i:], "\n")
+
+		t.Setenv("PATH", "")
+		t.Setenv("PREFLIGHT_RUN_TEST_UNSET", "set")
+		require.ErrorIs(t, newApp().runPreflightFile(t.Context(), runOptions{file: path, metricsFile: metrics}), ErrCheckFailed)
+
+		after, err := os.ReadFile(metrics)
+		require.NoError(t, err)
+		assert.Contains(t, string(after), `preflight_check_up{name="env: PATH",type="env"} 0`)
+		assert.Contains(t, string(after), line)
+	})
 }
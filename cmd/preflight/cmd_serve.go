@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/output"
+	"github.com/vertti/preflight/pkg/preflightfile"
+)
+
+// serveOptions are the flags of `preflight serve`.
+type serveOptions struct {
+	runOptions
+	addr     string
+	interval time.Duration // re-run in the background this often; 0 means on request
+	cacheTTL time.Duration // on request, reuse a report this recent
+}
+
+func newServeCmd(a *app) *cobra.Command {
+	var opts serveOptions
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve checks from a .preflight file as HTTP health endpoints",
+		Long: `Serve checks from a .preflight file as HTTP health endpoints.
+
+  /healthz  200 while the server is up
+  /readyz   200 if no check failed, 503 otherwise, with the report as JSON
+  /checks   the report as JSON, always 200
+  /metrics  the results in the Prometheus text format`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			switch {
+			case opts.jobs < 1:
+				return fmt.Errorf("--jobs must be at least 1, got %d", opts.jobs)
+			case opts.interval < 0:
+				return fmt.Errorf("--interval must not be negative, got %s", opts.interval)
+			case opts.cacheTTL < 0:
+				return fmt.Errorf("--cache-ttl must not be negative, got %s", opts.cacheTTL)
+			}
+			return a.serve(cmd.Context(), opts)
+		},
+	}
+	addFileFlags(cmd, &opts.runOptions)
+	cmd.Flags().StringVar(&opts.addr, "addr", ":8080", "address to listen on")
+	cmd.Flags().DurationVar(&opts.interval, "interval", 0, "re-run the checks this often in the background (default: on request)")
+	cmd.Flags().DurationVar(&opts.cacheTTL, "cache-ttl", 5*time.Second, "without --interval, reuse results this recent")
+	return cmd
+}
+
+// serve answers health probes from the checks in a .preflight file until it is
+// interrupted.
+//
+// A readiness probe that execs `preflight run` every few seconds forks a
+// process per probe, and a cobra startup and the checks with it. Serving keeps
+// one process up and runs the checks no more often than the cache allows, so
+// a dozen probes a minute cost one run.
+func (a *app) serve(ctx context.Context, opts serveOptions) error {
+	if a.collect != nil {
+		return errors.New("serve cannot be used inside a .preflight file")
+	}
+
+	preflightPath, commands, err := loadPreflightFile(opts.runOptions)
+	if err != nil {
+		return err
+	}
+	// A line that cannot be parsed would fail every probe for a reason no
+	// dependency can fix, so it stops the server from starting instead.
+	lines, err := prepareLines(commands)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if l.err != nil {
+			return fmt.Errorf("%s: %s: %w", preflightPath, preflightfile.Join(l.args), l.err)
+		}
+	}
+
+	p := &prober{
+		evaluate: func() output.Report {
+			return evaluateFile(ctx, preflightPath, commands, opts.jobs)
+		},
+		ttl:        opts.cacheTTL,
+		background: opts.interval > 0,
+		now:        time.Now,
+	}
+
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	server := &http.Server{Handler: p.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	if p.background {
+		go p.loop(ctx, opts.interval)
+	}
+
+	fmt.Fprintf(os.Stderr, "serving %d checks from %s on %s\n", len(commands), preflightPath, listener.Addr())
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	// A probe in flight gets its answer; a pod being stopped is not ready anyway.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// evaluateFile runs every command once and reports on them, printing nothing.
+func evaluateFile(ctx context.Context, preflightPath string, commands []string, jobs int) output.Report {
+	// serve has already parsed every line without an error, and parsing the
+	// same commands again cannot find one.
+	lines, _ := prepareLines(commands)
+	startLines(ctx, lines, max(jobs, 1))
+
+	records := make([]output.Record, 0, len(lines))
+	for _, l := range lines {
+		<-l.done
+		rec, _ := l.record()
+		records = append(records, rec)
+	}
+	return output.NewReport(preflightPath, records)
+}
+
+// prober holds the latest report and decides when it is too old to answer with.
+//
+// On request, a report younger than ttl is reused and an older one is replaced
+// by running the checks again while the probe waits. In the background, the
+// latest report is always the answer and probes never wait, except for the
+// first report of all.
+type prober struct {
+	evaluate   func() output.Report
+	ttl        time.Duration
+	background bool
+	now        func() time.Time // injected for testing
+
+	metrics output.Metrics
+
+	running sync.Mutex // held while the checks run, so concurrent probes share a run
+
+	mu     sync.Mutex // guards report and at
+	report *output.Report
+	at     time.Time
+}
+
+// current returns a report fresh enough to answer a probe with.
+func (p *prober) current() output.Report {
+	if report, ok := p.cached(); ok {
+		return report
+	}
+	p.running.Lock()
+	defer p.running.Unlock()
+	// Whoever held the lock may have just run the checks.
+	if report, ok := p.cached(); ok {
+		return report
+	}
+	return p.refresh()
+}
+
+// cached returns the latest report if it may still be used.
+func (p *prober) cached() (output.Report, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.report == nil {
+		return output.Report{}, false
+	}
+	if !p.background && p.now().Sub(p.at) >= p.ttl {
+		return output.Report{}, false
+	}
+	return *p.report, true
+}
+
+// refresh runs the checks and keeps their report. The caller holds running.
+func (p *prober) refresh() output.Report {
+	report := p.evaluate()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report, p.at = &report, p.now()
+	p.metrics.Observe(report.Checks, p.at)
+	return report
+}
+
+// loop runs the checks every interval until ctx is done, starting at once.
+func (p *prober) loop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.running.Lock()
+		p.refresh()
+		p.running.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handler serves the probe endpoints.
+//
+// /healthz does not run the checks. It is meant for a liveness probe, and a
+// pod restarted because its database is down comes back to the same database.
+func (p *prober) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, http.StatusOK, map[string]check.Status{"status": check.StatusOK})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		report := p.current()
+		status := http.StatusOK
+		if report.Failed > 0 {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+	mux.HandleFunc("GET /checks", func(w http.ResponseWriter, _ *http.Request) {
+		writeReport(w, http.StatusOK, p.current())
+	})
+	// A scrape is a probe like any other: it gets results no older than the
+	// cache allows.
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, _ *http.Request) {
+		p.current()
+		w.Header().Set("Content-Type", output.MetricsContentType)
+		_, _ = p.metrics.WriteTo(w)
+	})
+	return mux
+}
+
+// writeReport answers with v as JSON.
+func writeReport(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = output.WriteJSON(w, v)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/output"
+)
+
+// fakeProber serves reports of the given status, counting how often it ran.
+func fakeProber(status *check.Status, runs *int) *prober {
+	now := time.Unix(0, 0)
+	return &prober{
+		evaluate: func() output.Report {
+			*runs++
+			return output.NewReport(".preflight", []output.Record{{Name: "tcp: db:5432", Type: "tcp", Status: *status}})
+		},
+		ttl: 5 * time.Second,
+		now: func() time.Time { return now },
+	}
+}
+
+func probe(t *testing.T, h http.Handler, path string) (int, output.Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var report output.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report), rec.Body.String())
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	return rec.Code, report
+}
+
+func TestProber_Endpoints(t *testing.T) {
+	status, runs := check.StatusOK, 0
+	h := fakeProber(&status, &runs).handler()
+
+	code, report := probe(t, h, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, check.StatusOK, report.Status)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "tcp: db:5432", report.Checks[0].Name)
+
+	t.Run("a failure makes readyz unavailable but not checks", func(t *testing.T) {
+		status, runs := check.StatusFail, 0
+		h := fakeProber(&status, &runs).handler()
+
+		code, report := probe(t, h, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, check.StatusFail, report.Status)
+
+		code, report = probe(t, h, "/checks")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, check.StatusFail, report.Status)
+	})
+
+	t.Run("a warning is still ready", func(t *testing.T) {
+		status, runs := check.StatusWarn, 0
+		code, _ := probe(t, fakeProber(&status, &runs).handler(), "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("healthz runs no checks", func(t *testing.T) {
+		status, runs := check.StatusFail, 0
+		code, report := probe(t, fakeProber(&status, &runs).handler(), "/healthz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, check.StatusOK, report.Status)
+		assert.Zero(t, runs)
+	})
+
+	t.Run("only GET", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/readyz", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
+
+func TestProber_CacheTTL(t *testing.T) {
+	status, runs := check.StatusOK, 0
+	p := fakeProber(&status, &runs)
+	now := time.Unix(0, 0)
+	p.now = func() time.Time { return now }
+	h := p.handler()
+
+	probe(t, h, "/readyz")
+	probe(t, h, "/checks")
+	now = now.Add(4 * time.Second)
+	probe(t, h, "/readyz")
+	assert.Equal(t, 1, runs, "probes within the TTL share one run")
+
+	status = check.StatusFail
+	now = now.Add(time.Second)
+	code, _ := probe(t, h, "/readyz")
+	assert.Equal(t, 2, runs)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestProber_ConcurrentProbesShareARun(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	runs := 0
+	p := &prober{
+		evaluate: func() output.Report {
+			<-release
+			mu.Lock()
+			runs++
+			mu.Unlock()
+			return output.NewReport(".preflight", nil)
+		},
+		ttl: time.Minute,
+		now: time.Now,
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() { p.current() })
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 1, runs)
+}
+
+func TestProber_BackgroundNeverRunsOnRequest(t *testing.T) {
+	status, runs := check.StatusOK, 0
+	p := fakeProber(&status, &runs)
+	p.background = true
+	now := time.Unix(0, 0)
+	p.now = func() time.Time { return now }
+
+	// The first probe has nothing to answer with until a report exists.
+	probe(t, p.handler(), "/readyz")
+	now = now.Add(time.Hour)
+	probe(t, p.handler(), "/readyz")
+	assert.Equal(t, 1, runs)
+
+	p.refresh()
+	assert.Equal(t, 2, runs)
+}
+
+func TestEvaluateFile(t *testing.T) {
+	report := evaluateFile(t.Context(), ".preflight", []string{
+		"preflight env PATH",
+		"preflight warn env PREFLIGHT_NONEXISTENT_VAR_12345",
+		"preflight env PREFLIGHT_NONEXISTENT_VAR_12345",
+	}, 2)
+
+	assert.Equal(t, check.StatusFail, report.Status)
+	assert.Equal(t, 3, report.Ran)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Warned)
+	assert.Equal(t, "env: PATH", report.Checks[0].Name)
+}
+
+func TestServeCmd_RefusesABrokenFile(t *testing.T) {
+	path := writeTempFile(t, ".preflight", "env PATH\nenv --no-such-flag HOME\n")
+
+	_, err := executeCommand("serve", "--file", path, "--addr", "127.0.0.1:0")
+	require.ErrorContains(t, err, "env --no-such-flag HOME")
+	require.ErrorContains(t, err, "unknown flag")
+}
+
+func TestServeCmd_FlagValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"serve", "--jobs", "0"},
+		{"serve", "--interval", "-1s"},
+		{"serve", "--cache-ttl", "-1s"},
+	} {
+		_, err := executeCommand(args...)
+		require.ErrorContains(t, err, "--", "%q", args)
+	}
+}
+
+func TestProber_Metrics(t *testing.T) {
+	status, runs := check.StatusFail, 0
+	h := fakeProber(&status, &runs).handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, output.MetricsContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `preflight_check_up{name="tcp: db:5432",type="tcp"} 0`)
+	assert.Equal(t, 1, runs, "a scrape runs the checks like a probe")
+}
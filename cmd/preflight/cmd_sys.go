@@ -6,35 +6,27 @@ import (
 	"github.com/vertti/preflight/pkg/syscheck"
 )
 
-var (
-	sysOS   string
-	sysArch string
-)
+func newSysCmd(a *app) *cobra.Command {
+	var expectedOS, expectedArch string
 
-var sysCmd = &cobra.Command{
-	Use:   "sys",
-	Short: "Check system OS and architecture",
-	Long: `Verify the system matches expected OS and/or architecture.
+	cmd := &cobra.Command{
+		Use:   "sys",
+		Short: "Check system OS and architecture",
+		Long: `Verify the system matches expected OS and/or architecture.
 
 Examples:
   preflight sys --os linux
   preflight sys --arch amd64
   preflight sys --os linux --arch arm64`,
-	RunE: runSysCheck,
-}
-
-func init() {
-	sysCmd.Flags().StringVar(&sysOS, "os", "", "required OS (linux, darwin, windows)")
-	sysCmd.Flags().StringVar(&sysArch, "arch", "", "required architecture (amd64, arm64, 386)")
-	rootCmd.AddCommand(sysCmd)
-}
-
-func runSysCheck(_ *cobra.Command, _ []string) error {
-	c := &syscheck.Check{
-		ExpectedOS:   sysOS,
-		ExpectedArch: sysArch,
-		Info:         &syscheck.RealSysInfo{},
 	}
-
-	return runCheck(c)
+	cmd.Flags().StringVar(&expectedOS, "os", "", "required OS (linux, darwin, windows)")
+	cmd.Flags().StringVar(&expectedArch, "arch", "", "required architecture (amd64, arm64, 386)")
+
+	return a.checkCommand(cmd, func(*cobra.Command, []string) (Checker, error) {
+		return &syscheck.Check{
+			ExpectedOS:   expectedOS,
+			ExpectedArch: expectedArch,
+			Info:         &syscheck.RealSysInfo{},
+		}, nil
+	})
 }
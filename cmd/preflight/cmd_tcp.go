@@ -8,28 +8,21 @@ import (
 	"github.com/vertti/preflight/pkg/tcpcheck"
 )
 
-var tcpTimeout time.Duration
+func newTCPCmd(a *app) *cobra.Command {
+	var timeout time.Duration
 
-var tcpCmd = &cobra.Command{
-	Use:   "tcp <host:port>",
-	Short: "Check TCP connectivity to a host:port",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runTCPCheck,
-}
-
-func init() {
-	tcpCmd.Flags().DurationVar(&tcpTimeout, "timeout", 5*time.Second, "connection timeout")
-	rootCmd.AddCommand(tcpCmd)
-}
-
-func runTCPCheck(_ *cobra.Command, args []string) error {
-	address := args[0]
-
-	c := &tcpcheck.Check{
-		Address: address,
-		Timeout: tcpTimeout,
-		Dialer:  &tcpcheck.RealTCPDialer{},
+	cmd := &cobra.Command{
+		Use:   "tcp <host:port>",
+		Short: "Check TCP connectivity to a host:port",
+		Args:  cobra.ExactArgs(1),
 	}
-
-	return runCheck(c)
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "connection timeout")
+
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		return &tcpcheck.Check{
+			Address: args[0],
+			Timeout: timeout,
+			Dialer:  &tcpcheck.RealTCPDialer{},
+		}, nil
+	})
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/filecheck"
+	"github.com/vertti/preflight/pkg/templatecheck"
+)
+
+func newTemplateCmd(a *app) *cobra.Command {
+	var mode string
+
+	cmd := &cobra.Command{
+		Use:   "template <source> <dest>",
+		Short: "Render a config file from environment variables",
+		Long: `Render a Go text/template file from environment variables to dest.
+
+Templates read variables as {{ .Env.NAME }}, and have these helpers:
+  env "NAME"            the variable's value, or empty
+  required "NAME"       the variable's value; unset or empty, nothing is written
+  default "x" VALUE     VALUE, or "x" when VALUE is empty
+  split "," VALUE       VALUE as a list, for {{ range }}
+  toJSON VALUE          VALUE as JSON
+  b64 VALUE             VALUE base64-encoded
+
+Examples:
+  preflight template nginx.conf.tmpl /etc/nginx/nginx.conf -- nginx -g 'daemon off;'
+  preflight template --mode 0600 secrets.tmpl /run/app/secrets.env`,
+		Args: cobra.ExactArgs(2),
+	}
+	cmd.Flags().StringVar(&mode, "mode", "0644", "permissions of the written file")
+
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		perm, err := filecheck.ParseOctalMode(mode)
+		if err != nil {
+			return nil, fmt.Errorf("--mode: %w", err)
+		}
+
+		return &templatecheck.Check{
+			Source: args[0],
+			Dest:   args[1],
+			Mode:   perm,
+			Env:    &templatecheck.RealEnvironment{},
+			FS:     &templatecheck.RealFileSystem{},
+		}, nil
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/tcpcheck"
+	"github.com/vertti/preflight/pkg/tlscheck"
+)
+
+func newTLSCmd(a *app) *cobra.Command {
+	var (
+		sni            string
+		minVersion     string
+		alpn           []string
+		caFile         string
+		clientCert     string
+		clientKey      string
+		minDays        int
+		expectHostname string
+		insecure       bool
+		timeout        time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "tls <host:port>",
+		Short: "Check a TLS handshake with a host:port",
+		Long: `Dial host:port and complete a TLS handshake. No openssl needed.
+
+The served certificate must chain to a trusted root and be valid for the
+server name unless --insecure is set. The protocol, cipher, negotiated ALPN
+protocol and the certificate's subject, SANs, issuer and days left are always
+listed.
+
+Examples:
+  preflight tls api.example.com:443                        # openssl s_client -verify_return_error
+  preflight tls api.example.com:443 --min-version 1.2 --min-days 30
+  preflight tls api.example.com:443 --alpn h2
+  preflight tls 10.0.0.5:443 --sni api.example.com --ca-file /certs/ca.crt
+  preflight tls broker:9093 --client-cert /certs/client.crt --client-key /certs/client.key`,
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&sni, "sni", "", "server name to send (default: the host)")
+	cmd.Flags().StringVar(&minVersion, "min-version", "", "lowest TLS version to accept: 1.0, 1.1, 1.2 or 1.3")
+	cmd.Flags().StringSliceVar(&alpn, "alpn", nil, "ALPN protocols to offer (comma-separated); the server must pick one")
+	cmd.Flags().StringVar(&caFile, "ca-file", "", "roots to trust (default: the system's)")
+	cmd.Flags().StringVar(&clientCert, "client-cert", "", "client certificate to present for mTLS")
+	cmd.Flags().StringVar(&clientKey, "client-key", "", "private key of --client-cert")
+	cmd.Flags().IntVar(&minDays, "min-days", 0, "served certificate must stay valid at least this many more days")
+	cmd.Flags().StringVar(&expectHostname, "expect-hostname", "", "served certificate must be valid for this name (default: the server name)")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "skip certificate chain and name verification")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "connection and handshake timeout")
+
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		if (clientCert == "") != (clientKey == "") {
+			return nil, errors.New("--client-cert and --client-key must be set together")
+		}
+		if minDays < 0 {
+			return nil, fmt.Errorf("--min-days must not be negative, got %d", minDays)
+		}
+		if insecure && (caFile != "" || expectHostname != "") {
+			return nil, errors.New("--ca-file and --expect-hostname cannot be used with --insecure")
+		}
+		var version uint16
+		if minVersion != "" {
+			v, err := tlscheck.ParseVersion(minVersion)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --min-version: %w", err)
+			}
+			version = v
+		}
+
+		return &tlscheck.Check{
+			Address:        args[0],
+			SNI:            sni,
+			MinVersion:     version,
+			ALPN:           alpn,
+			CAFile:         caFile,
+			ClientCert:     clientCert,
+			ClientKey:      clientKey,
+			MinDays:        minDays,
+			ExpectHostname: expectHostname,
+			Insecure:       insecure,
+			Timeout:        timeout,
+			Dialer:         &tcpcheck.RealTCPDialer{},
+			FS:             &tlscheck.RealFileSystem{},
+		}, nil
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSCommand_Flags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no address", []string{"tls"}, "accepts 1 arg(s)"},
+		{"cert without key", []string{"tls", "db:5432", "--client-cert", "client.crt"}, "--client-cert and --client-key must be set together"},
+		{"negative days", []string{"tls", "db:5432", "--min-days", "-1"}, "--min-days must not be negative"},
+		{"bad version", []string{"tls", "db:5432", "--min-version", "1.4"}, "invalid --min-version"},
+		{"insecure with CA file", []string{"tls", "db:5432", "--insecure", "--ca-file", "ca.crt"}, "cannot be used with --insecure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeCommand(tt.args...)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTLSCommand(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "https://")
+	caFile := writeTempFile(t, "ca.crt", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})))
+
+	_, err := executeCommand("tls", address, "--ca-file", caFile, "--min-version", "1.2", "--min-days", "30")
+	require.NoError(t, err)
+
+	_, err = executeCommand("tls", address)
+	assert.ErrorIs(t, err, ErrCheckFailed)
+}
@@ -6,36 +6,25 @@ import (
 	"github.com/vertti/preflight/pkg/usercheck"
 )
 
-var (
-	userUID  string
-	userGID  string
-	userHome string
-)
-
-var userCmd = &cobra.Command{
-	Use:   "user <username>",
-	Short: "Check that a user exists and meets requirements",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runUserCheck,
-}
+func newUserCmd(a *app) *cobra.Command {
+	var uid, gid, home string
 
-func init() {
-	userCmd.Flags().StringVar(&userUID, "uid", "", "expected user ID")
-	userCmd.Flags().StringVar(&userGID, "gid", "", "expected primary group ID")
-	userCmd.Flags().StringVar(&userHome, "home", "", "expected home directory")
-	rootCmd.AddCommand(userCmd)
-}
-
-func runUserCheck(_ *cobra.Command, args []string) error {
-	username := args[0]
-
-	c := &usercheck.Check{
-		Username: username,
-		UID:      userUID,
-		GID:      userGID,
-		Home:     userHome,
-		Lookup:   &usercheck.RealUserLookup{},
+	cmd := &cobra.Command{
+		Use:   "user <username>",
+		Short: "Check that a user exists and meets requirements",
+		Args:  cobra.ExactArgs(1),
 	}
-
-	return runCheck(c)
+	cmd.Flags().StringVar(&uid, "uid", "", "expected user ID")
+	cmd.Flags().StringVar(&gid, "gid", "", "expected primary group ID")
+	cmd.Flags().StringVar(&home, "home", "", "expected home directory")
+
+	return a.checkCommand(cmd, func(_ *cobra.Command, args []string) (Checker, error) {
+		return &usercheck.Check{
+			Username: args[0],
+			UID:      uid,
+			GID:      gid,
+			Home:     home,
+			Lookup:   &usercheck.RealUserLookup{},
+		}, nil
+	})
 }
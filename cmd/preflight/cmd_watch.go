@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/output"
+)
+
+// watchOptions are the flags of `preflight watch`.
+type watchOptions struct {
+	runOptions
+	interval    time.Duration
+	untilOK     bool
+	untilFail   bool
+	maxDuration time.Duration
+	watchFiles  []string
+}
+
+// filePollInterval is how often watched files are looked at. Polling needs no
+// platform-specific notification API, and a person waiting on an edit does not
+// notice a quarter of a second.
+const filePollInterval = 250 * time.Millisecond
+
+func newWatchCmd(a *app) *cobra.Command {
+	var opts watchOptions
+
+	cmd := &cobra.Command{
+		Use:   "watch [flags] [check [args...]]",
+		Short: "Re-run a check or a .preflight file and print what changes",
+		Long: `Re-run a check, or the checks in a .preflight file, and print a line only
+when a check's status changes.
+
+With a check, watch runs it:     preflight watch --until-ok tcp db:5432
+Without one, it runs the file:   preflight watch --tag database
+
+Flags for watch come before the check; everything after the check's name
+belongs to the check.`,
+		Args: cobra.ArbitraryArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case opts.interval <= 0:
+				return fmt.Errorf("--interval must be positive, got %s", opts.interval)
+			case opts.maxDuration < 0:
+				return fmt.Errorf("--max-duration must not be negative, got %s", opts.maxDuration)
+			case opts.untilOK && opts.untilFail:
+				return errors.New("--until-ok and --until-fail cannot be used together")
+			case opts.jobs < 1:
+				return fmt.Errorf("--jobs must be at least 1, got %d", opts.jobs)
+			}
+			return a.watch(cmd.Context(), args, opts)
+		},
+	}
+	// A check's own flags follow its name, and must not be taken for watch's.
+	cmd.Flags().SetInterspersed(false)
+	addFileFlags(cmd, &opts.runOptions)
+	cmd.Flags().DurationVar(&opts.interval, "interval", 2*time.Second, "pause between runs")
+	cmd.Flags().BoolVar(&opts.untilOK, "until-ok", false, "stop once no check fails")
+	cmd.Flags().BoolVar(&opts.untilFail, "until-fail", false, "stop once a check fails")
+	cmd.Flags().DurationVar(&opts.maxDuration, "max-duration", 0, "stop after this long (default: run until interrupted)")
+	cmd.Flags().StringArrayVar(&opts.watchFiles, "watch-file", nil, "also re-run as soon as this file changes, can be repeated")
+	return cmd
+}
+
+// watch re-runs checks until a stop condition is met, printing transitions.
+//
+// Watching flaky infrastructure used to mean a shell loop around preflight and
+// a screen of identical [OK] lines with the one change somewhere in them. Only
+// a change of status is printed here, with the time it was seen.
+//
+// The exit code is that of the last run, whatever ended the watch: --until-ok
+// exits 0, --until-fail exits 1, and running out of --max-duration reports
+// where things stood. A watch that ends passing can be followed by exec mode,
+// which makes `watch --until-ok ... -- ./app` a wait-for-dependencies entrypoint.
+func (a *app) watch(ctx context.Context, args []string, opts watchOptions) error {
+	if a.collect != nil {
+		return errors.New("watch cannot be used inside a .preflight file")
+	}
+
+	// Before the target, which runs its checks under this context: a check
+	// still running when --max-duration is up is cut short with the watch.
+	if opts.maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.maxDuration)
+		defer cancel()
+	}
+
+	evaluate, err := a.watchTarget(ctx, args, opts)
+	if err != nil || evaluate == nil {
+		return err
+	}
+
+	changed := pollFiles(ctx, opts.watchFiles, filePollInterval)
+
+	w := &watcher{evaluate: evaluate, now: time.Now, print: a.printTransition}
+	for {
+		failed := w.step()
+		a.checkRan = true
+
+		switch {
+		case opts.untilOK && !failed:
+			return nil
+		case opts.untilFail && failed:
+			return ErrCheckFailed
+		}
+
+		select {
+		case <-ctx.Done():
+			if failed {
+				return ErrCheckFailed
+			}
+			return nil
+		case <-time.After(opts.interval):
+		case <-changed:
+		}
+	}
+}
+
+// watchTarget returns what each run evaluates: the check args name, or the
+// selected lines of a .preflight file when args is empty. A nil function with
+// no error means there is nothing to run, as after --help.
+//
+// The file is read again on every run, so an edit to it takes effect without
+// restarting the watch. A file that no longer parses fails that run rather
+// than ending the watch, since the edit that broke it is likely not the last.
+func (a *app) watchTarget(ctx context.Context, args []string, opts watchOptions) (func() []output.Record, error) {
+	if len(args) == 0 {
+		// Read once up front so that a missing file is a usage error.
+		if _, _, err := loadPreflightFile(opts.runOptions); err != nil {
+			return nil, err
+		}
+		return func() []output.Record {
+			preflightPath, commands, err := loadPreflightFile(opts.runOptions)
+			if err != nil {
+				return []output.Record{{
+					Name:    cmp.Or(opts.file, ".preflight"),
+					Type:    "run",
+					Status:  check.StatusFail,
+					Details: []string{err.Error()},
+					Error:   err.Error(),
+				}}
+			}
+			return evaluateFile(ctx, preflightPath, commands, opts.jobs).Checks
+		}, nil
+	}
+
+	var help bytes.Buffer
+	parsed, err := parseLine(args, &help)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", strings.Join(args, " "), err)
+	}
+	if parsed.checker == nil {
+		_, _ = os.Stdout.Write(help.Bytes())
+		return nil, nil
+	}
+	return func() []output.Record {
+		return []output.Record{output.NewRecord(check.Run(ctx, parsed.checker), parsed.cmd.Name())}
+	}, nil
+}
+
+// printTransition writes one change of status in the selected format.
+func (a *app) printTransition(at time.Time, from check.Status, rec output.Record) {
+	if a.format == output.FormatJSON {
+		_ = output.PrintJSON(output.Transition{Time: at, From: from, Record: rec})
+		return
+	}
+	output.PrintTransition(at, from, rec.Result())
+}
+
+// watcher remembers each check's last status between runs.
+type watcher struct {
+	evaluate func() []output.Record
+	now      func() time.Time // injected for testing
+	print    func(at time.Time, from check.Status, rec output.Record)
+
+	// last holds statuses by position, and lastNames the checks they were
+	// for: two lines may share a name, and an edited .preflight file may run
+	// different checks from one run to the next.
+	last      []check.Status
+	lastNames []string
+}
+
+// step runs the checks once, prints those whose status changed, and reports
+// whether any failed. Every check is printed the first time.
+func (w *watcher) step() (failed bool) {
+	records := w.evaluate()
+	at := w.now()
+	names := make([]string, len(records))
+	for i, rec := range records {
+		names[i] = rec.Name
+	}
+	// Different checks from last time start over, every one printed.
+	if !slices.Equal(names, w.lastNames) {
+		w.last = w.last[:0]
+	}
+	w.lastNames = names
+	for i, rec := range records {
+		var from check.Status
+		if i < len(w.last) {
+			from = w.last[i]
+			if from == rec.Status {
+				continue
+			}
+		}
+		w.print(at, from, rec)
+	}
+
+	w.last = w.last[:0]
+	for _, rec := range records {
+		w.last = append(w.last, rec.Status)
+		failed = failed || rec.Status == check.StatusFail
+	}
+	return failed
+}
+
+// pollFiles returns a channel that receives when any of paths is created,
+// removed, or modified. It is never ready when paths is empty.
+func pollFiles(ctx context.Context, paths []string, every time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	if len(paths) == 0 {
+		return changed
+	}
+
+	type state struct {
+		exists  bool
+		size    int64
+		modTime time.Time
+	}
+	look := func() []state {
+		states := make([]state, len(paths))
+		for i, path := range paths {
+			if info, err := os.Stat(path); err == nil {
+				states[i] = state{true, info.Size(), info.ModTime()}
+			}
+		}
+		return states
+	}
+
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		last := look()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			now := look()
+			same := slices.EqualFunc(now, last, func(a, b state) bool {
+				return a.exists == b.exists && a.size == b.size && a.modTime.Equal(b.modTime)
+			})
+			if !same {
+				// A change already waiting covers this one too.
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			last = now
+		}
+	}()
+	return changed
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/output"
+)
+
+func TestWatcher_PrintsOnlyTransitions(t *testing.T) {
+	runs := [][]check.Status{
+		{check.StatusOK, check.StatusFail},
+		{check.StatusOK, check.StatusFail},
+		{check.StatusFail, check.StatusFail},
+		{check.StatusFail, check.StatusOK},
+	}
+	type printed struct {
+		name     string
+		from, to check.Status
+	}
+	var got []printed
+	n := 0
+	w := &watcher{
+		evaluate: func() []output.Record {
+			statuses := runs[n]
+			n++
+			return []output.Record{{Name: "tcp: db", Status: statuses[0]}, {Name: "env: X", Status: statuses[1]}}
+		},
+		now: time.Now,
+		print: func(_ time.Time, from check.Status, rec output.Record) {
+			got = append(got, printed{rec.Name, from, rec.Status})
+		},
+	}
+
+	var failed []bool
+	for range runs {
+		failed = append(failed, w.step())
+	}
+
+	want := []printed{
+		{"tcp: db", "", check.StatusOK},
+		{"env: X", "", check.StatusFail},
+		{"tcp: db", check.StatusOK, check.StatusFail},
+		{"env: X", check.StatusFail, check.StatusOK},
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, []bool{true, true, true, true}, failed)
+}
+
+func TestWatchCmd_StopConditions(t *testing.T) {
+	t.Run("until-ok stops at the first pass", func(t *testing.T) {
+		a := newApp()
+		require.NoError(t, a.watch(t.Context(), []string{"env", "PATH"}, watchOptions{untilOK: true, interval: time.Hour}))
+		assert.True(t, a.checkRan, "a passing watch may be followed by exec mode")
+	})
+
+	t.Run("until-fail stops at the first failure", func(t *testing.T) {
+		err := newApp().watch(t.Context(), []string{"env", "PREFLIGHT_WATCH_TEST_UNSET"}, watchOptions{untilFail: true, interval: time.Hour})
+		require.ErrorIs(t, err, ErrCheckFailed)
+	})
+
+	t.Run("max-duration reports the last status", func(t *testing.T) {
+		opts := watchOptions{interval: 5 * time.Millisecond, maxDuration: 30 * time.Millisecond}
+		require.NoError(t, newApp().watch(t.Context(), []string{"env", "PATH"}, opts))
+		require.ErrorIs(t, newApp().watch(t.Context(), []string{"env", "PREFLIGHT_WATCH_TEST_UNSET"}, opts), ErrCheckFailed)
+	})
+
+	t.Run("max-duration cuts a running check short", func(t *testing.T) {
+		opts := watchOptions{interval: time.Hour, maxDuration: 50 * time.Millisecond}
+		start := time.Now()
+		err := newApp().watch(t.Context(), []string{"cmd", "sleep", "--version-cmd", "10", "--timeout", "30s"}, opts)
+		require.ErrorIs(t, err, ErrCheckFailed)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("until-ok outlasts a failure", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ready")
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			_ = os.WriteFile(path, nil, 0o600)
+		}()
+		opts := watchOptions{untilOK: true, interval: 5 * time.Millisecond, maxDuration: 5 * time.Second}
+		require.NoError(t, newApp().watch(t.Context(), []string{"file", path}, opts))
+	})
+}
+
+func TestWatchCmd_Arguments(t *testing.T) {
+	t.Run("flags after the check belong to it", func(t *testing.T) {
+		_, err := executeCommand("watch", "--until-ok", "env", "PREFLIGHT_WATCH_TEST_UNSET", "--not-set")
+		require.NoError(t, err)
+	})
+
+	t.Run("a usage mistake names the check", func(t *testing.T) {
+		_, err := executeCommand("watch", "--until-ok", "env", "--no-such-flag")
+		require.ErrorContains(t, err, "env --no-such-flag: unknown flag")
+	})
+
+	t.Run("flag validation", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"watch", "--interval", "0s", "env", "PATH"},
+			{"watch", "--max-duration", "-1s", "env", "PATH"},
+			{"watch", "--until-ok", "--until-fail", "env", "PATH"},
+		} {
+			_, err := executeCommand(args...)
+			require.ErrorContains(t, err, "--", "%q", args)
+		}
+	})
+
+	t.Run("without a check, the file is watched", func(t *testing.T) {
+		path := writeTempFile(t, ".preflight", "env PATH\n[later]\nenv PREFLIGHT_WATCH_TEST_UNSET\n")
+		_, err := executeCommand("watch", "--until-ok", "--file", path, "--skip-tag", "later")
+		require.NoError(t, err)
+	})
+}
+
+func TestWatchCmd_RereadsFile(t *testing.T) {
+	path := writeTempFile(t, ".preflight", "env PREFLIGHT_WATCH_TEST_UNSET\n")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = os.WriteFile(path, []byte("env PATH\n"), 0o600)
+	}()
+	opts := watchOptions{runOptions: runOptions{file: path}, untilOK: true, interval: 5 * time.Millisecond, maxDuration: 5 * time.Second}
+	require.NoError(t, newApp().watch(t.Context(), nil, opts))
+
+	t.Run("a broken edit fails the run", func(t *testing.T) {
+		path := writeTempFile(t, ".preflight", "env PATH\n")
+		evaluate, err := newApp().watchTarget(t.Context(), nil, watchOptions{runOptions: runOptions{file: path}})
+		require.NoError(t, err)
+		require.NoError(t, os.Remove(path))
+
+		records := evaluate()
+		require.Len(t, records, 1)
+		assert.Equal(t, check.StatusFail, records[0].Status)
+	})
+}
+
+func TestWatcher_NewChecksStartOver(t *testing.T) {
+	runs := [][]output.Record{
+		{{Name: "env: A", Status: check.StatusOK}},
+		{{Name: "env: B", Status: check.StatusOK}},
+	}
+	var from []check.Status
+	n := 0
+	w := &watcher{
+		evaluate: func() []output.Record { n++; return runs[n-1] },
+		now:      time.Now,
+		print:    func(_ time.Time, f check.Status, _ output.Record) { from = append(from, f) },
+	}
+	w.step()
+	w.step()
+	assert.Equal(t, []check.Status{"", ""}, from)
+}
+
+func TestPollFiles(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "config")
+
+	changed := pollFiles(ctx, []string{path}, time.Millisecond)
+	select {
+	case <-changed:
+		t.Fatal("nothing changed yet")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("x"), 0o600))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("creating the file was not noticed")
+	}
+
+	t.Run("no files never change", func(t *testing.T) {
+		select {
+		case <-pollFiles(ctx, nil, time.Millisecond):
+			t.Fatal("no files to watch")
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/yamlcheck"
+)
+
+func newYAMLCmd(a *app) *cobra.Command {
+	var hasKey, key, exact, match string
+	var document int
+	var allDocuments bool
+
+	cmd := &cobra.Command{
+		Use:   "yaml <file>",
+		Short: "Validate YAML file and check values",
+		Long: `Validate a YAML file and check values, as preflight json does for JSON.
+
+Every document in a stream separated by --- must parse. Key checks in a file
+with more than one document need --document or --all-documents.
+
+Examples:
+  preflight yaml values.yaml                                   # yq e 'true' values.yaml
+  preflight yaml values.yaml --key image.tag --match '^v[0-9]'
+  preflight yaml deploy.yaml --document 0 --key spec.replicas --exact 3
+  preflight yaml deploy.yaml --all-documents --has-key metadata.name`,
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&hasKey, "has-key", "", "check that key exists (dot notation for nested)")
+	cmd.Flags().StringVar(&key, "key", "", "key to check value of (dot notation for nested)")
+	cmd.Flags().StringVar(&exact, "exact", "", "exact value required (requires --key)")
+	cmd.Flags().StringVar(&match, "match", "", "regex pattern for value (requires --key)")
+	cmd.Flags().IntVar(&document, "document", 0, "check keys in this document of a --- stream, counting from 0")
+	cmd.Flags().BoolVar(&allDocuments, "all-documents", false, "check keys in every document of a --- stream")
+
+	return a.checkCommand(cmd, func(cmd *cobra.Command, args []string) (Checker, error) {
+		exactGiven := cmd.Flags().Changed("exact")
+		if (exactGiven || match != "") && key == "" {
+			return nil, errors.New("--exact and --match require --key to be set")
+		}
+		if err := validateRegex("--match", match); err != nil {
+			return nil, err
+		}
+		documentGiven := cmd.Flags().Changed("document")
+		if documentGiven && allDocuments {
+			return nil, errors.New("only one of --document, --all-documents can be specified")
+		}
+		if document < 0 {
+			return nil, fmt.Errorf("--document must not be negative, got %d", document)
+		}
+
+		c := &yamlcheck.Check{
+			File:         args[0],
+			HasKey:       hasKey,
+			Key:          key,
+			Match:        match,
+			AllDocuments: allDocuments,
+			FS:           &yamlcheck.RealFileSystem{},
+		}
+
+		// Only set if given, so `--exact ""` asserts the value is the empty string
+		// rather than reading as "no --exact".
+		if exactGiven {
+			c.Exact = &exact
+		}
+		// Likewise `--document 0`, which is the first document, not no choice.
+		if documentGiven {
+			c.Document = &document
+		}
+
+		return c, nil
+	})
+}
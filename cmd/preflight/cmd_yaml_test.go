@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYAMLCommand_Flags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no file", []string{"yaml"}, "accepts 1 arg(s)"},
+		{"exact without key", []string{"yaml", "values.yaml", "--exact", "x"}, "--exact and --match require --key to be set"},
+		{"bad regex", []string{"yaml", "values.yaml", "--key", "a", "--match", "(["}, "invalid --match regex"},
+		{"both document modes", []string{"yaml", "values.yaml", "--document", "0", "--all-documents"}, "only one of --document, --all-documents"},
+		{"negative document", []string{"yaml", "values.yaml", "--document", "-1"}, "--document must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := executeCommand(tt.args...)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestYAMLCommand(t *testing.T) {
+	path := writeTempFile(t, "deploy.yaml", "kind: Deployment\nspec:\n  replicas: 3\n---\nkind: Service\n")
+
+	_, err := executeCommand("yaml", path)
+	require.NoError(t, err)
+
+	_, err = executeCommand("yaml", path, "--document", "0", "--key", "spec.replicas", "--exact", "3")
+	require.NoError(t, err)
+
+	_, err = executeCommand("yaml", path, "--all-documents", "--has-key", "kind")
+	require.NoError(t, err)
+
+	_, err = executeCommand("yaml", path, "--all-documents", "--has-key", "spec")
+	assert.ErrorIs(t, err, ErrCheckFailed)
+
+	_, err = executeCommand("yaml", writeTempFile(t, "bad.yaml", "kind: [\n"))
+	assert.ErrorIs(t, err, ErrCheckFailed)
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"os"
 
 	"github.com/vertti/preflight/pkg/exec"
+	"github.com/vertti/preflight/pkg/usercheck"
 )
 
 // extractExecArgs finds "--" in args and returns everything after it.
@@ -23,16 +26,51 @@ func extractExecArgs(args *[]string) []string {
 // executor is the default exec implementation, can be overridden for testing.
 var executor exec.Executor = &exec.RealExecutor{}
 
+// initExecutor runs the command under --init, can be overridden for testing.
+var initExecutor exec.Executor = &exec.InitExecutor{}
+
+// switchUser becomes the --user identity, can be overridden for testing.
+var switchUser = func(spec string) error {
+	cred, err := exec.ResolveUser(spec, &usercheck.RealUserLookup{}, os.Getgid())
+	if err != nil {
+		return err
+	}
+	return exec.SwitchUser(cred)
+}
+
 // runExec executes the command specified in execArgs.
 // Returns an error if the exec fails, or if no check ran — handing control to
 // the target when nothing was verified would turn the gate into a no-op that
 // reports success.
-func runExec(execArgs []string) error {
+//
+// With --init, the command runs as a child instead of replacing preflight.
+// Replaced, it would be PID 1 itself: signals it sets no handler for are
+// ignored there, and the orphans it inherits are never reaped.
+//
+// With --user, preflight becomes that user first, so the checks run with the
+// privileges they need — fixing ownership of a volume, reading a root-only
+// secret — and the command without them. The same identity is inherited by the
+// command under --init, which leaves nothing for gosu or su-exec to do.
+func (a *app) runExec(execArgs []string) error {
 	if len(execArgs) == 0 {
+		switch {
+		case a.initMode:
+			return errors.New("--init needs a command to run after --")
+		case a.user != "":
+			return errors.New("--user needs a command to run after --")
+		}
 		return nil
 	}
-	if !checkRan {
+	if !a.checkRan {
 		return errors.New("refusing to exec: no check ran before --")
 	}
+	if a.user != "" {
+		if err := switchUser(a.user); err != nil {
+			return fmt.Errorf("--user %s: %w", a.user, err)
+		}
+	}
+	if a.initMode {
+		return initExecutor.Exec(execArgs[0], execArgs[1:])
+	}
 	return executor.Exec(execArgs[0], execArgs[1:])
 }
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/vertti/preflight/pkg/check"
@@ -21,20 +23,20 @@ func (m *mockExecutor) Exec(name string, args []string) error {
 
 type passingChecker struct{}
 
-func (passingChecker) Run() check.Result {
+func (passingChecker) RunContext(context.Context) check.Result {
 	return check.Result{Name: "stub", Status: check.StatusOK}
 }
 
 type failingChecker struct{}
 
-func (failingChecker) Run() check.Result {
+func (failingChecker) RunContext(context.Context) check.Result {
 	return check.Result{Name: "stub", Status: check.StatusFail}
 }
 
 func TestRunExec_RequiresACheckToHaveRun(t *testing.T) {
 	originalExecutor := executor
-	originalCheckRan := checkRan
-	defer func() { executor = originalExecutor; checkRan = originalCheckRan }()
+	defer func() { executor = originalExecutor }()
+	a := newApp()
 
 	t.Run("refuses to exec when no check ran", func(t *testing.T) {
 		execCalled := false
@@ -42,9 +44,9 @@ func TestRunExec_RequiresACheckToHaveRun(t *testing.T) {
 			execCalled = true
 			return nil
 		}}
-		checkRan = false
+		a.checkRan = false
 
-		err := runExec([]string{"./myapp"})
+		err := a.runExec([]string{"./myapp"})
 		if err == nil {
 			t.Fatal("runExec() = nil, want error when no check ran")
 		}
@@ -59,9 +61,9 @@ func TestRunExec_RequiresACheckToHaveRun(t *testing.T) {
 			execCalled = true
 			return nil
 		}}
-		checkRan = true
+		a.checkRan = true
 
-		if err := runExec([]string{"./myapp"}); err != nil {
+		if err := a.runExec([]string{"./myapp"}); err != nil {
 			t.Fatalf("runExec() = %v, want nil", err)
 		}
 		if !execCalled {
@@ -70,26 +72,25 @@ func TestRunExec_RequiresACheckToHaveRun(t *testing.T) {
 	})
 
 	t.Run("no exec args is not an error even without a check", func(t *testing.T) {
-		checkRan = false
-		if err := runExec(nil); err != nil {
+		a.checkRan = false
+		if err := a.runExec(nil); err != nil {
 			t.Errorf("runExec(nil) = %v, want nil", err)
 		}
 	})
 }
 
 func TestRunCheckRecordsThatItRan(t *testing.T) {
-	original := checkRan
-	defer func() { checkRan = original }()
-
-	checkRan = false
-	_ = runCheck(passingChecker{})
-	if !checkRan {
+	a := newApp()
+	a.root.SetContext(t.Context())
+	_ = a.runCheck(a.root, passingChecker{})
+	if !a.checkRan {
 		t.Error("runCheck did not record that a check ran")
 	}
 
-	checkRan = false
-	_ = runCheck(failingChecker{})
-	if !checkRan {
+	a = newApp()
+	a.root.SetContext(t.Context())
+	_ = a.runCheck(a.root, failingChecker{})
+	if !a.checkRan {
 		t.Error("runCheck did not record a failing check as having run")
 	}
 }
@@ -97,19 +98,19 @@ func TestRunCheckRecordsThatItRan(t *testing.T) {
 func TestRunExec(t *testing.T) {
 	// Save original executor and restore after test
 	originalExecutor := executor
-	originalCheckRan := checkRan
-	checkRan = true
-	defer func() { executor = originalExecutor; checkRan = originalCheckRan }()
+	defer func() { executor = originalExecutor }()
+	a := newApp()
+	a.checkRan = true
 
 	t.Run("empty args returns nil", func(t *testing.T) {
-		err := runExec([]string{})
+		err := a.runExec([]string{})
 		if err != nil {
 			t.Errorf("runExec([]) = %v, want nil", err)
 		}
 	})
 
 	t.Run("nil args returns nil", func(t *testing.T) {
-		err := runExec(nil)
+		err := a.runExec(nil)
 		if err != nil {
 			t.Errorf("runExec(nil) = %v, want nil", err)
 		}
@@ -127,7 +128,7 @@ func TestRunExec(t *testing.T) {
 			},
 		}
 
-		err := runExec([]string{"./myapp", "arg1", "arg2"})
+		err := a.runExec([]string{"./myapp", "arg1", "arg2"})
 		if err != nil {
 			t.Errorf("runExec() = %v, want nil", err)
 		}
@@ -151,7 +152,7 @@ func TestRunExec(t *testing.T) {
 			},
 		}
 
-		err := runExec([]string{"./myapp"})
+		err := a.runExec([]string{"./myapp"})
 		if err != nil {
 			t.Errorf("runExec() = %v, want nil", err)
 		}
@@ -171,13 +172,143 @@ func TestRunExec(t *testing.T) {
 			},
 		}
 
-		err := runExec([]string{"./myapp"})
+		err := a.runExec([]string{"./myapp"})
 		if !errors.Is(err, expectedErr) {
 			t.Errorf("runExec() = %v, want %v", err, expectedErr)
 		}
 	})
 }
 
+func TestRunExec_Init(t *testing.T) {
+	originalExecutor, originalInitExecutor := executor, initExecutor
+	defer func() { executor, initExecutor = originalExecutor, originalInitExecutor }()
+
+	var used string
+	executor = &mockExecutor{execFunc: func(string, []string) error {
+		used = "exec"
+		return nil
+	}}
+	initExecutor = &mockExecutor{execFunc: func(string, []string) error {
+		used = "init"
+		return nil
+	}}
+
+	a := newApp()
+	a.execMode = true
+	if _, err := a.execute(context.Background(), []string{"--init", "env", "PATH"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.runExec([]string{"./myapp"}); err != nil {
+		t.Fatalf("runExec() = %v, want nil", err)
+	}
+	if used != "init" {
+		t.Errorf("runExec used %q, want the init executor", used)
+	}
+
+	t.Run("init without a command is an error", func(t *testing.T) {
+		if err := a.runExec(nil); err == nil {
+			t.Error("runExec(nil) = nil, want an error for --init with nothing to run")
+		}
+	})
+}
+
+func TestRunExec_User(t *testing.T) {
+	originalExecutor, originalSwitchUser := executor, switchUser
+	defer func() { executor, switchUser = originalExecutor, originalSwitchUser }()
+
+	var steps []string
+	executor = &mockExecutor{execFunc: func(string, []string) error {
+		steps = append(steps, "exec")
+		return nil
+	}}
+	switchUser = func(spec string) error {
+		steps = append(steps, "switch to "+spec)
+		if spec == "nobody-here" {
+			return errors.New(`unknown user "nobody-here"`)
+		}
+		return nil
+	}
+
+	a := newApp()
+	a.execMode = true
+	if _, err := a.execute(context.Background(), []string{"--output", "json", "--user", "app:app", "env", "PATH"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.runExec([]string{"./myapp"}); err != nil {
+		t.Fatalf("runExec() = %v, want nil", err)
+	}
+	if want := []string{"switch to app:app", "exec"}; !reflect.DeepEqual(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+
+	t.Run("a user that cannot be switched to does not exec", func(t *testing.T) {
+		steps = nil
+		a.user = "nobody-here"
+		if err := a.runExec([]string{"./myapp"}); err == nil {
+			t.Error("runExec() = nil, want an error")
+		}
+		if want := []string{"switch to nobody-here"}; !reflect.DeepEqual(steps, want) {
+			t.Errorf("steps = %v, want %v", steps, want)
+		}
+	})
+
+	t.Run("no check, no switch", func(t *testing.T) {
+		steps = nil
+		b := newApp()
+		b.user = "app"
+		if err := b.runExec([]string{"./myapp"}); err == nil {
+			t.Error("runExec() = nil, want an error when no check ran")
+		}
+		if len(steps) != 0 {
+			t.Errorf("steps = %v, want none", steps)
+		}
+	})
+
+	t.Run("user without a command is an error", func(t *testing.T) {
+		if err := a.runExec(nil); err == nil {
+			t.Error("runExec(nil) = nil, want an error for --user with nothing to run")
+		}
+	})
+}
+
+// --init and --user belong to the root command and to exec mode alone, so a
+// subcommand is free to have a --user of its own.
+func TestExecFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		execMode bool
+		args     []string
+		wantErr  string
+	}{
+		{"without a command to run", false, []string{"--init", "env", "PATH"}, "--init and --user need a command to run after --"},
+		{"user without a command to run", false, []string{"--user=app", "env", "PATH"}, "--init and --user need a command to run after --"},
+		{"after the subcommand", true, []string{"env", "PATH", "--init"}, "unknown flag: --init"},
+		{"on the user command", true, []string{"user", "app", "--user", "app"}, "unknown flag: --user"},
+		{"user after the subcommand", true, []string{"env", "PATH", "--user", "app"}, "unknown flag: --user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newApp()
+			a.execMode = tt.execMode
+			_, err := a.execute(context.Background(), tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("execute(%q) = %v, want %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("parsed before the subcommand", func(t *testing.T) {
+		a := newApp()
+		a.execMode = true
+		if _, err := a.execute(context.Background(), []string{"-v", "--user", "app:app", "--init", "env", "PATH"}); err != nil {
+			t.Fatal(err)
+		}
+		if !a.initMode || a.user != "app:app" || !a.verbose {
+			t.Errorf("initMode = %v, user = %q, verbose = %v", a.initMode, a.user, a.verbose)
+		}
+	})
+}
+
 // The set of subcommands must come from cobra rather than a hand-kept list.
 // The list had already drifted: it named "version", which is not a command, and
 // a command added without updating it would be mistaken for a script path
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,36 +13,20 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/spf13/cobra"
-	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// executeCommand runs args against a fresh command tree, as main does.
 func executeCommand(args ...string) (string, error) {
 	buf := new(bytes.Buffer)
-	rootCmd.SetOut(buf)
-	rootCmd.SetErr(buf)
-	rootCmd.SetArgs(args)
-	resetFlags(rootCmd)
-	err := rootCmd.Execute()
+	a := newApp()
+	a.root.SetOut(buf)
+	a.root.SetErr(buf)
+	_, err := a.execute(context.Background(), args)
 	return buf.String(), err
 }
 
-func resetFlags(cmd *cobra.Command) {
-	cmd.Flags().VisitAll(func(f *pflag.Flag) {
-		if f.Value.Type() == "stringSlice" || f.Value.Type() == "intSlice" {
-			_ = f.Value.Set("")
-		} else {
-			_ = f.Value.Set(f.DefValue)
-		}
-		f.Changed = false
-	})
-	for _, sub := range cmd.Commands() {
-		resetFlags(sub)
-	}
-}
-
 func writeTempFile(t *testing.T, name, content string) string {
 	t.Helper()
 	path := filepath.Join(t.TempDir(), name)
@@ -86,6 +71,15 @@ func TestEnvCommand(t *testing.T) {
 	}
 }
 
+// --warn reports a failure without failing the command, so a new advisory
+// check cannot break a pipeline on the day it is added.
+func TestWarnFlag(t *testing.T) {
+	_, err := executeCommand("env", "--warn", "PREFLIGHT_NONEXISTENT_VAR_12345")
+	assert.NoError(t, err)
+	_, err = executeCommand("env", "--warn", "--not-set", "PREFLIGHT_NONEXISTENT_VAR_12345")
+	assert.NoError(t, err)
+}
+
 func TestEnvExactMatch(t *testing.T) {
 	t.Setenv("PREFLIGHT_TEST_VAR", "expected_value")
 	_, err := executeCommand("env", "--exact", "expected_value", "PREFLIGHT_TEST_VAR")
@@ -312,6 +306,42 @@ func TestHashCommand(t *testing.T) {
 	})
 }
 
+func TestTemplateCommand(t *testing.T) {
+	t.Run("renders to dest", func(t *testing.T) {
+		t.Setenv("PREFLIGHT_TEMPLATE_TEST", "db")
+		src := writeTempFile(t, "app.tmpl", `host={{ required "PREFLIGHT_TEMPLATE_TEST" }}`)
+		dest := filepath.Join(t.TempDir(), "app.conf")
+		_, err := executeCommand("template", "--mode", "0600", src, dest)
+		require.NoError(t, err)
+
+		content, err := os.ReadFile(dest)
+		require.NoError(t, err)
+		assert.Equal(t, "host=db", string(content))
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(dest)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		}
+	})
+
+	t.Run("missing required variable writes nothing", func(t *testing.T) {
+		src := writeTempFile(t, "app.tmpl", `{{ required "PREFLIGHT_NONEXISTENT_VAR_12345" }}`)
+		dest := filepath.Join(t.TempDir(), "app.conf")
+		_, err := executeCommand("template", src, dest)
+		require.Error(t, err)
+		assert.NoFileExists(t, dest)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := executeCommand("template", "--mode", "0o644", "a.tmpl", "a.conf")
+		require.ErrorContains(t, err, `--mode: invalid octal mode "0o644"`)
+
+		// The same bound as file --mode.
+		_, err = executeCommand("template", "--mode", "17777", "a.tmpl", "a.conf")
+		require.ErrorContains(t, err, "expected at most 7777")
+	})
+}
+
 func TestGitCommand(t *testing.T) {
 	// "clean flag" used to live here, asserting inside `if err != nil` with no
 	// else. It could not fail: a clean tree asserted nothing, and a dirty tree
@@ -342,9 +372,9 @@ func TestGitCommand(t *testing.T) {
 }
 
 func TestRunCommand(t *testing.T) {
-	// The command loop is unit-tested in cmd_run_test.go against an injected
-	// runner; the real spawn and exit-code propagation are covered by
-	// TestIntegration_Run. This file only covers argument handling.
+	// The command loop is unit-tested in cmd_run_test.go; exit-code
+	// propagation through the built binary is covered by TestIntegration_Run.
+	// This file only covers argument handling.
 	t.Run("nonexistent file", func(t *testing.T) {
 		_, err := executeCommand("run", "--file", "/nonexistent/.preflight")
 		assert.Error(t, err)
@@ -352,7 +382,7 @@ func TestRunCommand(t *testing.T) {
 }
 
 func TestSubcommandHelp(t *testing.T) {
-	subcommands := []string{"env", "sys", "cmd", "file", "user", "resource", "tcp", "json", "hash", "git", "run", "http", "prometheus"}
+	subcommands := []string{"env", "sys", "cmd", "file", "user", "resource", "tcp", "json", "hash", "git", "run", "http", "prometheus", "template", "pkg", "proc", "cert", "tls", "yaml", "lint", "fmt"}
 
 	for _, subcmd := range subcommands {
 		t.Run(subcmd, func(t *testing.T) {
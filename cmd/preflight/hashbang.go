@@ -17,29 +17,28 @@ func realFileChecker(path string) bool {
 // transformArgsForHashbang detects hashbang invocation and transforms args.
 // When preflight is invoked as a hashbang interpreter (e.g., #!/usr/bin/env preflight),
 // the first arg is the script file path. This transforms ["preflight", "script.pf"]
-// into ["preflight", "run"] and sets runFile to "script.pf".
-func transformArgsForHashbang(args []string, checkFile fileChecker) (newArgs []string, filePath string) {
+// into ["preflight", "run", "--file", "script.pf"].
+func transformArgsForHashbang(args []string, checkFile fileChecker) []string {
 	if len(args) <= 1 {
-		return args, ""
+		return args
 	}
 
 	firstArg := args[1]
 
 	// Skip if it's a flag
 	if strings.HasPrefix(firstArg, "-") {
-		return args, ""
+		return args
 	}
 
 	// Skip if it's a known subcommand
 	if isKnownSubcommand(firstArg) {
-		return args, ""
+		return args
 	}
 
 	// Check if it's a file - if so, treat as hashbang invocation
 	if checkFile(firstArg) {
-		newArgs := append([]string{args[0], "run"}, args[2:]...)
-		return newArgs, firstArg
+		return append([]string{args[0], "run", "--file", firstArg}, args[2:]...)
 	}
 
-	return args, ""
+	return args
 }
@@ -6,25 +6,31 @@ import (
 )
 
 func main() {
-	var file string
-	os.Args, file = transformArgsForHashbang(os.Args, realFileChecker)
-	if file != "" {
-		runFile = file
-	}
+	os.Args = transformArgsForHashbang(os.Args, realFileChecker)
 
 	// Extract exec args (everything after "--")
 	execArgs := extractExecArgs(&os.Args)
 
-	// ExecuteC returns the command that actually ran, so usage errors can show
-	// that command's usage rather than the root's.
-	cmd, err := rootCmd.ExecuteC()
+	ctx, stopSignals := notifySignals()
+	a := newApp()
+	a.execMode = len(execArgs) > 0
+	cmd, err := a.execute(ctx, os.Args[1:])
+	// From here a signal is the exec target's to handle, or --init's to forward.
+	interrupted := stopSignals()
 	if err != nil {
 		reportExecuteError(cmd, err, os.Stderr)
-		os.Exit(1)
+		os.Exit(exitCode(interrupted))
+	}
+
+	// serve and watch end cleanly on a signal, but the checks that ran are no
+	// reason to start the command when preflight was told to stop.
+	if interrupted != nil && len(execArgs) > 0 {
+		fmt.Fprintf(os.Stderr, "exec: not started: %v\n", interrupted)
+		os.Exit(exitCode(interrupted))
 	}
 
 	// Checks passed - exec into command if args were provided
-	if err := runExec(execArgs); err != nil {
+	if err := a.runExec(execArgs); err != nil {
 		fmt.Fprintf(os.Stderr, "exec: %v\n", err)
 		os.Exit(1)
 	}
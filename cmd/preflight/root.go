@@ -1,41 +1,254 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/vertti/preflight/pkg/output"
 )
 
 // Version is set at build time via ldflags
 var Version = "dev"
 
+// app is one invocation of the command tree: the tree itself, the flags that
+// apply to every command, and what happened while it ran.
+//
+// Flags used to be package-level variables bound once in init(). A cobra
+// command built that way cannot be run twice safely — a slice flag from one
+// run leaks into the next — which is what kept .preflight lines out of this
+// process. Every invocation now builds a tree of its own with newApp.
+type app struct {
+	root *cobra.Command
+
+	outputFlag string        // raw --output value
+	format     output.Format // what it resolved to
+
+	// checkRan records that a check actually executed. Exec mode is gated on
+	// it: the root command has no RunE, so `preflight -- ./app` and
+	// `preflight --help -- ./app` reach the end of Execute() with a nil error,
+	// which would otherwise be indistinguishable from "every check passed".
+	checkRan bool
+
+	// execMode is set when a command follows --, which is all --init and
+	// --user apply to.
+	execMode bool
+
+	// init runs the exec target as a child with preflight staying on as its
+	// init, rather than replacing preflight with it.
+	initMode bool
+
+	// user is who the exec target runs as, if not preflight's own user.
+	user string
+
+	// verbose adds how long each check took to its result line.
+	verbose bool
+
+	// deadline bounds the whole invocation; stopDeadline releases its timer.
+	deadline     time.Duration
+	stopDeadline context.CancelFunc
+
+	// collect, when set, receives the Checker a command builds instead of the
+	// command running it. This is how a .preflight line becomes a check without
+	// a process of its own.
+	collect func(cmd *cobra.Command, c Checker)
+}
+
+// newApp builds a fresh command tree.
+func newApp() *app {
+	a := &app{format: output.FormatText}
+	a.root = newRootCmd(a)
+	return a
+}
+
 // SilenceUsage and SilenceErrors are set because a failing check is the tool's
 // normal operating mode, not a usage mistake. Cobra treats any error from RunE
 // as a usage error and dumps the full flag list, which buried the one line that
 // mattered. reportExecuteError puts the usage output back for real usage errors.
-var rootCmd = &cobra.Command{
-	Use:           "preflight",
-	Short:         "Docker preflight checks for your runtime environment",
-	Long:          "Preflight is a CLI tool for running sanity checks on container and CI environments.",
-	Version:       Version,
-	SilenceUsage:  true,
-	SilenceErrors: true,
+func newRootCmd(a *app) *cobra.Command {
+	root := &cobra.Command{
+		Use:           "preflight",
+		Short:         "Docker preflight checks for your runtime environment",
+		Long:          "Preflight is a CLI tool for running sanity checks on container and CI environments.",
+		Version:       Version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+
+		PersistentPreRunE: a.beforeRun,
+	}
+	root.PersistentFlags().StringVar(&a.outputFlag, "output", "text", "output format: text or json (env: PREFLIGHT_OUTPUT)")
+	root.Flags().BoolVar(&a.initMode, "init", false, "with -- <command>, stay on as its init: forward signals and reap zombies")
+	root.Flags().StringVar(&a.user, "user", "", "with -- <command>, run it as this user[:group], by name or id")
+	root.PersistentFlags().BoolVarP(&a.verbose, "verbose", "v", false, "show how long each check took")
+	root.PersistentFlags().DurationVar(&a.deadline, "deadline", 0, "fail whatever is still running after this long (default: no limit)")
+
+	root.AddCommand(
+		newCertCmd(a),
+		newCmdCmd(a),
+		newEnvCmd(a),
+		newFileCmd(a),
+		newFmtCmd(a),
+		newGitCmd(a),
+		newHashCmd(a),
+		newHTTPCmd(a),
+		newJSONCmd(a),
+		newLintCmd(a),
+		newPkgCmd(a),
+		newProcCmd(a),
+		newPrometheusCmd(a),
+		newResourceCmd(a),
+		newRunCmd(a),
+		newServeCmd(a),
+		newSysCmd(a),
+		newTCPCmd(a),
+		newTemplateCmd(a),
+		newTLSCmd(a),
+		newUserCmd(a),
+		newWatchCmd(a),
+		newYAMLCmd(a),
+	)
+	return root
+}
+
+// execute runs the command tree on args under ctx, returning the command that
+// ran so usage errors can show that command's usage rather than the root's.
+func (a *app) execute(ctx context.Context, args []string) (*cobra.Command, error) {
+	defer func() {
+		if a.stopDeadline != nil {
+			a.stopDeadline()
+		}
+	}()
+	args, err := a.parseExecFlags(args)
+	if err != nil {
+		return a.root, err
+	}
+	a.root.SetArgs(a.addPlugin(args))
+	return a.root.ExecuteContextC(ctx)
 }
 
-// isKnownSubcommand reports whether name is one of preflight's own commands.
-// Derived from cobra rather than a hand-kept list: the list had drifted (it
-// named "version", which is not a command), and adding a command without
-// updating it meant a file of that name in the working directory would be
-// mistaken for a hashbang script.
+// parseExecFlags takes --init and --user from before the command's name, where
+// they are written, and returns the rest of args for cobra.
+//
+// They are the root command's own flags rather than persistent ones: they only
+// mean something with a command after --, and as persistent flags every
+// subcommand accepted them, and they claimed names such as --user that a
+// subcommand should be free to use. cobra would look for them on the
+// subcommand, so they are parsed here instead.
+func (a *app) parseExecFlags(args []string) ([]string, error) {
+	own := a.root.LocalNonPersistentFlags()
+	end := commandIndex(args, a.root.PersistentFlags(), own)
+	if end < 0 {
+		end = len(args)
+		if i := slices.Index(args, "--"); i >= 0 {
+			end = i
+		}
+	}
+
+	var execFlags, rest []string
+	for i := 0; i < end; i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !strings.HasPrefix(arg, "--") || own.Lookup(name) == nil {
+			rest = append(rest, arg)
+			if !hasValue && takesValue(arg, []*pflag.FlagSet{a.root.PersistentFlags()}) && i+1 < end {
+				i++
+				rest = append(rest, args[i])
+			}
+			continue
+		}
+		execFlags = append(execFlags, arg)
+		if !hasValue && takesValue(arg, []*pflag.FlagSet{own}) && i+1 < end {
+			i++
+			execFlags = append(execFlags, args[i])
+		}
+	}
+	if len(execFlags) == 0 {
+		return args, nil
+	}
+	if !a.execMode {
+		return nil, errors.New("--init and --user need a command to run after --")
+	}
+	if err := own.Parse(execFlags); err != nil {
+		return nil, err
+	}
+	return append(rest, args[end:]...), nil
+}
+
+// beforeRun settles what every command needs before it starts.
+func (a *app) beforeRun(cmd *cobra.Command, args []string) error {
+	if err := a.resolveOutputFormat(cmd, args); err != nil {
+		return err
+	}
+	return a.applyDeadline(cmd)
+}
+
+// applyDeadline puts --deadline on the command's context. Retries, waits and
+// every line of a .preflight file share it, so a deploy step given five
+// minutes gets an answer in five minutes, with whatever was still running
+// named as interrupted.
+func (a *app) applyDeadline(cmd *cobra.Command) error {
+	switch {
+	case a.deadline < 0:
+		return fmt.Errorf("--deadline must not be negative, got %s", a.deadline)
+	case a.deadline == 0:
+		return nil
+	case a.collect != nil:
+		// A line is only parsed here; it runs later under the file's context.
+		return errors.New("--deadline cannot be used inside a .preflight file")
+	}
+	ctx, cancel := context.WithTimeoutCause(cmd.Context(), a.deadline, fmt.Errorf("--deadline of %s exceeded", a.deadline))
+	a.stopDeadline = cancel
+	cmd.SetContext(ctx)
+	return nil
+}
+
+// resolveOutputFormat settles the format before any check runs. The flag wins
+// over PREFLIGHT_OUTPUT so a single invocation can override what an image sets.
+func (a *app) resolveOutputFormat(cmd *cobra.Command, _ []string) error {
+	value := a.outputFlag
+	if !cmd.Flags().Changed("output") {
+		if env, ok := os.LookupEnv("PREFLIGHT_OUTPUT"); ok {
+			value = env
+		}
+	}
+
+	format, err := output.ParseFormat(value)
+	if err != nil {
+		return err
+	}
+	a.format = format
+	return nil
+}
+
+// isKnownSubcommand reports whether name is one of preflight's own commands or
+// a plugin's. Derived from cobra rather than a hand-kept list: the list had
+// drifted (it named "version", which is not a command), and adding a command
+// without updating it meant a file of that name in the working directory would
+// be mistaken for a hashbang script. A plugin's name is claimed the same way.
 func isKnownSubcommand(name string) bool {
+	if isBuiltinCommand(newApp().root, name) {
+		return true
+	}
+	_, err := lookPlugin(name)
+	return err == nil
+}
+
+// isBuiltinCommand reports whether name is one of root's own commands. cobra
+// adds help and completion only once it executes.
+func isBuiltinCommand(root *cobra.Command, name string) bool {
 	switch name {
-	case "help", "--help", "-h":
+	case "help", "--help", "-h", "completion":
 		return true
 	}
-	for _, cmd := range rootCmd.Commands() {
+	for _, cmd := range root.Commands() {
 		if cmd.Name() == name {
 			return true
 		}
@@ -8,10 +8,12 @@ import (
 	"testing"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vertti/preflight/pkg/output"
 )
 
 func TestKnownSubcommandsMatchesCobra(t *testing.T) {
-	for _, cmd := range rootCmd.Commands() {
+	for _, cmd := range newApp().root.Commands() {
 		name := cmd.Name()
 		t.Run(name, func(t *testing.T) {
 			if !isKnownSubcommand(name) {
@@ -22,7 +24,7 @@ func TestKnownSubcommandsMatchesCobra(t *testing.T) {
 
 	t.Run("does not claim commands that do not exist", func(t *testing.T) {
 		registered := map[string]bool{}
-		for _, cmd := range rootCmd.Commands() {
+		for _, cmd := range newApp().root.Commands() {
 			registered[cmd.Name()] = true
 		}
 		// "version" was listed by hand but has never been a subcommand.
@@ -87,3 +89,33 @@ func TestReportExecuteError(t *testing.T) {
 		}
 	})
 }
+
+func TestOutputFormat(t *testing.T) {
+	format := func(args ...string) (output.Format, error) {
+		a := newApp()
+		a.root.SetArgs(args)
+		err := a.root.Execute()
+		return a.format, err
+	}
+
+	t.Run("an unknown format is a usage error", func(t *testing.T) {
+		_, err := format("--output", "xml", "sys")
+		if err == nil || !strings.Contains(err.Error(), "xml") {
+			t.Errorf("got %v, want an error naming the bad format", err)
+		}
+	})
+
+	t.Run("PREFLIGHT_OUTPUT sets the default", func(t *testing.T) {
+		t.Setenv("PREFLIGHT_OUTPUT", "json")
+		if got, _ := format("sys"); got != output.FormatJSON {
+			t.Errorf("format = %q, want json", got)
+		}
+	})
+
+	t.Run("the flag wins over the environment", func(t *testing.T) {
+		t.Setenv("PREFLIGHT_OUTPUT", "json")
+		if got, _ := format("--output", "text", "sys"); got != output.FormatText {
+			t.Errorf("format = %q, want text", got)
+		}
+	})
+}
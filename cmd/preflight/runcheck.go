@@ -1,35 +1,138 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
 
 	"github.com/vertti/preflight/pkg/check"
 	"github.com/vertti/preflight/pkg/output"
+	"github.com/vertti/preflight/pkg/retry"
 )
 
-// Checker is implemented by all check types.
+// Checker is implemented by all check types. A check stops early, and fails,
+// once ctx is done.
 type Checker interface {
-	Run() check.Result
+	RunContext(ctx context.Context) check.Result
 }
 
 // ErrCheckFailed is returned when a check fails.
 var ErrCheckFailed = errors.New("check failed")
 
-// checkRan records that a check actually executed. Exec mode is gated on it:
-// the root command has no RunE, so `preflight -- ./app` and
-// `preflight --help -- ./app` reach the end of Execute() with a nil error,
-// which would otherwise be indistinguishable from "every check passed".
-var checkRan bool
+// buildFunc turns a command's arguments and flags into the check it describes.
+// It reports usage mistakes — a bad size string, a missing flag — as errors, and
+// does nothing that belongs to running the check.
+type buildFunc func(cmd *cobra.Command, args []string) (Checker, error)
+
+// checkCommand gives cmd a RunE that builds its check and runs it. Building is
+// kept apart from running so a .preflight line can be turned into a Checker
+// in-process and run by whoever collected it.
+//
+// Every check command gets the retry flags here, so waiting for a socket file
+// or a port works the same way as waiting for an HTTP endpoint, and --warn.
+func (a *app) checkCommand(cmd *cobra.Command, build buildFunc) *cobra.Command {
+	retryFlags := addRetryFlags(cmd)
+	var warn bool
+	cmd.Flags().BoolVar(&warn, "warn", false, "report a failure as a warning and exit 0")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		policy, err := retryFlags.policy()
+		if err != nil {
+			return err
+		}
+		c, err := build(cmd, args)
+		if err != nil {
+			return err
+		}
+		// Retries come first: a check is only a warning once it has run out of them.
+		c = retry.Wrap(c, policy)
+		if warn {
+			c = check.Advisory{Checker: c}
+		}
+		if a.collect != nil {
+			a.collect(cmd, c)
+			return nil
+		}
+		return a.runCheck(cmd, c)
+	}
+	return cmd
+}
 
 // runCheck executes a check, prints the result, and returns an error if failed.
-// The returned error causes Cobra to exit with code 1.
-func runCheck(c Checker) error {
-	checkRan = true
-	result := c.Run()
-	output.PrintResult(result)
+// The returned error causes Cobra to exit with code 1; a warning does not fail.
+// cmd names the check type in JSON output.
+func (a *app) runCheck(cmd *cobra.Command, c Checker) error {
+	a.checkRan = true
+	result := check.Run(cmd.Context(), c)
 
-	if !result.OK() {
+	if a.format == output.FormatJSON {
+		if err := output.PrintJSON(output.NewRecord(result, cmd.Name())); err != nil {
+			return err
+		}
+	} else {
+		a.printResult(result)
+	}
+
+	if result.Failed() {
 		return ErrCheckFailed
 	}
 	return nil
 }
+
+// printResult writes a result as text, with its duration under --verbose.
+func (a *app) printResult(result check.Result) {
+	if a.verbose {
+		output.PrintResultVerbose(result)
+		return
+	}
+	output.PrintResult(result)
+}
+
+// retryFlags are the flags every check command shares for retrying a failure.
+type retryFlags struct {
+	retries  int
+	delay    time.Duration
+	wait     time.Duration
+	backoff  float64
+	maxDelay time.Duration
+	jitter   float64
+}
+
+func addRetryFlags(cmd *cobra.Command) *retryFlags {
+	f := &retryFlags{}
+	cmd.Flags().IntVar(&f.retries, "retry", 0, "retry count on failure")
+	cmd.Flags().DurationVar(&f.delay, "retry-delay", retry.DefaultDelay, "delay between retries")
+	cmd.Flags().DurationVar(&f.wait, "wait", 0, "keep retrying until this much time has passed")
+	cmd.Flags().Float64Var(&f.backoff, "retry-backoff", 1, "multiply the delay by this after every retry")
+	cmd.Flags().DurationVar(&f.maxDelay, "retry-max-delay", 0, "longest delay between retries (default: no limit)")
+	cmd.Flags().Float64Var(&f.jitter, "retry-jitter", 0, "randomise each delay by up to this fraction of it (0-1)")
+	return f
+}
+
+// policy validates the flags and turns them into a retry.Policy.
+func (f *retryFlags) policy() (retry.Policy, error) {
+	switch {
+	case f.retries < 0:
+		return retry.Policy{}, fmt.Errorf("--retry must not be negative, got %d", f.retries)
+	case f.delay < 0:
+		return retry.Policy{}, fmt.Errorf("--retry-delay must not be negative, got %s", f.delay)
+	case f.wait < 0:
+		return retry.Policy{}, fmt.Errorf("--wait must not be negative, got %s", f.wait)
+	case f.backoff < 1:
+		return retry.Policy{}, fmt.Errorf("--retry-backoff must be at least 1, got %v", f.backoff)
+	case f.maxDelay < 0:
+		return retry.Policy{}, fmt.Errorf("--retry-max-delay must not be negative, got %s", f.maxDelay)
+	case f.jitter < 0 || f.jitter > 1:
+		return retry.Policy{}, fmt.Errorf("--retry-jitter must be between 0 and 1, got %v", f.jitter)
+	}
+	return retry.Policy{
+		Retries:  f.retries,
+		Delay:    f.delay,
+		Wait:     f.wait,
+		Backoff:  f.backoff,
+		MaxDelay: f.maxDelay,
+		Jitter:   f.jitter,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/httpcheck"
+	"github.com/vertti/preflight/pkg/retry"
+)
+
+// Waiting for a socket file used to need a shell loop around preflight.
+func TestRetryFlags_WaitForAFileToAppear(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sock")
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = os.WriteFile(path, nil, 0o600)
+	}()
+
+	parsed, err := parseLine([]string{"file", path, "--wait", "5s", "--retry-delay", "5ms"}, os.Stdout)
+	require.NoError(t, err)
+	result := parsed.checker.RunContext(t.Context())
+	assert.Equal(t, check.StatusOK, result.Status)
+	assert.Contains(t, result.Details[len(result.Details)-1], "succeeded on attempt")
+}
+
+func TestRetryFlags_EveryCheckCommandHasThem(t *testing.T) {
+	for _, cmd := range newApp().root.Commands() {
+		// Commands that run a file of checks are not checks themselves.
+		if cmd.RunE == nil || slices.Contains([]string{"fmt", "lint", "run", "serve", "watch"}, cmd.Name()) {
+			continue
+		}
+		for _, flag := range []string{"retry", "retry-delay", "wait", "retry-backoff", "retry-max-delay", "retry-jitter"} {
+			assert.NotNil(t, cmd.Flags().Lookup(flag), "%s has no --%s", cmd.Name(), flag)
+		}
+	}
+}
+
+func TestRetryFlags_Policy(t *testing.T) {
+	t.Run("flags reach the policy", func(t *testing.T) {
+		parsed, err := parseLine([]string{"env", "PATH", "--retry", "3", "--retry-delay", "2s",
+			"--wait", "1m", "--retry-backoff", "2", "--retry-max-delay", "10s", "--retry-jitter", "0.2"}, os.Stdout)
+		require.NoError(t, err)
+		wrapped, ok := parsed.checker.(*retry.Check)
+		require.True(t, ok, "got %T", parsed.checker)
+		assert.Equal(t, retry.Policy{
+			Retries: 3, Delay: 2 * time.Second, Wait: time.Minute,
+			Backoff: 2, MaxDelay: 10 * time.Second, Jitter: 0.2,
+		}, wrapped.Policy)
+	})
+
+	t.Run("no retry flags leave the check unwrapped", func(t *testing.T) {
+		parsed, err := parseLine([]string{"env", "PATH"}, os.Stdout)
+		require.NoError(t, err)
+		_, wrapped := parsed.checker.(*retry.Check)
+		assert.False(t, wrapped)
+	})
+
+	// http tells a bad request from a slow server itself, so it takes the
+	// policy rather than being rerun whole.
+	t.Run("http applies the policy itself", func(t *testing.T) {
+		parsed, err := parseLine([]string{"http", "http://localhost/health", "--retry", "2"}, os.Stdout)
+		require.NoError(t, err)
+		c, ok := parsed.checker.(*httpcheck.Check)
+		require.True(t, ok, "got %T", parsed.checker)
+		assert.Equal(t, 2, c.Retry.Retries)
+	})
+
+	for _, tc := range []struct {
+		name string
+		args []string
+	}{
+		{"negative retry", []string{"--retry", "-1"}},
+		{"negative delay", []string{"--retry-delay", "-1s"}},
+		{"negative wait", []string{"--wait", "-1s"}},
+		{"shrinking backoff", []string{"--retry-backoff", "0.5"}},
+		{"negative max delay", []string{"--retry-max-delay", "-1s"}},
+		{"jitter above one", []string{"--retry-jitter", "1.5"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseLine(append([]string{"env", "PATH"}, tc.args...), os.Stdout)
+			require.Error(t, err)
+		})
+	}
+}
+
+// A check cut short fails and says what stopped it, even one that was still
+// retrying a failure it expected to recover from.
+func TestDeadline(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "app.sock")
+
+	t.Run("an interrupted check fails and says why", func(t *testing.T) {
+		parsed, err := parseLine([]string{"file", missing, "--wait", "1m", "--retry-delay", "5ms"}, os.Stdout)
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeoutCause(t.Context(), 30*time.Millisecond, errors.New("--deadline of 30ms exceeded"))
+		defer cancel()
+
+		result := check.Run(ctx, parsed.checker)
+		assert.Equal(t, check.StatusFail, result.Status)
+		assert.Equal(t, "interrupted: --deadline of 30ms exceeded", result.Details[len(result.Details)-1])
+		assert.Less(t, result.Duration, 5*time.Second)
+	})
+
+	t.Run("--deadline ends a wait", func(t *testing.T) {
+		start := time.Now()
+		_, err := executeCommand("--deadline", "30ms", "file", missing, "--wait", "1m", "--retry-delay", "5ms")
+		require.ErrorIs(t, err, ErrCheckFailed)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("a passing check is not held up", func(t *testing.T) {
+		_, err := executeCommand("--deadline", "1m", "env", "PATH")
+		require.NoError(t, err)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		_, err := executeCommand("--deadline", "-1s", "env", "PATH")
+		require.ErrorContains(t, err, "--deadline must not be negative")
+	})
+
+	// The file's lines share the run's deadline; one of their own would
+	// govern parsing and nothing else.
+	t.Run("not on a .preflight line", func(t *testing.T) {
+		_, err := parseLine([]string{"--deadline", "1s", "env", "PATH"}, os.Stdout)
+		require.ErrorContains(t, err, "inside a .preflight file")
+	})
+}
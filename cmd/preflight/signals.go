@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptedError is the cause of a context cancelled by a signal.
+type interruptedError struct {
+	sig syscall.Signal
+}
+
+func (e *interruptedError) Error() string {
+	switch e.sig {
+	case syscall.SIGINT:
+		return "received SIGINT"
+	case syscall.SIGTERM:
+		return "received SIGTERM"
+	}
+	return "received " + e.sig.String()
+}
+
+// notifySignals returns a context cancelled by the first SIGINT or SIGTERM,
+// and a function that stops listening and returns the signal that arrived, if
+// one did.
+//
+// Cancelling instead of dying lets the check that was running report that it
+// was interrupted, and lets a .preflight run say which lines it never reached.
+// A second signal is not caught: it ends preflight the usual way, for when a
+// check will not let go.
+func notifySignals() (context.Context, func() *interruptedError) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			s, _ := sig.(syscall.Signal)
+			cancel(&interruptedError{sig: s})
+		case <-done:
+		}
+	}()
+
+	return ctx, func() *interruptedError {
+		signal.Stop(signals)
+		close(done)
+		var interrupted *interruptedError
+		if errors.As(context.Cause(ctx), &interrupted) {
+			return interrupted
+		}
+		return nil
+	}
+}
+
+// exitCode is what preflight exits with after a failure: 1, or 128 plus the
+// signal number when a signal cut it short, as a shell would report.
+func exitCode(interrupted *interruptedError) int {
+	if interrupted == nil {
+		return 1
+	}
+	return 128 + int(interrupted.sig)
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"strings"
+
+	"github.com/vertti/preflight/pkg/check"
 )
 
 // flagValue represents a flag name and its current value for validation.
@@ -56,3 +58,14 @@ func requireAtLeastOne(flags ...flagSet) error {
 	flagList := strings.Join(names, ", ")
 	return fmt.Errorf("at least one of %s is required", flagList)
 }
+
+// validateRegex returns an error if pattern, given as flag, does not compile.
+// The check compiles it again when it runs; doing it here as well means a typo
+// in a .preflight file is a usage error that lint reports, not a failure found
+// in production.
+func validateRegex(flag, pattern string) error {
+	if _, err := check.CompileRegex(pattern); err != nil {
+		return fmt.Errorf("invalid %s regex: %w", flag, err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package preflight_test
 
 import (
+	"encoding/json"
 	"errors"
 	"net"
 	"net/http"
@@ -455,7 +456,7 @@ func TestIntegration_Git(t *testing.T) {
 	runner := &gitcheck.RealGitRunner{}
 
 	// Verify we're in a git repo
-	isRepo, err := runner.IsGitRepo()
+	isRepo, err := runner.IsGitRepo(t.Context())
 	if err != nil {
 		t.Fatalf("IsGitRepo() error = %v", err)
 	}
@@ -464,7 +465,7 @@ func TestIntegration_Git(t *testing.T) {
 	}
 
 	// Test that CurrentBranch works
-	branch, err := runner.CurrentBranch()
+	branch, err := runner.CurrentBranch(t.Context())
 	if err != nil {
 		t.Errorf("CurrentBranch() error = %v", err)
 	}
@@ -473,13 +474,13 @@ func TestIntegration_Git(t *testing.T) {
 	}
 
 	// Test that Status works (output varies, just verify no error)
-	_, err = runner.Status()
+	_, err = runner.Status(t.Context())
 	if err != nil {
 		t.Errorf("Status() error = %v", err)
 	}
 
 	// Test that TagsAtHead works (may return empty, just verify no error)
-	_, err = runner.TagsAtHead()
+	_, err = runner.TagsAtHead(t.Context())
 	if err != nil {
 		t.Errorf("TagsAtHead() error = %v", err)
 	}
@@ -693,9 +694,9 @@ func TestIntegration_ExecMode(t *testing.T) {
 	})
 }
 
-// The unit tests in cmd_run_test.go cover the command loop with an injected
-// runner. This covers what they cannot: the real spawn, and exit-code
-// propagation through os.Exit.
+// The unit tests in cmd_run_test.go cover the command loop in-process. This
+// covers what they cannot: file discovery from a working directory, and the
+// exit code the built binary actually returns.
 func TestIntegration_Run(t *testing.T) {
 	binaryPath := filepath.Join(t.TempDir(), "preflight")
 	buildCmd := exec.Command("go", "build", "-o", binaryPath, "./cmd/preflight") //nolint:gosec // intentional: building test binary
@@ -785,4 +786,59 @@ func TestIntegration_Run(t *testing.T) {
 		assert.Equal(t, 0, code)
 		assert.Contains(t, out, "[OK] env: PATH")
 	})
+
+	t.Run("json output is one report covering every line", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".preflight"),
+			[]byte("env PATH\nenv PREFLIGHT_DEFINITELY_UNSET_XYZ\n"), 0o600))
+
+		cmd := exec.Command(binaryPath, "run", "--output", "json")
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		var exitErr *exec.ExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, 1, exitErr.ExitCode())
+
+		var report struct {
+			Status string `json:"status"`
+			Ran    int    `json:"ran"`
+			Failed int    `json:"failed"`
+			Checks []struct {
+				Name   string `json:"name"`
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"checks"`
+		}
+		require.NoError(t, json.Unmarshal(out, &report), string(out))
+		assert.Equal(t, "FAIL", report.Status)
+		assert.Equal(t, 2, report.Ran)
+		assert.Equal(t, 1, report.Failed)
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "env: PATH", report.Checks[0].Name)
+		assert.Equal(t, "env", report.Checks[0].Type)
+		assert.Equal(t, "OK", report.Checks[0].Status)
+		assert.Equal(t, "FAIL", report.Checks[1].Status)
+	})
+
+	t.Run("junit report is written alongside the usual text", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".preflight"),
+			[]byte("env PATH\nenv PREFLIGHT_DEFINITELY_UNSET_XYZ\n"), 0o600))
+		reportPath := filepath.Join(dir, "junit.xml")
+
+		cmd := exec.Command(binaryPath, "run", "--junit", reportPath)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "NO_COLOR=1")
+		out, err := cmd.CombinedOutput()
+		var exitErr *exec.ExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Contains(t, string(out), "[OK] env: PATH")
+		assert.Contains(t, string(out), "[FAIL] env: PREFLIGHT_DEFINITELY_UNSET_XYZ")
+		assert.Contains(t, string(out), "1 of 2 checks failed")
+
+		report, err := os.ReadFile(reportPath) //nolint:gosec // test-controlled path
+		require.NoError(t, err)
+		assert.Contains(t, string(report), `tests="2" failures="1"`)
+		assert.Contains(t, string(report), `<testcase name="env: PATH" classname="env"`)
+	})
 }
@@ -0,0 +1,226 @@
+// Package certcheck checks a certificate file: that it parses, when it
+// expires, who it names, that its private key belongs to it, and that it
+// chains to a trusted root. A service that mounts its certificate from a
+// secret fails fast on a bad one instead of starting and failing every
+// handshake.
+package certcheck
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// Check verifies a certificate file.
+type Check struct {
+	File        string     // certificate: PEM, DER or a PKCS#12 bundle
+	Password    string     // PKCS#12 bundle password
+	NotExpired  bool       // --not-expired: must be valid now
+	MinDays     int        // --min-days: must stay valid at least this many more days
+	VerifyChain bool       // --verify-chain: must chain to a trusted root
+	CAFile      string     // --ca-file: roots to trust instead of the system's
+	Hostname    string     // --hostname: must be valid for this name or IP
+	KeyFile     string     // --key-file: private key that must match the certificate
+	Issuer      string     // --issuer: regex matched against the issuer's name
+	Subject     string     // --subject: regex matched against the subject's name
+	FS          FileSystem // injected for testing
+}
+
+// Run executes the certificate check.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. ctx is unused: the check
+// reads a file or two and checks what they hold.
+func (c *Check) RunContext(context.Context) check.Result {
+	result := check.Result{
+		Name: "cert: " + c.File,
+	}
+
+	subjectRe, err := check.CompileRegex(c.Subject)
+	if err != nil {
+		return result.Failf("invalid subject regex: %v", err)
+	}
+	issuerRe, err := check.CompileRegex(c.Issuer)
+	if err != nil {
+		return result.Failf("invalid issuer regex: %v", err)
+	}
+
+	data, err := c.FS.ReadFile(c.File)
+	if err != nil {
+		return result.Failf("failed to read certificate: %v", err)
+	}
+	certs, err := parseCertificates(data, c.Password)
+	if err != nil {
+		return result.Failf("%v", err)
+	}
+	leaf := certs[0]
+
+	// Every failure below reads better next to what the certificate is.
+	now := time.Now()
+	for _, detail := range Details(leaf, now) {
+		result.AddDetail(detail)
+	}
+
+	if c.NotExpired || c.MinDays > 0 {
+		if err := CheckValidity(leaf, now, c.MinDays); err != nil {
+			return result.Failf("%v", err)
+		}
+	}
+
+	if subjectRe != nil && !subjectRe.MatchString(leaf.Subject.String()) {
+		return result.Failf("subject %q does not match %q", leaf.Subject, c.Subject)
+	}
+	if issuerRe != nil && !issuerRe.MatchString(leaf.Issuer.String()) {
+		return result.Failf("issuer %q does not match %q", leaf.Issuer, c.Issuer)
+	}
+
+	if c.Hostname != "" {
+		if err := leaf.VerifyHostname(c.Hostname); err != nil {
+			return result.Failf("%v", err)
+		}
+	}
+
+	if c.KeyFile != "" {
+		keyData, err := c.FS.ReadFile(c.KeyFile)
+		if err != nil {
+			return result.Failf("failed to read private key: %v", err)
+		}
+		key, err := parsePrivateKey(keyData)
+		if err != nil {
+			return result.Failf("%s: %v", c.KeyFile, err)
+		}
+		if !keyMatches(key, leaf) {
+			return result.Failf("private key %s does not match the certificate", c.KeyFile)
+		}
+		result.AddDetailf("key: %s matches", c.KeyFile)
+	}
+
+	if c.VerifyChain {
+		chain, err := c.verify(certs, now)
+		if err != nil {
+			return result.Failf("%v", err)
+		}
+		result.AddDetailf("chain: %s", chain)
+	}
+
+	result.Status = check.StatusOK
+	return result
+}
+
+// verify checks that certs chain to a trusted root, the first of them as the
+// leaf and the rest as intermediates, and describes the chain it found.
+func (c *Check) verify(certs []*x509.Certificate, now time.Time) (string, error) {
+	roots, err := c.roots()
+	if err != nil {
+		return "", err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		// A certificate file says nothing of what it will be used for.
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return "", fmt.Errorf("chain does not verify: %w", err)
+	}
+	names := make([]string, len(chains[0]))
+	for i, cert := range chains[0] {
+		names[i] = commonName(cert)
+	}
+	return strings.Join(names, " -> "), nil
+}
+
+// roots returns the certificates in CAFile, or the system's roots without one.
+func (c *Check) roots() (*x509.CertPool, error) {
+	if c.CAFile == "" {
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system roots: %w", err)
+		}
+		return roots, nil
+	}
+	data, err := c.FS.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	certs, err := parseCertificates(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.CAFile, err)
+	}
+	roots := x509.NewCertPool()
+	for _, cert := range certs {
+		roots.AddCert(cert)
+	}
+	return roots, nil
+}
+
+// Details describes cert for a check result: its subject, the names it is
+// valid for, its issuer, and how many days it has left.
+func Details(cert *x509.Certificate, now time.Time) []string {
+	details := []string{"subject: " + cert.Subject.String()}
+	if sans := subjectAltNames(cert); len(sans) > 0 {
+		details = append(details, "SANs: "+strings.Join(sans, ", "))
+	}
+	details = append(details, "issuer: "+cert.Issuer.String())
+	expiry := cert.NotAfter.UTC().Format(time.DateOnly)
+	if days := daysLeft(cert, now); days < 0 {
+		details = append(details, fmt.Sprintf("expired: %s (%d days ago)", expiry, -days))
+	} else {
+		details = append(details, fmt.Sprintf("expires: %s (%d days left)", expiry, days))
+	}
+	return details
+}
+
+// CheckValidity returns an error unless cert is valid at now and stays valid
+// for at least minDays more days.
+func CheckValidity(cert *x509.Certificate, now time.Time, minDays int) error {
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("not valid until %s", cert.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("expired on %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if days := daysLeft(cert, now); days < minDays {
+		return fmt.Errorf("expires in %d days, want at least %d", days, minDays)
+	}
+	return nil
+}
+
+// daysLeft returns the whole days until cert expires, negative once it has.
+func daysLeft(cert *x509.Certificate, now time.Time) int {
+	return int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+}
+
+// subjectAltNames lists the names and addresses cert is valid for.
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// commonName names cert in a chain: its common name, or its whole subject
+// when it has none.
+func commonName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return cert.Subject.String()
+}
@@ -0,0 +1,103 @@
+package certcheck
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// pemMarker starts every PEM block. A file without one is read as binary.
+var pemMarker = []byte("-----BEGIN ")
+
+// parseCertificates returns the certificates in data, the leaf first and then
+// any chain that came with it. data is PEM, DER, or a PKCS#12 bundle opened
+// with password.
+func parseCertificates(data []byte, password string) ([]*x509.Certificate, error) {
+	if bytes.Contains(data, pemMarker) {
+		var certs []*x509.Certificate
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			// A combined file may hold the private key too.
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		}
+		if len(certs) == 0 {
+			return nil, errors.New("no CERTIFICATE block in PEM file")
+		}
+		return certs, nil
+	}
+
+	if certs, err := x509.ParseCertificates(data); err == nil && len(certs) > 0 {
+		return certs, nil
+	}
+
+	// A PKCS#12 bundle holds a key with its certificate and chain; a Java
+	// trust store holds certificates alone.
+	_, leaf, chain, err := pkcs12.DecodeChain(data, password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		if password == "" {
+			return nil, errors.New("PKCS#12 bundle is protected by a password")
+		}
+		return nil, errors.New("incorrect PKCS#12 password")
+	}
+	if err == nil {
+		return append([]*x509.Certificate{leaf}, chain...), nil
+	}
+	if certs, err := pkcs12.DecodeTrustStore(data, password); err == nil && len(certs) > 0 {
+		return certs, nil
+	}
+	return nil, errors.New("not a PEM, DER or PKCS#12 certificate")
+}
+
+// parsePrivateKey returns the private key in data, PEM or DER, in PKCS#8,
+// PKCS#1 or SEC 1 form.
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	der := data
+	if bytes.Contains(data, pemMarker) {
+		der = nil
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+				continue
+			}
+			if block.Type == "ENCRYPTED PRIVATE KEY" || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+				return nil, errors.New("encrypted private keys are not supported")
+			}
+			der = block.Bytes
+			break
+		}
+		if der == nil {
+			return nil, errors.New("no PRIVATE KEY block in PEM file")
+		}
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("not a PKCS#8, PKCS#1 or EC private key")
+}
+
+// keyMatches reports whether key is the private half of cert's public key.
+func keyMatches(key crypto.Signer, cert *x509.Certificate) bool {
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	return ok && pub.Equal(cert.PublicKey)
+}
@@ -1,15 +1,43 @@
 package check
 
+import "context"
+
 // Checker is implemented by all check types.
 // Each check validates a specific aspect of the environment
 // and returns a Result indicating success or failure.
 //
+// A check that waits — on a connection, a request, a command, a retry — gives up
+// once ctx is done, and reports the failure that caused; the caller knows from
+// ctx whether it was a deadline or an interrupt. Checks that never block still
+// take ctx, so that every check can be run and wrapped the same way. Each also
+// has a Run method that runs it with a background context.
+//
 // Implementations:
 //   - cmdcheck.Check: verifies command existence and version
 //   - envcheck.Check: validates environment variables
 //   - filecheck.Check: checks file/directory properties
 //   - tcpcheck.Check: tests TCP connectivity
 //   - usercheck.Check: verifies user existence and properties
+//
+// retry.Check wraps any of them to rerun it until it passes.
 type Checker interface {
-	Run() Result
+	RunContext(ctx context.Context) Result
+}
+
+// Advisory runs a check whose failure is reported as a warning rather than
+// failing the run. New checks can be rolled out this way without breaking
+// every pipeline the day they land.
+type Advisory struct {
+	Checker Checker
+}
+
+// Run executes the wrapped check and downgrades a failure to a warning.
+func (a Advisory) Run() Result {
+	return a.RunContext(context.Background())
+}
+
+// RunContext is Run with ctx passed to the wrapped check.
+func (a Advisory) RunContext(ctx context.Context) Result {
+	r := a.Checker.RunContext(ctx)
+	return r.Warn()
 }
@@ -3,7 +3,6 @@ package check
 import (
 	"fmt"
 	"regexp"
-	"slices"
 )
 
 // Fail sets the result to failed status with a detail message.
@@ -26,10 +25,32 @@ func (r *Result) Failf(format string, args ...any) Result {
 // spelled out at eleven of them across two files, where nothing kept the count
 // and the message together — and two had drifted into a different phrasing.
 func (r *Result) FailAfter(attempts int, format string, args ...any) Result {
-	if attempts <= 1 {
-		return r.Failf(format, args...)
+	r.Failf(format, args...)
+	return r.NoteAttempts(attempts)
+}
+
+// NoteAttempts adds the FailAfter wording to a failure that has already been
+// recorded, for a retry loop that only sees the result of its last attempt.
+func (r *Result) NoteAttempts(attempts int) Result {
+	if attempts <= 1 || r.Status != StatusFail {
+		return *r
+	}
+	suffix := fmt.Sprintf(" (after %d attempts)", attempts)
+	if n := len(r.Details); n > 0 {
+		r.Details[n-1] += suffix
 	}
-	return r.Failf(format+" (after %d attempts)", append(slices.Clone(args), attempts)...)
+	if r.Err != nil {
+		r.Err = fmt.Errorf("%w%s", r.Err, suffix)
+	}
+	return *r
+}
+
+// Warn downgrades a failure to a warning. A passing result is left as it is.
+func (r *Result) Warn() Result {
+	if r.Status == StatusFail {
+		r.Status = StatusWarn
+	}
+	return *r
 }
 
 // AddDetail appends a detail line to the result.
@@ -1,22 +1,35 @@
 package check
 
+import "time"
+
 // Status represents the outcome of a check.
 type Status string
 
 const (
 	StatusOK   Status = "OK"
+	StatusWarn Status = "WARN" // failed, but only advisory; does not fail the run
 	StatusFail Status = "FAIL"
 )
 
 // Result holds the outcome of a single check.
 type Result struct {
 	Name    string   // e.g., "cmd:node", "env:DATABASE_URL"
-	Status  Status   // OK or FAIL
+	Status  Status   // OK, WARN or FAIL
 	Details []string // human-readable details
 	Err     error    // underlying error for failures
+
+	// Duration is how long the check took, retries included. Checks leave it
+	// alone: it is measured by Run.
+	Duration time.Duration
 }
 
 // OK returns true if the check passed.
 func (r *Result) OK() bool {
 	return r.Status == StatusOK
 }
+
+// Failed returns true if the check failed in a way that should fail the run.
+// A warning is neither OK nor Failed.
+func (r *Result) Failed() bool {
+	return r.Status == StatusFail
+}
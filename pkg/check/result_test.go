@@ -1,6 +1,9 @@
 package check
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestStatus(t *testing.T) {
 	if StatusOK != "OK" {
@@ -95,3 +98,69 @@ func TestResult_FailAfter(t *testing.T) {
 		}
 	})
 }
+
+// The retry engine only sees the result of the last attempt, after the check
+// has already worded its failure.
+func TestResult_NoteAttempts(t *testing.T) {
+	t.Run("annotates the last detail and the error", func(t *testing.T) {
+		r := &Result{Name: "tcp: db:5432"}
+		r.AddDetail("dialing db:5432")
+		r.Failf("connection refused")
+
+		result := r.NoteAttempts(4)
+
+		want := []string{"dialing db:5432", "connection refused (after 4 attempts)"}
+		if len(result.Details) != 2 || result.Details[0] != want[0] || result.Details[1] != want[1] {
+			t.Errorf("Details = %v, want %v", result.Details, want)
+		}
+		if result.Err == nil || result.Err.Error() != "connection refused (after 4 attempts)" {
+			t.Errorf("Err = %v, want the count appended", result.Err)
+		}
+	})
+
+	t.Run("leaves a pass alone", func(t *testing.T) {
+		r := &Result{Name: "x", Status: StatusOK, Details: []string{"fine"}}
+
+		result := r.NoteAttempts(3)
+
+		if len(result.Details) != 1 || result.Details[0] != "fine" {
+			t.Errorf("Details = %v, want [fine]", result.Details)
+		}
+	})
+}
+
+func TestResult_Warn(t *testing.T) {
+	r := &Result{Name: "resource"}
+	r.Failf("disk space 12.0GB < required 20.0GB")
+
+	result := r.Warn()
+
+	if result.Status != StatusWarn {
+		t.Errorf("Status = %v, want %v", result.Status, StatusWarn)
+	}
+	if result.OK() || result.Failed() {
+		t.Error("a warning is neither OK nor Failed")
+	}
+
+	ok := &Result{Name: "x", Status: StatusOK}
+	if got := ok.Warn(); got.Status != StatusOK {
+		t.Errorf("Warn() on a pass = %v, want %v", got.Status, StatusOK)
+	}
+}
+
+type resultChecker Result
+
+func (c resultChecker) RunContext(context.Context) Result { return Result(c) }
+
+func TestAdvisory(t *testing.T) {
+	failing := resultChecker{Name: "cmd: node", Status: StatusFail, Details: []string{"version 16.0.0 < minimum 20.0.0"}}
+
+	result := Advisory{Checker: failing}.Run()
+
+	if result.Status != StatusWarn {
+		t.Errorf("Status = %v, want %v", result.Status, StatusWarn)
+	}
+	if len(result.Details) != 1 {
+		t.Errorf("Details = %v, want the check's own", result.Details)
+	}
+}
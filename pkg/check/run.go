@@ -0,0 +1,37 @@
+package check
+
+import (
+	"context"
+	"time"
+)
+
+// Run runs c under ctx and records how long it took in the result.
+//
+// A check cut short by ctx fails whatever it found, and says why: a check that
+// gave up when SIGTERM or a deadline arrived would otherwise read like a
+// dependency that was down.
+func Run(ctx context.Context, c Checker) Result {
+	start := time.Now()
+	result := c.RunContext(ctx)
+	result.Duration = time.Since(start)
+	if ctx.Err() != nil {
+		cause := context.Cause(ctx)
+		result.Status = StatusFail
+		result.Details = append(result.Details, "interrupted: "+cause.Error())
+		if result.Err == nil {
+			result.Err = cause
+		}
+	}
+	return result
+}
+
+// NotRun is the result of a check named name that was never started because
+// of cause. It keeps the check in a report's counts rather than dropping it.
+func NotRun(name string, cause error) Result {
+	return Result{
+		Name:    name,
+		Status:  StatusFail,
+		Details: []string{"not run: " + cause.Error()},
+		Err:     cause,
+	}
+}
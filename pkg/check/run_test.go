@@ -0,0 +1,42 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	passing := resultChecker{Name: "env: HOME", Status: StatusOK}
+
+	if result := Run(t.Context(), passing); result.Status != StatusOK || len(result.Details) != 0 {
+		t.Errorf("Run = %+v, want a plain pass", result)
+	}
+
+	t.Run("an interrupted check fails and says why", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(t.Context())
+		cancel(errors.New("received SIGTERM"))
+
+		result := Run(ctx, passing)
+		if result.Status != StatusFail {
+			t.Errorf("Status = %v, want %v", result.Status, StatusFail)
+		}
+		if want := []string{"interrupted: received SIGTERM"}; len(result.Details) != 1 || result.Details[0] != want[0] {
+			t.Errorf("Details = %q, want %q", result.Details, want)
+		}
+		if result.Err == nil {
+			t.Error("Err is nil")
+		}
+	})
+}
+
+func TestNotRun(t *testing.T) {
+	result := NotRun("tcp: db:5432", errors.New("deadline exceeded"))
+
+	if result.Status != StatusFail || result.Name != "tcp: db:5432" {
+		t.Errorf("NotRun = %+v", result)
+	}
+	if len(result.Details) != 1 || result.Details[0] != "not run: deadline exceeded" {
+		t.Errorf("Details = %q", result.Details)
+	}
+}
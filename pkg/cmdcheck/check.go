@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"time"
 
-	semver "github.com/Masterminds/semver/v3"
-
 	"github.com/vertti/preflight/pkg/check"
 	"github.com/vertti/preflight/pkg/version"
 )
@@ -27,6 +25,12 @@ type Check struct {
 
 // Run executes the command check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run with the version command killed once ctx is done, if its
+// own timeout has not killed it first.
+func (c *Check) RunContext(ctx context.Context) check.Result {
 	result := check.Result{
 		Name: "cmd: " + c.Name,
 	}
@@ -48,12 +52,14 @@ func (c *Check) Run() check.Result {
 	if timeout == 0 {
 		timeout = DefaultTimeout
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	stdout, stderr, err := c.Runner.RunCommandContext(ctx, c.Name, args...)
+	stdout, stderr, err := c.Runner.RunCommandContext(cmdCtx, c.Name, args...)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		// Only the timeout set here is reported as one; the caller's deadline
+		// running out is the caller's to report.
+		if ctx.Err() == nil && cmdCtx.Err() == context.DeadlineExceeded {
 			return result.Failf("version command timed out after %s", timeout)
 		}
 		result.AddDetailf("version command failed: %v", err)
@@ -151,46 +157,15 @@ func (c *Check) checkVersionConstraints(output string, result *check.Result) err
 
 	result.AddDetailf("version: %s", parsedVersion)
 
-	// Check exact version
-	if c.ExactVersion != nil && parsedVersion.Compare(*c.ExactVersion) != 0 {
-		err := fmt.Errorf("version %s does not match required %s", parsedVersion, c.ExactVersion)
-		result.Fail(fmt.Sprintf("version %s != required %s", parsedVersion, c.ExactVersion), err)
-		return err
-	}
-
-	// Check min version (inclusive: version >= min)
-	if c.MinVersion != nil && !parsedVersion.GreaterThanOrEqual(*c.MinVersion) {
-		err := fmt.Errorf("version %s below minimum %s", parsedVersion, c.MinVersion)
-		result.Fail(fmt.Sprintf("version %s < minimum %s", parsedVersion, c.MinVersion), err)
-		return err
+	constraints := version.Constraints{
+		Min:   c.MinVersion,
+		Max:   c.MaxVersion,
+		Exact: c.ExactVersion,
+		Range: c.VersionRange,
 	}
-
-	// Check max version (exclusive: version < max)
-	if c.MaxVersion != nil && !parsedVersion.LessThan(*c.MaxVersion) {
-		err := fmt.Errorf("version %s at or above maximum %s", parsedVersion, c.MaxVersion)
-		result.Fail(fmt.Sprintf("version %s >= maximum %s", parsedVersion, c.MaxVersion), err)
-		return err
-	}
-
-	// Check semver constraint range
-	if c.VersionRange != "" {
-		constraint, constraintErr := semver.NewConstraint(c.VersionRange)
-		if constraintErr != nil {
-			result.Failf("invalid version range %q: %v", c.VersionRange, constraintErr)
-			return constraintErr
-		}
-		// Convert our version to semver.Version
-		semverStr := fmt.Sprintf("%d.%d.%d", parsedVersion.Major, parsedVersion.Minor, parsedVersion.Patch)
-		sv, svErr := semver.NewVersion(semverStr)
-		if svErr != nil {
-			result.Failf("could not convert version to semver: %v", svErr)
-			return svErr
-		}
-		if !constraint.Check(sv) {
-			err := fmt.Errorf("version %s does not satisfy constraint %q", parsedVersion, c.VersionRange)
-			result.Fail(fmt.Sprintf("version %s does not satisfy %q", parsedVersion, c.VersionRange), err)
-			return err
-		}
+	if cerr := constraints.Check(parsedVersion); cerr != nil {
+		result.Fail(cerr.Detail, cerr.Err)
+		return cerr.Err
 	}
 
 	return nil
@@ -3,6 +3,7 @@ package cmdcheck
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -110,3 +111,21 @@ func TestCommandCheck_Run(t *testing.T) {
 		})
 	}
 }
+
+// The version command stops with the caller's context, and that is not
+// mistaken for the command's own --timeout running out.
+func TestCommandCheck_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	c := Check{Name: "slow", Runner: &mockCmdRunner{
+		LookPathFunc: func(string) (string, error) { return "/usr/bin/slow", nil },
+		RunCommandContextFunc: func(ctx context.Context, _ string, _ ...string) (string, string, error) {
+			cancel()
+			<-ctx.Done()
+			return "", "", ctx.Err()
+		},
+	}}
+
+	result := c.RunContext(ctx)
+	assert.Equal(t, check.StatusFail, result.Status)
+	assert.NotContains(t, strings.Join(result.Details, " "), "timed out")
+}
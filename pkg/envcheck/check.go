@@ -1,6 +1,7 @@
 package envcheck
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -42,6 +43,12 @@ type Check struct {
 
 // Run executes the environment variable check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. Reading the environment
+// never blocks, so there is nothing for ctx to cancel.
+func (c *Check) RunContext(context.Context) check.Result {
 	result := check.Result{
 		Name: "env: " + c.Name,
 	}
@@ -16,6 +16,12 @@ type Executor interface {
 // RealExecutor is the production implementation.
 type RealExecutor struct{}
 
+// InitExecutor runs the command as a child and stays on as its init: it
+// forwards signals to the command, reaps orphaned processes, and exits with the
+// command's status once it is done. A container whose entrypoint is preflight
+// then needs no tini or dumb-init of its own.
+type InitExecutor struct{}
+
 // lookPath finds the executable in PATH.
 func lookPath(name string) (string, error) {
 	return exec.LookPath(name)
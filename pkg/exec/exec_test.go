@@ -23,6 +23,7 @@ func TestExecutorInterface(t *testing.T) {
 	// Verify MockExecutor implements Executor interface
 	var _ Executor = &MockExecutor{}
 	var _ Executor = &RealExecutor{}
+	var _ Executor = &InitExecutor{}
 }
 
 func TestMockExecutor(t *testing.T) {
@@ -12,3 +12,8 @@ var ErrExecNotSupported = errors.New("exec mode not supported on Windows; use a
 func (e *RealExecutor) Exec(name string, args []string) error {
 	return ErrExecNotSupported
 }
+
+// Exec is not supported on Windows.
+func (e *InitExecutor) Exec(name string, args []string) error {
+	return ErrExecNotSupported
+}
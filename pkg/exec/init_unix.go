@@ -0,0 +1,108 @@
+//go:build unix
+
+package exec
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// forwardedSignals are passed on to the command. They are the ones a container
+// runtime or an operator sends to stop, reload, or dump the process; as PID 1
+// with no handler, the kernel would discard them instead of acting on them.
+var forwardedSignals = []os.Signal{
+	syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP,
+	syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2,
+}
+
+// exitFunc ends the process once the command has. Can be overridden for testing.
+var exitFunc = os.Exit
+
+// Exec runs the command under Supervise and exits with its status. Like
+// RealExecutor.Exec, it only returns on failure to start the command.
+func (e *InitExecutor) Exec(name string, args []string) error {
+	code, err := Supervise(name, args)
+	if err != nil {
+		return err
+	}
+	exitFunc(code)
+	return nil
+}
+
+// Supervise starts the command and waits for it, forwarding signals and reaping
+// every child that ends meanwhile. It returns the command's exit code, or 128
+// plus the signal number if a signal killed it, as a shell would.
+//
+// The command gets a process group of its own and signals go to the whole
+// group, so an app started from a wrapper script stops along with the script.
+// On a terminal the group is put in the foreground, so Ctrl-C reaches the
+// command directly rather than twice.
+func Supervise(name string, args []string) (int, error) {
+	binary, err := lookPath(name)
+	if err != nil {
+		return 0, err
+	}
+
+	// Listening starts before the command does, so neither a signal nor the
+	// command's exit can arrive unnoticed.
+	signals := make(chan os.Signal, 32)
+	signal.Notify(signals, append(forwardedSignals, syscall.SIGCHLD)...)
+	defer signal.Stop(signals)
+
+	// Outside PID 1, orphans go to init rather than here unless we ask for them.
+	becomeSubreaper()
+
+	sys := &syscall.SysProcAttr{Setpgid: true}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		sys.Foreground, sys.Ctty = true, int(os.Stdin.Fd())
+	}
+	// #nosec G204 -- This is intentional: exec mode allows users to specify the command to run after checks pass.
+	proc, err := os.StartProcess(binary, append([]string{name}, args...), &os.ProcAttr{
+		Env:   environ(),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		Sys:   sys,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for sig := range signals {
+		if s, ok := sig.(syscall.Signal); ok && s != syscall.SIGCHLD {
+			// The group may already be gone, which leaves nothing to tell.
+			_ = syscall.Kill(-proc.Pid, s)
+			continue
+		}
+		if code, done := reap(proc.Pid); done {
+			return code, nil
+		}
+	}
+	return 0, errors.New("signal channel closed")
+}
+
+// reap collects every child that has exited, and reports the exit code of pid
+// once it is among them. One SIGCHLD can stand for several children.
+func reap(pid int) (code int, done bool) {
+	for {
+		var status syscall.WaitStatus
+		child, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil || child <= 0 {
+			return code, done
+		}
+		if child != pid {
+			continue
+		}
+		switch {
+		case status.Exited():
+			code, done = status.ExitStatus(), true
+		case status.Signaled():
+			code, done = 128+int(status.Signal()), true
+		}
+	}
+}
@@ -0,0 +1,98 @@
+//go:build unix
+
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSupervise_ExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   int
+	}{
+		{"success", "exit 0", 0},
+		{"failure", "exit 3", 3},
+		{"killed by a signal", "kill -KILL $$", 128 + int(syscall.SIGKILL)},
+		// The background job outlives the shell and is reaped along the way;
+		// its exit status must not be taken for the command's.
+		{"an orphan's exit is not the command's", "(sleep 0.05; exit 9) & exit 4", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Supervise("sh", []string{"-c", tt.script})
+			if err != nil {
+				t.Fatalf("Supervise() error = %v", err)
+			}
+			if code != tt.want {
+				t.Errorf("Supervise() = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupervise_CommandNotFound(t *testing.T) {
+	if _, err := Supervise("nonexistent-command-that-does-not-exist-12345", nil); err == nil {
+		t.Error("expected error for nonexistent command")
+	}
+}
+
+// A signal sent to preflight reaches the command, which decides how to exit.
+func TestSupervise_ForwardsSignals(t *testing.T) {
+	ready := filepath.Join(t.TempDir(), "ready")
+	script := `trap 'exit 42' TERM; touch "$1"; while :; do sleep 0.01; done`
+
+	done := make(chan int, 1)
+	go func() {
+		code, err := Supervise("sh", []string{"-c", script, "sh", ready})
+		if err != nil {
+			t.Errorf("Supervise() error = %v", err)
+		}
+		done <- code
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(ready); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("command never became ready")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// Supervise is listening for SIGTERM, so this test process survives it.
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case code := <-done:
+		if code != 42 {
+			t.Errorf("Supervise() = %d, want 42 from the command's trap", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command did not exit after SIGTERM")
+	}
+}
+
+func TestInitExecutor_ExitsWithTheCommandsStatus(t *testing.T) {
+	originalExitFunc := exitFunc
+	defer func() { exitFunc = originalExitFunc }()
+
+	exited := -1
+	exitFunc = func(code int) { exited = code }
+
+	e := &InitExecutor{}
+	if err := e.Exec("sh", []string{"-c", "exit 5"}); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if exited != 5 {
+		t.Errorf("exited with %d, want 5", exited)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build unix && !linux
+
+package exec
+
+// becomeSubreaper does nothing: only Linux lets a process other than PID 1
+// adopt orphans.
+func becomeSubreaper() {}
@@ -0,0 +1,127 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vertti/preflight/pkg/usercheck"
+)
+
+// AccountLookup resolves users and groups. usercheck.RealUserLookup implements
+// it, so --user finds accounts exactly the way `preflight user` checks them.
+type AccountLookup interface {
+	usercheck.UserLookup
+	LookupID(uid string) (*user.User, error)
+	LookupGroup(name string) (*user.Group, error)
+	GroupIDs(u *user.User) ([]string, error)
+}
+
+// Credential is the identity a command is started as.
+type Credential struct {
+	UID    int
+	GID    int
+	Groups []int  // supplementary groups, the primary one among them
+	Name   string // empty for a uid with no account
+	Home   string
+}
+
+// ResolveUser turns "user[:group]" into a Credential. Either part may be a name
+// or a numeric ID.
+//
+// A numeric uid with no account is accepted, as gosu and Docker accept it: an
+// image may run as an arbitrary uid it never created. It gets defaultGID unless
+// a group is given, "/" as its home, and no supplementary groups. A name that
+// matches no account is an error.
+func ResolveUser(spec string, lookup AccountLookup, defaultGID int) (*Credential, error) {
+	userPart, groupPart, hasGroup := strings.Cut(spec, ":")
+	if userPart == "" || (hasGroup && groupPart == "") {
+		return nil, fmt.Errorf("invalid user %q: want user[:group]", spec)
+	}
+
+	cred := &Credential{GID: defaultGID, Home: "/"}
+	u, err := lookupUser(userPart, lookup)
+	switch {
+	case err != nil:
+		return nil, err
+	case u == nil:
+		// A bare uid, which lookupUser has already checked is numeric.
+		cred.UID, _ = parseID(userPart)
+	default:
+		var ok bool
+		if cred.UID, ok = parseID(u.Uid); !ok {
+			return nil, fmt.Errorf("user %q has invalid uid %q", userPart, u.Uid)
+		}
+		if cred.GID, ok = parseID(u.Gid); !ok {
+			return nil, fmt.Errorf("user %q has invalid gid %q", userPart, u.Gid)
+		}
+		cred.Name, cred.Home = u.Username, u.HomeDir
+		// Fewer groups than the account has only ever takes access away, so a
+		// group database that cannot be read costs the extras, not the exec.
+		if ids, err := lookup.GroupIDs(u); err == nil {
+			for _, id := range ids {
+				if gid, ok := parseID(id); ok {
+					cred.Groups = append(cred.Groups, gid)
+				}
+			}
+		}
+	}
+
+	if hasGroup {
+		if cred.GID, err = lookupGroup(groupPart, lookup); err != nil {
+			return nil, err
+		}
+	}
+	if !slices.Contains(cred.Groups, cred.GID) {
+		cred.Groups = append([]int{cred.GID}, cred.Groups...)
+	}
+	return cred, nil
+}
+
+// lookupUser finds the account for name. It returns nil and no error for a
+// numeric uid that has no account.
+func lookupUser(name string, lookup AccountLookup) (*user.User, error) {
+	if _, ok := parseID(name); !ok {
+		u, err := lookup.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown user %q: %w", name, err)
+		}
+		return u, nil
+	}
+	u, err := lookup.LookupID(name)
+	if errors.As(err, new(user.UnknownUserIdError)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up uid %s: %w", name, err)
+	}
+	return u, nil
+}
+
+// lookupGroup returns the gid for name, which may already be one.
+func lookupGroup(name string, lookup AccountLookup) (int, error) {
+	if gid, ok := parseID(name); ok {
+		return gid, nil
+	}
+	g, err := lookup.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %w", name, err)
+	}
+	gid, ok := parseID(g.Gid)
+	if !ok {
+		return 0, fmt.Errorf("group %q has invalid gid %q", name, g.Gid)
+	}
+	return gid, nil
+}
+
+// parseID parses a uid or gid, which is never negative.
+func parseID(s string) (int, bool) {
+	id, err := strconv.ParseUint(s, 10, 31) // fits an int on 32-bit platforms too
+	if err != nil {
+		return 0, false
+	}
+	return int(id), true
+}
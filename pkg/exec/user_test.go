@@ -0,0 +1,95 @@
+package exec
+
+import (
+	"errors"
+	"os/user"
+	"reflect"
+	"testing"
+)
+
+// fakeAccounts is an /etc/passwd and /etc/group in memory.
+type fakeAccounts struct {
+	users  []*user.User
+	groups []*user.Group
+	member map[string][]string // username -> gids
+}
+
+func (f *fakeAccounts) Lookup(name string) (*user.User, error) {
+	for _, u := range f.users {
+		if u.Username == name {
+			return u, nil
+		}
+	}
+	return nil, user.UnknownUserError(name)
+}
+
+func (f *fakeAccounts) LookupID(uid string) (*user.User, error) {
+	for _, u := range f.users {
+		if u.Uid == uid {
+			return u, nil
+		}
+	}
+	return nil, user.UnknownUserIdError(0)
+}
+
+func (f *fakeAccounts) LookupGroup(name string) (*user.Group, error) {
+	for _, g := range f.groups {
+		if g.Name == name {
+			return g, nil
+		}
+	}
+	return nil, user.UnknownGroupError(name)
+}
+
+func (f *fakeAccounts) GroupIDs(u *user.User) ([]string, error) {
+	ids, ok := f.member[u.Username]
+	if !ok {
+		return nil, errors.New("no group database")
+	}
+	return ids, nil
+}
+
+func TestResolveUser(t *testing.T) {
+	accounts := &fakeAccounts{
+		users: []*user.User{
+			{Uid: "1000", Gid: "1000", Username: "app", HomeDir: "/home/app"},
+			{Uid: "999", Gid: "999", Username: "postgres", HomeDir: "/var/lib/postgresql"},
+		},
+		groups: []*user.Group{{Gid: "1000", Name: "app"}, {Gid: "50", Name: "staff"}},
+		member: map[string][]string{"app": {"1000", "50"}},
+	}
+
+	tests := []struct {
+		spec string
+		want *Credential
+	}{
+		{"app", &Credential{UID: 1000, GID: 1000, Groups: []int{1000, 50}, Name: "app", Home: "/home/app"}},
+		{"1000", &Credential{UID: 1000, GID: 1000, Groups: []int{1000, 50}, Name: "app", Home: "/home/app"}},
+		{"app:staff", &Credential{UID: 1000, GID: 50, Groups: []int{1000, 50}, Name: "app", Home: "/home/app"}},
+		{"app:4242", &Credential{UID: 1000, GID: 4242, Groups: []int{4242, 1000, 50}, Name: "app", Home: "/home/app"}},
+		// A group database that cannot be read leaves only the primary group.
+		{"postgres", &Credential{UID: 999, GID: 999, Groups: []int{999}, Name: "postgres", Home: "/var/lib/postgresql"}},
+		// A uid with no account, as an image run with an arbitrary uid has.
+		{"4242", &Credential{UID: 4242, GID: 7, Groups: []int{7}, Home: "/"}},
+		{"4242:4242", &Credential{UID: 4242, GID: 4242, Groups: []int{4242}, Home: "/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ResolveUser(tt.spec, accounts, 7)
+			if err != nil {
+				t.Fatalf("ResolveUser(%q) error = %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveUser(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+
+	for _, spec := range []string{"", ":app", "app:", "nobody", "app:nogroup", "-1"} {
+		t.Run("error "+spec, func(t *testing.T) {
+			if _, err := ResolveUser(spec, accounts, 0); err == nil {
+				t.Errorf("ResolveUser(%q) succeeded, want an error", spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package exec
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// The identity syscalls SwitchUser makes. Can be overridden for testing, where
+// the real ones would change the test process for good.
+var (
+	setgroups = syscall.Setgroups
+	setgid    = syscall.Setgid
+	setuid    = syscall.Setuid
+)
+
+// SwitchUser makes this process run as cred, for the command it execs or
+// starts to inherit. HOME and USER are set to match, as a login would set them;
+// a uid with no account has USER removed rather than left naming root.
+//
+// Groups go first and the uid last: once the uid is dropped, the process no
+// longer has the privilege to change the others.
+func SwitchUser(cred *Credential) error {
+	if err := os.Setenv("HOME", cred.Home); err != nil {
+		return fmt.Errorf("failed to set HOME: %w", err)
+	}
+	if cred.Name != "" {
+		if err := os.Setenv("USER", cred.Name); err != nil {
+			return fmt.Errorf("failed to set USER: %w", err)
+		}
+	} else if err := os.Unsetenv("USER"); err != nil {
+		return fmt.Errorf("failed to unset USER: %w", err)
+	}
+
+	if err := setgroups(cred.Groups); err != nil {
+		return fmt.Errorf("failed to set supplementary groups: %w", err)
+	}
+	if err := setgid(cred.GID); err != nil {
+		return fmt.Errorf("failed to set gid %d: %w", cred.GID, err)
+	}
+	if err := setuid(cred.UID); err != nil {
+		return fmt.Errorf("failed to set uid %d: %w", cred.UID, err)
+	}
+	return nil
+}
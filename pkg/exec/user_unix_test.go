@@ -0,0 +1,76 @@
+//go:build unix
+
+package exec
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// stubIdentity replaces the identity syscalls for the test, recording calls.
+func stubIdentity(t *testing.T, calls *[]string, fail string) {
+	t.Helper()
+	originalSetgroups, originalSetgid, originalSetuid := setgroups, setgid, setuid
+	t.Cleanup(func() { setgroups, setgid, setuid = originalSetgroups, originalSetgid, originalSetuid })
+
+	record := func(name string) error {
+		*calls = append(*calls, name)
+		if name == fail {
+			return errors.New("operation not permitted")
+		}
+		return nil
+	}
+	setgroups = func([]int) error { return record("setgroups") }
+	setgid = func(int) error { return record("setgid") }
+	setuid = func(int) error { return record("setuid") }
+}
+
+func TestSwitchUser(t *testing.T) {
+	t.Setenv("HOME", "/root")
+	t.Setenv("USER", "root")
+	var calls []string
+	stubIdentity(t, &calls, "")
+
+	err := SwitchUser(&Credential{UID: 1000, GID: 1000, Groups: []int{1000}, Name: "app", Home: "/home/app"})
+	if err != nil {
+		t.Fatalf("SwitchUser() error = %v", err)
+	}
+
+	// The uid goes last: after it, the process may no longer change the rest.
+	if want := []string{"setgroups", "setgid", "setuid"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if os.Getenv("HOME") != "/home/app" || os.Getenv("USER") != "app" {
+		t.Errorf("HOME=%q USER=%q", os.Getenv("HOME"), os.Getenv("USER"))
+	}
+}
+
+func TestSwitchUser_BareUIDHasNoUser(t *testing.T) {
+	t.Setenv("USER", "root")
+	var calls []string
+	stubIdentity(t, &calls, "")
+
+	if err := SwitchUser(&Credential{UID: 4242, GID: 0, Groups: []int{0}, Home: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := os.LookupEnv("USER"); ok {
+		t.Error("USER is still set for a uid with no account")
+	}
+}
+
+func TestSwitchUser_StopsAtTheFirstFailure(t *testing.T) {
+	t.Setenv("HOME", "/root")
+	var calls []string
+	stubIdentity(t, &calls, "setgid")
+
+	err := SwitchUser(&Credential{UID: 1000, GID: 1000, Home: "/home/app"})
+	if err == nil {
+		t.Fatal("SwitchUser() succeeded, want an error")
+	}
+	// Running on as root with a dropped gid would be worse than not running.
+	if want := []string{"setgroups", "setgid"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package exec
+
+import "errors"
+
+// SwitchUser is not supported on Windows, which has no uid to switch to.
+func SwitchUser(cred *Credential) error {
+	return errors.New("--user is not supported on Windows")
+}
@@ -1,6 +1,7 @@
 package filecheck
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -64,6 +65,12 @@ func (c *Check) fileOnlyFlags() []string {
 
 // Run executes the file check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. ctx is unused: the
+// checks are a handful of stat and read calls, which os gives no way to cancel.
+func (c *Check) RunContext(context.Context) check.Result {
 	result := check.Result{
 		Name: "file: " + c.Path,
 	}
@@ -161,7 +168,7 @@ func (c *Check) Run() check.Result {
 }
 
 func (c *Check) checkModeMinimum(mode fs.FileMode, result *check.Result) error {
-	required, err := parseOctalMode(c.Mode)
+	required, err := ParseOctalMode(c.Mode)
 	if err != nil {
 		result.Failf("invalid mode: %v", err)
 		return err
@@ -178,7 +185,7 @@ func (c *Check) checkModeMinimum(mode fs.FileMode, result *check.Result) error {
 }
 
 func (c *Check) checkModeExact(mode fs.FileMode, result *check.Result) error {
-	required, err := parseOctalMode(c.ModeExact)
+	required, err := ParseOctalMode(c.ModeExact)
 	if err != nil {
 		result.Failf("invalid mode: %v", err)
 		return err
@@ -320,15 +327,27 @@ func (c *Check) checkOwner(result *check.Result) error {
 	return nil
 }
 
-// parseOctalMode parses an octal permission string like "0644" or "644".
+// ParseOctalMode parses an octal permission string like "0644" or "644".
 // ParseUint rejects trailing garbage, which Sscanf("%o") accepted: "0o600"
 // consumed a single 0 and yielded mode 0, silently disabling the check.
-func parseOctalMode(s string) (fs.FileMode, error) {
+//
+// The setuid, setgid and sticky digit of a mode like "4755" becomes the
+// matching fs.FileMode flag; in the low bits it would be ignored by chmod.
+func ParseOctalMode(s string) (fs.FileMode, error) {
 	mode, err := strconv.ParseUint(s, 8, 32)
 	if err != nil {
 		return 0, fmt.Errorf("invalid octal mode %q: expected octal digits like 0644", s)
 	}
-	return fs.FileMode(mode), nil
+	if mode > 0o7777 {
+		return 0, fmt.Errorf("invalid octal mode %q: expected at most 7777", s)
+	}
+	perm := fs.FileMode(mode) & fs.ModePerm
+	for bit, flag := range map[uint64]fs.FileMode{0o4000: fs.ModeSetuid, 0o2000: fs.ModeSetgid, 0o1000: fs.ModeSticky} {
+		if mode&bit != 0 {
+			perm |= flag
+		}
+	}
+	return perm, nil
 }
 
 // isSocket checks if the mode indicates a Unix socket
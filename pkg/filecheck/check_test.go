@@ -219,12 +219,16 @@ func TestParseOctalMode(t *testing.T) {
 		{"600,", 0, true},
 		{"", 0, true},
 		{"999", 0, true},
+		{"4755", fs.ModeSetuid | 0o755, false},
+		{"1777", fs.ModeSticky | 0o777, false},
+		{"7777", fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky | 0o777, false},
+		{"10000", 0, true},
 		{"-644", 0, true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.input, func(t *testing.T) {
-			got, err := parseOctalMode(tt.input)
+			got, err := ParseOctalMode(tt.input)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -1,6 +1,7 @@
 package gitcheck
 
 import (
+	"context"
 	"errors"
 	"path"
 	"strings"
@@ -20,12 +21,18 @@ type Check struct {
 
 // Run executes the git check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. The git commands behind
+// it run under ctx and are killed when it is done.
+func (c *Check) RunContext(ctx context.Context) check.Result {
 	result := check.Result{
 		Name: "git",
 	}
 
 	// First verify we're in a git repository
-	isRepo, err := c.Runner.IsGitRepo()
+	isRepo, err := c.Runner.IsGitRepo(ctx)
 	if err != nil {
 		return result.Failf("failed to check git repository: %v", err)
 	}
@@ -39,21 +46,21 @@ func (c *Check) Run() check.Result {
 
 	// Check for uncommitted/untracked changes
 	if noUncommitted || noUntracked {
-		if err := c.checkStatus(noUncommitted, noUntracked, &result); err != nil {
+		if err := c.checkStatus(ctx, noUncommitted, noUntracked, &result); err != nil {
 			return result
 		}
 	}
 
 	// Check branch
 	if c.Branch != "" {
-		if err := c.checkBranch(&result); err != nil {
+		if err := c.checkBranch(ctx, &result); err != nil {
 			return result
 		}
 	}
 
 	// Check tag match
 	if c.TagMatch != "" {
-		if err := c.checkTagMatch(&result); err != nil {
+		if err := c.checkTagMatch(ctx, &result); err != nil {
 			return result
 		}
 	}
@@ -62,8 +69,8 @@ func (c *Check) Run() check.Result {
 	return result
 }
 
-func (c *Check) checkStatus(noUncommitted, noUntracked bool, result *check.Result) error {
-	status, err := c.Runner.Status()
+func (c *Check) checkStatus(ctx context.Context, noUncommitted, noUntracked bool, result *check.Result) error {
+	status, err := c.Runner.Status(ctx)
 	if err != nil {
 		result.Failf("failed to get git status: %v", err)
 		return err
@@ -115,8 +122,8 @@ func (c *Check) checkStatus(noUncommitted, noUntracked bool, result *check.Resul
 	return nil
 }
 
-func (c *Check) checkBranch(result *check.Result) error {
-	branch, err := c.Runner.CurrentBranch()
+func (c *Check) checkBranch(ctx context.Context, result *check.Result) error {
+	branch, err := c.Runner.CurrentBranch(ctx)
 	if err != nil {
 		result.Failf("failed to get current branch: %v", err)
 		return err
@@ -132,8 +139,8 @@ func (c *Check) checkBranch(result *check.Result) error {
 	return nil
 }
 
-func (c *Check) checkTagMatch(result *check.Result) error {
-	tags, err := c.Runner.TagsAtHead()
+func (c *Check) checkTagMatch(ctx context.Context, result *check.Result) error {
+	tags, err := c.Runner.TagsAtHead(ctx)
 	if err != nil {
 		result.Failf("failed to get tags: %v", err)
 		return err
@@ -2,35 +2,41 @@ package gitcheck
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"os/exec"
 	"strings"
 )
 
-// GitRunner abstracts git command execution for testability.
+// GitRunner abstracts git command execution for testability. Each method runs
+// git under ctx, so a slow repository does not outlast the check's deadline.
 type GitRunner interface {
 	// IsGitRepo returns true if the current directory is inside a git repository.
-	IsGitRepo() (bool, error)
+	IsGitRepo(ctx context.Context) (bool, error)
 
 	// Status returns the output of 'git status --porcelain'.
 	// Lines starting with '??' are untracked, others are staged/modified.
-	Status() (string, error)
+	Status(ctx context.Context) (string, error)
 
 	// CurrentBranch returns the name of the current branch.
 	// Returns "HEAD" if in detached HEAD state.
-	CurrentBranch() (string, error)
+	CurrentBranch(ctx context.Context) (string, error)
 
 	// TagsAtHead returns all tags pointing at the current HEAD commit.
-	TagsAtHead() ([]string, error)
+	TagsAtHead(ctx context.Context) ([]string, error)
 }
 
 // RealGitRunner executes actual git commands.
 type RealGitRunner struct{}
 
-func (r *RealGitRunner) IsGitRepo() (bool, error) {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
+func (r *RealGitRunner) IsGitRepo(ctx context.Context) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--git-dir")
 	err := cmd.Run()
 	if err != nil {
+		// A git killed by ctx also exits non-zero; that is not an answer.
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
 		// ExitError means git ran but returned non-zero (not a git repo)
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
@@ -42,8 +48,8 @@ func (r *RealGitRunner) IsGitRepo() (bool, error) {
 	return true, nil
 }
 
-func (r *RealGitRunner) Status() (string, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
+func (r *RealGitRunner) Status(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	err := cmd.Run()
@@ -53,8 +59,8 @@ func (r *RealGitRunner) Status() (string, error) {
 	return strings.TrimSpace(out.String()), nil
 }
 
-func (r *RealGitRunner) CurrentBranch() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+func (r *RealGitRunner) CurrentBranch(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	err := cmd.Run()
@@ -64,8 +70,8 @@ func (r *RealGitRunner) CurrentBranch() (string, error) {
 	return strings.TrimSpace(out.String()), nil
 }
 
-func (r *RealGitRunner) TagsAtHead() ([]string, error) {
-	cmd := exec.Command("git", "tag", "--points-at", "HEAD")
+func (r *RealGitRunner) TagsAtHead(ctx context.Context) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "tag", "--points-at", "HEAD")
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	err := cmd.Run()
@@ -1,17 +1,20 @@
 package gitcheck
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
 )
 
 func TestRealGitRunner_IsGitRepo(t *testing.T) {
 	runner := &RealGitRunner{}
-	isRepo, err := runner.IsGitRepo()
+	isRepo, err := runner.IsGitRepo(t.Context())
 	require.NoError(t, err)
 	if !isRepo {
 		t.Skip("not running in a git repository")
@@ -20,20 +23,20 @@ func TestRealGitRunner_IsGitRepo(t *testing.T) {
 
 func TestRealGitRunner_Status(t *testing.T) {
 	runner := &RealGitRunner{}
-	_, err := runner.Status()
+	_, err := runner.Status(t.Context())
 	assert.NoError(t, err)
 }
 
 func TestRealGitRunner_CurrentBranch(t *testing.T) {
 	runner := &RealGitRunner{}
-	branch, err := runner.CurrentBranch()
+	branch, err := runner.CurrentBranch(t.Context())
 	require.NoError(t, err)
 	assert.NotEmpty(t, branch)
 }
 
 func TestRealGitRunner_TagsAtHead(t *testing.T) {
 	runner := &RealGitRunner{}
-	_, err := runner.TagsAtHead()
+	_, err := runner.TagsAtHead(t.Context())
 	assert.NoError(t, err)
 }
 
@@ -62,11 +65,23 @@ func TestRealGitRunner_IsGitRepo_NotRepo(t *testing.T) {
 	require.NoError(t, os.Chdir(tmpDir))
 
 	runner := &RealGitRunner{}
-	isRepo, err := runner.IsGitRepo()
+	isRepo, err := runner.IsGitRepo(t.Context())
 	require.NoError(t, err)
 	assert.False(t, isRepo)
 }
 
+func TestRealGitRunner_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	runner := &RealGitRunner{}
+	_, err := runner.IsGitRepo(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	c := Check{Clean: true, Runner: runner}
+	assert.Equal(t, check.StatusFail, c.RunContext(ctx).Status)
+}
+
 func TestRealGitRunner_TagsAtHead_WithTag(t *testing.T) {
 	tmpDir := t.TempDir()
 	oldWd, err := os.Getwd()
@@ -85,7 +100,7 @@ func TestRealGitRunner_TagsAtHead_WithTag(t *testing.T) {
 	git(t, "tag", "v1.0.0")
 
 	runner := &RealGitRunner{}
-	tags, err := runner.TagsAtHead()
+	tags, err := runner.TagsAtHead(t.Context())
 	require.NoError(t, err)
 	assert.Equal(t, []string{"v1.0.0"}, tags)
 }
@@ -97,7 +112,7 @@ type mockGitRunner struct {
 	TagsAtHeadFunc    func() ([]string, error)
 }
 
-func (m *mockGitRunner) IsGitRepo() (bool, error)       { return m.IsGitRepoFunc() }
-func (m *mockGitRunner) Status() (string, error)        { return m.StatusFunc() }
-func (m *mockGitRunner) CurrentBranch() (string, error) { return m.CurrentBranchFunc() }
-func (m *mockGitRunner) TagsAtHead() ([]string, error)  { return m.TagsAtHeadFunc() }
+func (m *mockGitRunner) IsGitRepo(context.Context) (bool, error)       { return m.IsGitRepoFunc() }
+func (m *mockGitRunner) Status(context.Context) (string, error)        { return m.StatusFunc() }
+func (m *mockGitRunner) CurrentBranch(context.Context) (string, error) { return m.CurrentBranchFunc() }
+func (m *mockGitRunner) TagsAtHead(context.Context) ([]string, error)  { return m.TagsAtHeadFunc() }
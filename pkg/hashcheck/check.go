@@ -2,6 +2,7 @@ package hashcheck
 
 import (
 	"bufio"
+	"context"
 	"crypto/md5"  //nolint:gosec // MD5 support is intentional for legacy use
 	"crypto/sha1" //nolint:gosec // SHA1 support is intentional for legacy use
 	"crypto/sha256"
@@ -105,7 +106,14 @@ func DetectAlgorithm(hashStr string) HashAlgorithm {
 	}
 }
 
+// Run executes the hash check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. Hashing stops between
+// reads once ctx is done, so a large file does not outlast the deadline.
+func (c *Check) RunContext(ctx context.Context) check.Result {
 	result := check.Result{
 		Name: "hash: " + c.File,
 	}
@@ -165,7 +173,7 @@ func (c *Check) Run() check.Result {
 	}
 	defer func() { _ = f.Close() }()
 
-	actualHash, err := c.computeHash(f, algorithm)
+	actualHash, err := c.computeHash(ctxReader{ctx, f}, algorithm)
 	if err != nil {
 		return result.Failf("failed to compute hash: %v", err)
 	}
@@ -201,6 +209,20 @@ func (c *Check) computeHash(r io.Reader, algorithm HashAlgorithm) (string, error
 	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// ctxReader fails the next Read once ctx is done, so io.Copy gives up partway
+// through a file instead of reading it to the end.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
 var bsdFormatRegex = regexp.MustCompile(`^(SHA256|SHA384|SHA512|SHA1|MD5)\s+\((.+)\)\s*=\s*([a-fA-F0-9]+)$`)
 
 func (c *Check) parseChecksumFile() (string, HashAlgorithm, error) {
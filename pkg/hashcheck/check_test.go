@@ -1,6 +1,7 @@
 package hashcheck
 
 import (
+	"context"
 	"errors"
 	"io"
 	"os"
@@ -99,6 +100,16 @@ func TestHashCheck(t *testing.T) {
 	}
 }
 
+func TestHashCheck_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	c := Check{File: "test.txt", ExpectedHash: testContentSHA256, Opener: opener("test content")}
+	result := c.RunContext(ctx)
+	assert.Equal(t, check.StatusFail, result.Status)
+	assert.Contains(t, strings.Join(result.Details, " "), "context canceled")
+}
+
 func TestChecksumFileParsing(t *testing.T) {
 	tests := []struct {
 		name            string
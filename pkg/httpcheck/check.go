@@ -2,6 +2,7 @@ package httpcheck
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -12,6 +13,7 @@ import (
 	"github.com/vertti/preflight/pkg/check"
 	"github.com/vertti/preflight/pkg/httpclient"
 	"github.com/vertti/preflight/pkg/jsonpath"
+	"github.com/vertti/preflight/pkg/retry"
 )
 
 // FileReader abstracts file reading for testability.
@@ -35,8 +37,7 @@ type Check struct {
 	Method          string            // HTTP method (default: GET)
 	Headers         map[string]string // custom headers
 	Insecure        bool              // skip TLS verification
-	Retry           int               // retry count on failure
-	RetryDelay      time.Duration     // delay between retries
+	Retry           retry.Policy      // how to retry a failed request
 	Body            string            // request body string
 	BodyFile        string            // path to file containing request body
 	Contains        string            // response body must contain this string
@@ -48,6 +49,12 @@ type Check struct {
 
 // Run executes the HTTP health check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run with every request made under ctx, and the pauses between
+// retries cut short by it.
+func (c *Check) RunContext(ctx context.Context) check.Result {
 	result := check.Result{
 		Name: "http: " + c.URL,
 	}
@@ -74,10 +81,6 @@ func (c *Check) Run() check.Result {
 	if timeout == 0 {
 		timeout = 5 * time.Second
 	}
-	retryDelay := c.RetryDelay
-	if retryDelay == 0 {
-		retryDelay = 1 * time.Second
-	}
 
 	// Initialize client if not injected
 	client := c.Client
@@ -101,95 +104,81 @@ func (c *Check) Run() check.Result {
 		bodyBytes = []byte(c.Body)
 	}
 
-	// Retry loop
-	maxAttempts := c.Retry + 1
+	return c.Retry.Do(ctx, func() (check.Result, bool) {
+		return c.attempt(ctx, client, method, expectedStatus, bodyBytes)
+	})
+}
 
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		var bodyReader io.Reader = http.NoBody
-		if len(bodyBytes) > 0 {
-			bodyReader = bytes.NewReader(bodyBytes)
-		}
-		req, err := http.NewRequest(method, c.URL, bodyReader)
-		if err != nil {
-			return result.Failf("failed to create request: %v", err)
-		}
+// WithRetry sets the check's retry policy.
+func (c *Check) WithRetry(p retry.Policy) check.Checker {
+	c.Retry = p
+	return c
+}
 
-		// Add custom headers
-		for k, v := range c.Headers {
-			req.Header.Set(k, v)
-		}
+// attempt makes one request. A failure that a later request could turn into a
+// pass is retryable; one that would fail the same way every time is not.
+func (c *Check) attempt(ctx context.Context, client httpclient.Client, method string, expectedStatus int, bodyBytes []byte) (result check.Result, retryable bool) {
+	result = check.Result{
+		Name: "http: " + c.URL,
+	}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			if attempt < maxAttempts {
-				time.Sleep(retryDelay)
-				continue
-			}
-			return result.FailAfter(maxAttempts, "request failed: %v", err)
-		}
+	var bodyReader io.Reader = http.NoBody
+	if len(bodyBytes) > 0 {
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.URL, bodyReader)
+	if err != nil {
+		return result.Failf("failed to create request: %v", err), false
+	}
 
-		statusCode := resp.StatusCode
-
-		// Read response body if needed for --contains or --json-path check
-		var respBody string
-		if c.Contains != "" || c.JSONPath != "" {
-			body, err := httpclient.ReadBody(resp.Body)
-			_ = resp.Body.Close()
-			if err != nil {
-				return result.Failf("failed to read response body: %v", err)
-			}
-			respBody = body
-		} else {
-			_ = resp.Body.Close()
-		}
+	// Add custom headers
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
 
-		if statusCode != expectedStatus {
-			if attempt < maxAttempts {
-				time.Sleep(retryDelay)
-				continue
-			}
-			return result.FailAfter(maxAttempts, "status %d, expected %d", statusCode, expectedStatus)
-		}
+	resp, err := client.Do(req)
+	if err != nil {
+		return result.Failf("request failed: %v", err), true
+	}
 
-		// Check --contains
-		if c.Contains != "" && !strings.Contains(respBody, c.Contains) {
-			if attempt < maxAttempts {
-				time.Sleep(retryDelay)
-				continue
-			}
-			return result.FailAfter(maxAttempts, "response body does not contain %q", c.Contains)
-		}
+	statusCode := resp.StatusCode
 
-		// Check --json-path
-		if c.JSONPath != "" {
-			path, expectedValue, hasExpectedValue := strings.Cut(c.JSONPath, "=")
-			jsonResult := jsonpath.Get(respBody, path)
-			if !jsonResult.Exists() {
-				if attempt < maxAttempts {
-					time.Sleep(retryDelay)
-					continue
-				}
-				return result.FailAfter(maxAttempts, "JSON path %q not found", path)
-			}
-			if hasExpectedValue && jsonResult.String() != expectedValue {
-				if attempt < maxAttempts {
-					time.Sleep(retryDelay)
-					continue
-				}
-				return result.FailAfter(maxAttempts, "JSON path %q: got %q, expected %q", path, jsonResult.String(), expectedValue)
-			}
+	// Read response body if needed for --contains or --json-path check
+	var respBody string
+	if c.Contains != "" || c.JSONPath != "" {
+		body, err := httpclient.ReadBody(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return result.Failf("failed to read response body: %v", err), false
 		}
+		respBody = body
+	} else {
+		_ = resp.Body.Close()
+	}
+
+	if statusCode != expectedStatus {
+		return result.Failf("status %d, expected %d", statusCode, expectedStatus), true
+	}
 
-		// Success
-		result.Status = check.StatusOK
-		result.AddDetailf("status %d", statusCode)
-		if maxAttempts > 1 && attempt > 1 {
-			result.AddDetailf("succeeded on attempt %d of %d", attempt, maxAttempts)
+	// Check --contains
+	if c.Contains != "" && !strings.Contains(respBody, c.Contains) {
+		return result.Failf("response body does not contain %q", c.Contains), true
+	}
+
+	// Check --json-path
+	if c.JSONPath != "" {
+		path, expectedValue, hasExpectedValue := strings.Cut(c.JSONPath, "=")
+		jsonResult := jsonpath.Get(respBody, path)
+		if !jsonResult.Exists() {
+			return result.Failf("JSON path %q not found", path), true
+		}
+		if hasExpectedValue && jsonResult.String() != expectedValue {
+			return result.Failf("JSON path %q: got %q, expected %q", path, jsonResult.String(), expectedValue), true
 		}
-		return result
 	}
 
-	// Unreachable: every path in the loop returns, or continues only while
-	// tries remain. The compiler cannot see that, so the line has to exist.
-	return result.Failf("check did not complete")
+	// Success
+	result.Status = check.StatusOK
+	result.AddDetailf("status %d", statusCode)
+	return result, true
 }
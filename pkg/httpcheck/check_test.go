@@ -2,6 +2,7 @@ package httpcheck
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/retry"
 	"github.com/vertti/preflight/pkg/testutil"
 )
 
@@ -132,7 +134,7 @@ func TestHTTPCheck(t *testing.T) {
 func TestHTTPCheckRetry(t *testing.T) {
 	t.Run("succeeds on second attempt", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/health", Retry: 2, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/health", Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			if attempts < 2 {
 				return nil, errors.New("connection refused")
@@ -146,7 +148,7 @@ func TestHTTPCheckRetry(t *testing.T) {
 
 	t.Run("exhausted after max attempts", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/health", Retry: 2, RetryDelay: time.Millisecond, Client: clientErr("connection refused")}
+		c := Check{URL: "http://localhost/health", Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: clientErr("connection refused")}
 		c.Client = &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			return nil, errors.New("connection refused")
@@ -159,7 +161,7 @@ func TestHTTPCheckRetry(t *testing.T) {
 
 	t.Run("retry on status mismatch", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/health", ExpectedStatus: 200, Retry: 1, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/health", ExpectedStatus: 200, Retry: retry.Policy{Retries: 1, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			if attempts < 2 {
 				return testutil.MockResponse(503, ""), nil
@@ -172,7 +174,7 @@ func TestHTTPCheckRetry(t *testing.T) {
 	})
 
 	t.Run("no retry message on single attempt", func(t *testing.T) {
-		c := Check{URL: "http://localhost/health", Retry: 0, Client: clientErr("connection refused")}
+		c := Check{URL: "http://localhost/health", Client: clientErr("connection refused")}
 		result := c.Run()
 		assert.Equal(t, check.StatusFail, result.Status)
 		assert.NotContains(t, strings.Join(result.Details, " "), "attempts")
@@ -180,7 +182,7 @@ func TestHTTPCheckRetry(t *testing.T) {
 
 	t.Run("exhausted on status mismatch", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/health", ExpectedStatus: 200, Retry: 2, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/health", ExpectedStatus: 200, Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			return testutil.MockResponse(503, ""), nil
 		}}}
@@ -194,7 +196,7 @@ func TestHTTPCheckRetry(t *testing.T) {
 
 	t.Run("success includes attempt info", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/health", Retry: 2, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/health", Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			if attempts < 3 {
 				return testutil.MockResponse(503, ""), nil
@@ -205,12 +207,26 @@ func TestHTTPCheckRetry(t *testing.T) {
 		assert.Equal(t, check.StatusOK, result.Status)
 		assert.Contains(t, strings.Join(result.Details, " "), "attempt 3 of 3")
 	})
+
+	t.Run("cancellation ends the retries", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		attempts := 0
+		c := Check{URL: "http://localhost/health", Retry: retry.Policy{Wait: time.Hour, Delay: time.Hour}, Client: &testutil.MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+			attempts++
+			assert.Equal(t, ctx, req.Context(), "the request carries the check's context")
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return testutil.MockResponse(503, ""), nil
+		}}}
+		result := c.RunContext(ctx)
+		assert.Equal(t, check.StatusFail, result.Status)
+		assert.Equal(t, 1, attempts)
+	})
 }
 
 func TestHTTPCheckContainsRetry(t *testing.T) {
 	t.Run("succeeds on second attempt", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/health", Contains: "healthy", Retry: 2, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/health", Contains: "healthy", Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			if attempts < 2 {
 				return testutil.MockResponse(200, `{"status": "starting"}`), nil
@@ -224,7 +240,7 @@ func TestHTTPCheckContainsRetry(t *testing.T) {
 
 	t.Run("exhausted", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/health", Contains: "healthy", Retry: 2, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/health", Contains: "healthy", Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			return testutil.MockResponse(200, `{"status": "starting"}`), nil
 		}}}
@@ -238,7 +254,7 @@ func TestHTTPCheckContainsRetry(t *testing.T) {
 func TestHTTPCheckJSONPathRetry(t *testing.T) {
 	t.Run("succeeds on second attempt", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/api", JSONPath: "status=ready", Retry: 2, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/api", JSONPath: "status=ready", Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			if attempts < 2 {
 				return testutil.MockResponse(200, `{"status": "starting"}`), nil
@@ -252,7 +268,7 @@ func TestHTTPCheckJSONPathRetry(t *testing.T) {
 
 	t.Run("exhausted on value mismatch", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/api", JSONPath: "status=ready", Retry: 2, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/api", JSONPath: "status=ready", Retry: retry.Policy{Retries: 2, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			return testutil.MockResponse(200, `{"status": "starting"}`), nil
 		}}}
@@ -264,7 +280,7 @@ func TestHTTPCheckJSONPathRetry(t *testing.T) {
 
 	t.Run("exhausted on path not found", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://localhost/api", JSONPath: "data.missing", Retry: 1, RetryDelay: time.Millisecond, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://localhost/api", JSONPath: "data.missing", Retry: retry.Policy{Retries: 1, Delay: time.Millisecond}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			return testutil.MockResponse(200, `{"data": {"id": 123}}`), nil
 		}}}
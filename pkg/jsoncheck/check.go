@@ -1,6 +1,7 @@
 package jsoncheck
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/vertti/preflight/pkg/check"
@@ -19,6 +20,12 @@ type Check struct {
 
 // Run executes the JSON check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. ctx is unused: the file
+// is read and parsed in one go.
+func (c *Check) RunContext(context.Context) check.Result {
 	result := check.Result{
 		Name: "json: " + c.File,
 	}
@@ -0,0 +1,145 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// Format selects how results are rendered.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates a --output value. An empty string is the default, text.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("unknown output format %q (want text or json)", s)
+}
+
+// Record is the machine-readable form of a check.Result.
+//
+// Deploy tooling used to screen-scrape the [OK] and [FAIL] lines, which broke
+// whenever a detail string was reworded. The fields here are the contract
+// instead; details are kept for people reading the report, not for parsing.
+//
+// Details are not sanitised the way the text renderer does it. JSON encoding
+// escapes every control character, so nothing a checked program prints can
+// forge a record of its own.
+type Record struct {
+	Name       string       `json:"name"`
+	Type       string       `json:"type"`
+	Status     check.Status `json:"status"`
+	Details    []string     `json:"details"`
+	Error      string       `json:"error,omitempty"`
+	DurationMS float64      `json:"duration_ms"`
+}
+
+// NewRecord builds the Record for one check run. checkType is the command that
+// ran it ("tcp", "env", ...), which the result's name only implies.
+func NewRecord(r check.Result, checkType string) Record {
+	rec := Record{
+		Name:       r.Name,
+		Type:       checkType,
+		Status:     r.Status,
+		Details:    r.Details,
+		DurationMS: float64(r.Duration) / float64(time.Millisecond),
+	}
+	// An empty list, not null, so consumers can iterate without a nil check.
+	if rec.Details == nil {
+		rec.Details = []string{}
+	}
+	if r.Err != nil {
+		rec.Error = r.Err.Error()
+	}
+	return rec
+}
+
+// Result converts a Record back into a check.Result, so a record decoded from
+// another process can go through the same rendering as one produced here.
+func (rec Record) Result() check.Result {
+	// The duration is rounded: the float milliseconds of a whole number of
+	// nanoseconds can multiply back to just under it.
+	r := check.Result{
+		Name:     rec.Name,
+		Status:   rec.Status,
+		Details:  rec.Details,
+		Duration: time.Duration(math.Round(rec.DurationMS * float64(time.Millisecond))),
+	}
+	if rec.Error != "" {
+		r.Err = recordError(rec.Error)
+	}
+	return r
+}
+
+// recordError carries an error message that crossed a process boundary as text.
+type recordError string
+
+func (e recordError) Error() string { return string(e) }
+
+// Report is the document `preflight run --output json` prints: every check's
+// record in file order, followed by the same counts the text summary gives.
+// Status is FAIL if any check failed, otherwise WARN if any warned.
+type Report struct {
+	File   string       `json:"file,omitempty"` // empty for checks run from Go
+	Status check.Status `json:"status"`
+	Ran    int          `json:"ran"`
+	Failed int          `json:"failed"`
+	Warned int          `json:"warned"`
+	Checks []Record     `json:"checks"`
+}
+
+// Transition is the record of a check whose status changed, as `preflight
+// watch` prints it. From is empty for a check's first result.
+type Transition struct {
+	Time time.Time    `json:"time"`
+	From check.Status `json:"from,omitempty"`
+	Record
+}
+
+// NewReport counts records and settles the status of the file they came from.
+func NewReport(file string, records []Record) Report {
+	report := Report{File: file, Status: check.StatusOK, Ran: len(records), Checks: records}
+	for _, rec := range records {
+		switch rec.Status {
+		case check.StatusFail:
+			report.Failed++
+		case check.StatusWarn:
+			report.Warned++
+		}
+	}
+	switch {
+	case report.Failed > 0:
+		report.Status = check.StatusFail
+	case report.Warned > 0:
+		report.Status = check.StatusWarn
+	}
+	return report
+}
+
+// PrintJSON writes v to stdout as a single line of JSON.
+func PrintJSON(v any) error {
+	return WriteJSON(os.Stdout, v)
+}
+
+// WriteJSON writes v to w as a single line of JSON.
+func WriteJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	// URLs and regexes are common in details, and \u0026 in place of & helps
+	// no one reading the report.
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
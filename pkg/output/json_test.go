@@ -0,0 +1,135 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatText, false},
+		{"text", FormatText, false},
+		{"json", FormatJSON, false},
+		{"JSON", "", true},
+		{"xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFormat(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	r := check.Result{
+		Name:     "tcp: db:5432",
+		Status:   check.StatusFail,
+		Details:  []string{"connection failed: refused"},
+		Err:      errors.New("connection failed: refused"),
+		Duration: 1500 * time.Microsecond,
+	}
+
+	rec := NewRecord(r, "tcp")
+
+	if rec.Name != "tcp: db:5432" || rec.Type != "tcp" || rec.Status != check.StatusFail {
+		t.Errorf("record = %+v", rec)
+	}
+	if rec.Error != "connection failed: refused" {
+		t.Errorf("Error = %q", rec.Error)
+	}
+	if rec.DurationMS != 1.5 {
+		t.Errorf("DurationMS = %v, want 1.5", rec.DurationMS)
+	}
+}
+
+// Consumers iterate details without a nil check, so null would break them.
+func TestNewRecord_DetailsAreNeverNull(t *testing.T) {
+	rec := NewRecord(check.Result{Name: "sys", Status: check.StatusOK}, "sys")
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"details":[]`) {
+		t.Errorf("got %s, want an empty details array", data)
+	}
+	if strings.Contains(string(data), `"error"`) {
+		t.Errorf("got %s, want no error field for a passing check", data)
+	}
+}
+
+func TestRecord_Result(t *testing.T) {
+	rec := Record{Name: "env: X", Status: check.StatusFail, Details: []string{"not set"}, Error: "not set"}
+
+	r := rec.Result()
+
+	if r.Name != "env: X" || r.Status != check.StatusFail || len(r.Details) != 1 {
+		t.Errorf("result = %+v", r)
+	}
+	if r.Err == nil || r.Err.Error() != "not set" {
+		t.Errorf("Err = %v, want not set", r.Err)
+	}
+
+	if d := (Record{DurationMS: 1.5}).Result().Duration; d != 1500*time.Microsecond {
+		t.Errorf("Duration = %v, want 1.5ms", d)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []check.Status
+		want     check.Status
+		failed   int
+		warned   int
+	}{
+		{"empty", nil, check.StatusOK, 0, 0},
+		{"all pass", []check.Status{check.StatusOK, check.StatusOK}, check.StatusOK, 0, 0},
+		{"a warning", []check.Status{check.StatusOK, check.StatusWarn}, check.StatusWarn, 0, 1},
+		{"a failure outranks a warning", []check.Status{check.StatusWarn, check.StatusFail, check.StatusFail}, check.StatusFail, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := []Record{}
+			for _, s := range tt.statuses {
+				records = append(records, Record{Status: s})
+			}
+
+			report := NewReport(".preflight", records)
+
+			if report.Status != tt.want || report.Ran != len(tt.statuses) || report.Failed != tt.failed || report.Warned != tt.warned {
+				t.Errorf("report = %+v", report)
+			}
+		})
+	}
+}
+
+// A checked program controls detail text. In JSON the encoder escapes control
+// characters, so the output stays one line per record whatever the detail holds.
+func TestPrintJSON_StaysOnOneLine(t *testing.T) {
+	got := captureOutput(func() {
+		_ = PrintJSON(Record{Name: "cmd: x", Status: check.StatusOK, Details: []string{"a\nb\x1b[2K & <c>"}})
+	})
+
+	if strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "\n") {
+		t.Errorf("got %q, want a single line", got)
+	}
+	if !strings.Contains(got, "& <c>") {
+		t.Errorf("got %q, want & and <> left readable", got)
+	}
+}
@@ -0,0 +1,112 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// junitSuite and junitCase are the subset of the JUnit XML schema that GitLab,
+// Jenkins and Buildkite all read: one suite, one case per check, and a failure
+// element on the cases that failed.
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes records as a JUnit XML test suite named suite.
+//
+// A failed check's error becomes the failure message, which is what CI test
+// tabs show in their summary, and every detail goes in the body. The
+// XML encoder replaces characters XML cannot carry, so program output in a
+// detail cannot break the document.
+//
+// JUnit has no notion of a warning. A warned check is a passing case with its
+// details in system-out, which CI shows alongside the case without failing it.
+func WriteJUnit(w io.Writer, suite string, records []Record) error {
+	s := junitSuite{Name: suite, Tests: len(records)}
+
+	var total float64
+	for _, rec := range records {
+		total += rec.DurationMS
+		c := junitCase{
+			Name:      rec.Name,
+			ClassName: rec.Type,
+			Time:      junitSeconds(rec.DurationMS),
+		}
+		if c.ClassName == "" {
+			c.ClassName = "preflight"
+		}
+		switch rec.Status {
+		case check.StatusOK:
+		case check.StatusWarn:
+			c.SystemOut = "WARN: " + strings.Join(rec.Details, "\n")
+		default:
+			s.Failures++
+			c.Failure = &junitFailure{
+				Message: failureMessage(rec),
+				Type:    string(rec.Status),
+				Text:    strings.Join(rec.Details, "\n"),
+			}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	s.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// failureMessage is the first line of why rec failed: its error, or without
+// one its last detail.
+//
+// The message is the failure detail, but it is read from the error rather than
+// found among the details. Result.Fail records the same text as both, and the
+// error is the one place it always is. The details are in whatever order the
+// check added them: the reason follows the steps that passed, an interrupted
+// check gets a line after it, a plugin's stderr follows it, and a plugin's own
+// error may not be a detail at all.
+func failureMessage(rec Record) string {
+	msg := rec.Error
+	if msg == "" && len(rec.Details) > 0 {
+		msg = rec.Details[len(rec.Details)-1]
+	}
+	first, _, _ := strings.Cut(msg, "\n")
+	return first
+}
+
+// junitSeconds formats a duration the way the schema wants it: seconds, as a
+// decimal.
+func junitSeconds(ms float64) string {
+	return fmt.Sprintf("%.3f", ms/1000)
+}
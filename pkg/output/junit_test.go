@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	records := []Record{
+		{Name: "env: HOME", Type: "env", Status: check.StatusOK, Details: []string{"value: /root"}, DurationMS: 1},
+		{Name: "tcp: db:5432", Type: "tcp", Status: check.StatusFail, Details: []string{"connection failed: refused", "second"}, Error: "connection failed: refused", DurationMS: 1500},
+		{Name: "env", Status: check.StatusFail, Error: "command failed without reporting a check result"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, ".preflight", records); err != nil {
+		t.Fatal(err)
+	}
+
+	var suite junitSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	if suite.Name != ".preflight" || suite.Tests != 3 || suite.Failures != 2 {
+		t.Errorf("suite = %+v", suite)
+	}
+	if suite.Time != "1.501" {
+		t.Errorf("suite time = %q, want 1.501", suite.Time)
+	}
+	if c := suite.Cases[0]; c.Name != "env: HOME" || c.ClassName != "env" || c.Failure != nil || c.Time != "0.001" {
+		t.Errorf("passing case = %+v", c)
+	}
+
+	failed := suite.Cases[1]
+	if failed.Failure == nil {
+		t.Fatal("failing check has no <failure>")
+	}
+	if failed.Failure.Message != "connection failed: refused" {
+		t.Errorf("message = %q, want the error", failed.Failure.Message)
+	}
+	if failed.Failure.Text != "connection failed: refused\nsecond" {
+		t.Errorf("body = %q, want every detail", failed.Failure.Text)
+	}
+
+	// A line that never reached a check has no details, only an error.
+	if c := suite.Cases[2]; c.ClassName != "preflight" || c.Failure == nil || !strings.Contains(c.Failure.Message, "without reporting") {
+		t.Errorf("case without a record = %+v", c)
+	}
+}
+
+// Result.Fail appends the reason after the details of the steps that passed,
+// so the message is the error, or the last detail, never the first.
+func TestWriteJUnit_FailureMessageIsTheFailure(t *testing.T) {
+	records := []Record{
+		{Name: "yaml: k8s.yaml", Type: "yaml", Status: check.StatusFail, Details: []string{"syntax: valid", `key "kind" not found`}, Error: `key "kind" not found`},
+		{Name: "cmd: sh", Type: "cmd", Status: check.StatusFail, Details: []string{"path: /usr/bin/sh", "version 5.2 < minimum 6.0\nfrom sh --version"}},
+		{Name: "disk", Type: "preflight-disk", Status: check.StatusFail, Details: []string{"/data: 91% used", "stderr: df: /mnt: stale handle"}, Error: "/data: 91% used"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, ".preflight", records); err != nil {
+		t.Fatal(err)
+	}
+	var suite junitSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	want := []string{`key "kind" not found`, "version 5.2 < minimum 6.0", "/data: 91% used"}
+	for i, c := range suite.Cases {
+		if c.Failure == nil || c.Failure.Message != want[i] {
+			t.Errorf("case %q failure = %+v, want message %q", c.Name, c.Failure, want[i])
+		}
+	}
+}
+
+// JUnit has no warning, and a warning must not fail the CI test tab.
+func TestWriteJUnit_WarningIsAPassWithOutput(t *testing.T) {
+	records := []Record{{Name: "resource", Type: "resource", Status: check.StatusWarn, Details: []string{"disk free: 12GB"}}}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, ".preflight", records); err != nil {
+		t.Fatal(err)
+	}
+	var suite junitSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	if suite.Failures != 0 {
+		t.Errorf("failures = %d, want 0", suite.Failures)
+	}
+	if c := suite.Cases[0]; c.Failure != nil || c.SystemOut != "WARN: disk free: 12GB" {
+		t.Errorf("warned case = %+v", c)
+	}
+}
+
+// Detail text comes from checked programs and can hold anything, including
+// characters XML 1.0 cannot represent at all.
+func TestWriteJUnit_ProgramOutputCannotBreakTheDocument(t *testing.T) {
+	records := []Record{{
+		Name:    "cmd: x</testcase>",
+		Status:  check.StatusFail,
+		Details: []string{"a\x1b[2K<b>&\"</failure>"},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "s", records); err != nil {
+		t.Fatal(err)
+	}
+
+	var suite junitSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+	if len(suite.Cases) != 1 || suite.Cases[0].Name != "cmd: x</testcase>" {
+		t.Errorf("cases = %+v", suite.Cases)
+	}
+}
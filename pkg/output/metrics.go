@@ -0,0 +1,195 @@
+package output
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// MetricsContentType is the media type of the Prometheus text exposition format.
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	metricUp          = "preflight_check_up"
+	metricDuration    = "preflight_check_duration_seconds"
+	metricLastSuccess = "preflight_check_last_success_timestamp"
+)
+
+// metricKey identifies a check's series.
+type metricKey struct {
+	name, checkType string
+}
+
+// metricSeries is what one series reports.
+type metricSeries struct {
+	key      metricKey
+	up       bool
+	duration time.Duration
+}
+
+// Metrics renders check results in the Prometheus text exposition format, so
+// that a failing dependency can be alerted on like anything else Prometheus
+// scrapes.
+//
+// Every check becomes three series labelled with its name and type: whether it
+// passed, how long it took, and when it last passed. The last of those outlives
+// a single run, which is why Metrics is kept between observations rather than
+// built from one set of records. A zero Metrics is ready to use and is safe for
+// concurrent use.
+type Metrics struct {
+	mu          sync.Mutex
+	series      []metricSeries
+	lastSuccess map[metricKey]float64 // Unix seconds
+}
+
+// Observe replaces the latest results with records, which finished at at.
+//
+// Only OK counts as passing. A warning is a check that failed and was allowed
+// to, and the metric says so even though the run did not fail.
+//
+// Lines that check the same thing share a name and type, and Prometheus cannot
+// take the same series twice. They are reported as one: up only if every one
+// of them passed, for as long as they took together.
+func (m *Metrics) Observe(records []Record, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lastSuccess == nil {
+		m.lastSuccess = map[metricKey]float64{}
+	}
+
+	m.series = m.series[:0]
+	index := map[metricKey]int{}
+	for _, rec := range records {
+		key := metricKey{rec.Name, rec.Type}
+		up := rec.Status == check.StatusOK
+		// Summed as a Duration and converted once: seconds worked out from
+		// float milliseconds carry noise such as 0.000035035999999999995.
+		duration := rec.Result().Duration
+		if i, ok := index[key]; ok {
+			m.series[i].up = m.series[i].up && up
+			m.series[i].duration += duration
+			continue
+		}
+		index[key] = len(m.series)
+		m.series = append(m.series, metricSeries{key: key, up: up, duration: duration})
+	}
+	for _, s := range m.series {
+		if s.up {
+			m.lastSuccess[s.key] = float64(at.UnixNano()) / float64(time.Second)
+		}
+	}
+}
+
+// WriteTo writes the latest results. A check that has never passed has no
+// last-success series, rather than one claiming it passed in 1970.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	family := func(name, help string, value func(metricSeries) (float64, bool)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, s := range m.series {
+			if v, ok := value(s); ok {
+				fmt.Fprintf(&b, "%s{name=%s,type=%s} %s\n", name, quoteLabel(s.key.name), quoteLabel(s.key.checkType), strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+	}
+	family(metricUp, "Whether the check passed (1) or not (0).", func(s metricSeries) (float64, bool) {
+		if s.up {
+			return 1, true
+		}
+		return 0, true
+	})
+	family(metricDuration, "How long the check took to run.", func(s metricSeries) (float64, bool) {
+		return s.duration.Seconds(), true
+	})
+	family(metricLastSuccess, "When the check last passed, in seconds since the epoch.", func(s metricSeries) (float64, bool) {
+		t, ok := m.lastSuccess[s.key]
+		return t, ok
+	})
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ReadLastSuccess takes the last-success times from metrics written earlier,
+// so a check that fails in this run still reports when it last passed. A
+// one-shot run has no memory of its own, and a file it wrote last time is the
+// only place that time is kept. Lines that are not last-success samples are
+// skipped.
+func (m *Metrics) ReadLastSuccess(r io.Reader) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lastSuccess == nil {
+		m.lastSuccess = map[metricKey]float64{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		rest, ok := strings.CutPrefix(scanner.Text(), metricLastSuccess+"{")
+		if !ok {
+			continue
+		}
+		labels, rest, err := parseLabels(rest)
+		if err != nil {
+			return err
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(rest), 64)
+		if err != nil {
+			return fmt.Errorf("invalid sample value %q", strings.TrimSpace(rest))
+		}
+		m.lastSuccess[metricKey{labels["name"], labels["type"]}] = value
+	}
+	return scanner.Err()
+}
+
+// labelEscaper escapes a label value as the exposition format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+// parseLabels reads `a="x",b="y"}` from the start of s and returns what
+// follows the closing brace.
+func parseLabels(s string) (labels map[string]string, rest string, err error) {
+	labels = map[string]string{}
+	for {
+		s = strings.TrimLeft(s, ", ")
+		if after, ok := strings.CutPrefix(s, "}"); ok {
+			return labels, after, nil
+		}
+		name, value, ok := strings.Cut(s, `="`)
+		if !ok {
+			return nil, "", errors.New("malformed label set")
+		}
+		var b strings.Builder
+		i := 0
+		for ; i < len(value) && value[i] != '"'; i++ {
+			if value[i] == '\\' && i+1 < len(value) {
+				i++
+				switch value[i] {
+				case 'n':
+					b.WriteByte('\n')
+				default:
+					b.WriteByte(value[i])
+				}
+				continue
+			}
+			b.WriteByte(value[i])
+		}
+		if i == len(value) {
+			return nil, "", errors.New("unterminated label value")
+		}
+		labels[strings.TrimSpace(name)] = b.String()
+		s = value[i+1:]
+	}
+}
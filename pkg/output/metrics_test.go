@@ -0,0 +1,144 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+func TestMetrics(t *testing.T) {
+	var m Metrics
+	m.Observe([]Record{
+		{Name: "tcp: db:5432", Type: "tcp", Status: check.StatusOK, DurationMS: 12.5},
+		{Name: "env: API_KEY", Type: "env", Status: check.StatusFail, DurationMS: 0.5},
+		{Name: "file: /data", Type: "file", Status: check.StatusWarn, DurationMS: 1},
+	}, time.Unix(1700000000, 0))
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP preflight_check_up Whether the check passed (1) or not (0).
+# TYPE preflight_check_up gauge
+preflight_check_up{name="tcp: db:5432",type="tcp"} 1
+preflight_check_up{name="env: API_KEY",type="env"} 0
+preflight_check_up{name="file: /data",type="file"} 0
+# HELP preflight_check_duration_seconds How long the check took to run.
+# TYPE preflight_check_duration_seconds gauge
+preflight_check_duration_seconds{name="tcp: db:5432",type="tcp"} 0.0125
+preflight_check_duration_seconds{name="env: API_KEY",type="env"} 0.0005
+preflight_check_duration_seconds{name="file: /data",type="file"} 0.001
+# HELP preflight_check_last_success_timestamp When the check last passed, in seconds since the epoch.
+# TYPE preflight_check_last_success_timestamp gauge
+preflight_check_last_success_timestamp{name="tcp: db:5432",type="tcp"} 1700000000
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+// Durations come from records, in float milliseconds. Worked out naively, the
+// seconds of a real run come out as 0.000035035999999999995.
+func TestMetrics_DurationsAreExact(t *testing.T) {
+	var m Metrics
+	m.Observe([]Record{
+		NewRecord(check.Result{Name: "env: A", Status: check.StatusOK, Duration: 35036 * time.Nanosecond}, "env"),
+		NewRecord(check.Result{Name: "env: B", Status: check.StatusOK, Duration: 1234567891 * time.Nanosecond}, "env"),
+		NewRecord(check.Result{Name: "env: B", Status: check.StatusOK, Duration: 100 * time.Nanosecond}, "env"),
+	}, time.Unix(1700000000, 0))
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP preflight_check_up Whether the check passed (1) or not (0).
+# TYPE preflight_check_up gauge
+preflight_check_up{name="env: A",type="env"} 1
+preflight_check_up{name="env: B",type="env"} 1
+# HELP preflight_check_duration_seconds How long the check took to run.
+# TYPE preflight_check_duration_seconds gauge
+preflight_check_duration_seconds{name="env: A",type="env"} 0.000035036
+preflight_check_duration_seconds{name="env: B",type="env"} 1.234567991
+# HELP preflight_check_last_success_timestamp When the check last passed, in seconds since the epoch.
+# TYPE preflight_check_last_success_timestamp gauge
+preflight_check_last_success_timestamp{name="env: A",type="env"} 1700000000
+preflight_check_last_success_timestamp{name="env: B",type="env"} 1700000000
+`
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestMetrics_LastSuccessOutlivesAFailure(t *testing.T) {
+	var m Metrics
+	m.Observe([]Record{{Name: "tcp: db", Type: "tcp", Status: check.StatusOK}}, time.Unix(100, 0))
+	m.Observe([]Record{{Name: "tcp: db", Type: "tcp", Status: check.StatusFail}}, time.Unix(200, 0))
+
+	var b strings.Builder
+	_, _ = m.WriteTo(&b)
+	if !strings.Contains(b.String(), `preflight_check_up{name="tcp: db",type="tcp"} 0`) ||
+		!strings.Contains(b.String(), `preflight_check_last_success_timestamp{name="tcp: db",type="tcp"} 100`) {
+		t.Errorf("got\n%s", b.String())
+	}
+}
+
+// Prometheus rejects a series given twice, which would lose every metric in
+// the file, so two lines with the same name and type become one series.
+func TestMetrics_DuplicateChecksShareASeries(t *testing.T) {
+	var m Metrics
+	m.Observe([]Record{
+		{Name: "env: HOME", Type: "env", Status: check.StatusOK, DurationMS: 1},
+		{Name: "env: HOME", Type: "env", Status: check.StatusFail, DurationMS: 2},
+	}, time.Unix(100, 0))
+
+	var b strings.Builder
+	_, _ = m.WriteTo(&b)
+	out := b.String()
+	if strings.Count(out, "preflight_check_up{") != 1 || !strings.Contains(out, `preflight_check_up{name="env: HOME",type="env"} 0`) {
+		t.Errorf("got\n%s", out)
+	}
+	if !strings.Contains(out, `preflight_check_duration_seconds{name="env: HOME",type="env"} 0.003`) {
+		t.Errorf("got\n%s", out)
+	}
+	if strings.Contains(out, "preflight_check_last_success_timestamp{") {
+		t.Errorf("a series that failed has no new last success:\n%s", out)
+	}
+}
+
+func TestMetrics_ReadLastSuccessRoundTrips(t *testing.T) {
+	name := `file: C:\data "quoted"` + "\nnext"
+
+	var first Metrics
+	first.Observe([]Record{{Name: name, Type: "file", Status: check.StatusOK}}, time.Unix(1700000000, 500000000))
+	var b strings.Builder
+	_, _ = first.WriteTo(&b)
+
+	var second Metrics
+	if err := second.ReadLastSuccess(strings.NewReader(b.String())); err != nil {
+		t.Fatal(err)
+	}
+	second.Observe([]Record{{Name: name, Type: "file", Status: check.StatusFail}}, time.Unix(1800000000, 0))
+	var again strings.Builder
+	_, _ = second.WriteTo(&again)
+
+	if !strings.Contains(again.String(), "} 1700000000.5\n") {
+		t.Errorf("got\n%s", again.String())
+	}
+}
+
+func TestMetrics_ReadLastSuccessErrors(t *testing.T) {
+	for _, in := range []string{
+		`preflight_check_last_success_timestamp{name="x 1`,
+		`preflight_check_last_success_timestamp{name} 1`,
+		`preflight_check_last_success_timestamp{name="x",type="y"} soon`,
+	} {
+		var m Metrics
+		if err := m.ReadLastSuccess(strings.NewReader(in)); err == nil {
+			t.Errorf("ReadLastSuccess(%q) succeeded", in)
+		}
+	}
+}
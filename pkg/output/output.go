@@ -1,10 +1,14 @@
 package output
 
 import (
+	"cmp"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"golang.org/x/term"
@@ -13,15 +17,16 @@ import (
 )
 
 var (
-	green = "\033[32m"
-	red   = "\033[31m"
-	dim   = "\033[90m"
-	reset = "\033[0m"
+	green  = "\033[32m"
+	red    = "\033[31m"
+	yellow = "\033[33m"
+	dim    = "\033[90m"
+	reset  = "\033[0m"
 )
 
 func init() {
 	if !shouldEnableColor() {
-		green, red, dim, reset = "", "", "", ""
+		green, red, yellow, dim, reset = "", "", "", "", ""
 	}
 }
 
@@ -123,38 +128,118 @@ func isCIEnvironment() bool {
 
 // PrintResult outputs a check result with colored status.
 func PrintResult(r check.Result) {
-	if r.OK() {
-		fmt.Printf("%s[OK]%s %s\n", green, reset, formatLabel(sanitizeInline(r.Name)))
-		// Align with content after "[OK] ".
-		printDetails(r.Details, "     ", true)
-		return
+	WriteResult(os.Stdout, r)
+}
+
+// WriteResult writes a check result to w as PrintResult would.
+func WriteResult(w io.Writer, r check.Result) {
+	writeResult(w, "", r, "")
+}
+
+// PrintResultVerbose is PrintResult with how long the check took on its line.
+func PrintResultVerbose(r check.Result) {
+	writeResult(os.Stdout, "", r, fmt.Sprintf(" %s(%s)%s", dim, formatDuration(r.Duration), reset))
+}
+
+// PrintTable outputs one row per record with its status and duration, slowest
+// first. It closes a run of many checks: the results above it may have scrolled
+// away, and their order says nothing about where the time went.
+func PrintTable(records []Record) {
+	WriteTable(os.Stdout, records)
+}
+
+// WriteTable writes the table PrintTable prints to w.
+func WriteTable(w io.Writer, records []Record) {
+	rows := slices.Clone(records)
+	slices.SortStableFunc(rows, func(a, b Record) int {
+		return cmp.Compare(b.DurationMS, a.DurationMS)
+	})
+
+	durations := make([]string, len(rows))
+	width := 0
+	for i, rec := range rows {
+		durations[i] = formatDuration(time.Duration(rec.DurationMS * float64(time.Millisecond)))
+		width = max(width, len(durations[i]))
 	}
 
-	fmt.Printf("%s[FAIL]%s %s\n", red, reset, formatLabel(sanitizeInline(r.Name)))
-	// Align with content after "[FAIL] ".
-	printDetails(r.Details, "       ", false)
+	_, _ = fmt.Fprintln(w)
+	for i, rec := range rows {
+		color := green
+		switch rec.Status {
+		case check.StatusWarn:
+			color = yellow
+		case check.StatusFail:
+			color = red
+		}
+		// Padded outside the color codes, which would otherwise count as width.
+		status := fmt.Sprintf("%s%s%s%s", color, rec.Status, reset, strings.Repeat(" ", 4-len(rec.Status)))
+		_, _ = fmt.Fprintf(w, "%s  %*s  %s\n", status, width, durations[i], sanitizeInline(rec.Name))
+	}
+}
+
+// formatDuration rounds d to what a person comparing checks cares about:
+// milliseconds under a second, hundredths of a second above.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return "<1ms"
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
+// PrintTransition outputs a result whose status differs from the one before
+// it, stamped with when it changed. from is empty for a check's first result.
+func PrintTransition(at time.Time, from check.Status, r check.Result) {
+	suffix := ""
+	if from != "" {
+		suffix = fmt.Sprintf(" %s(%s -> %s)%s", dim, from, r.Status, reset)
+	}
+	writeResult(os.Stdout, at.Format(time.DateTime)+" ", r, suffix)
+}
+
+// writeResult writes r between prefix and suffix, with its details aligned
+// under the name.
+func writeResult(w io.Writer, prefix string, r check.Result, suffix string) {
+	pad := strings.Repeat(" ", len(prefix))
+	switch r.Status {
+	case check.StatusOK:
+		_, _ = fmt.Fprintf(w, "%s%s[OK]%s %s%s\n", prefix, green, reset, formatLabel(sanitizeInline(r.Name)), suffix)
+		// Align with content after "[OK] ".
+		writeDetails(w, r.Details, pad+"     ", "")
+	case check.StatusWarn:
+		_, _ = fmt.Fprintf(w, "%s%s[WARN]%s %s%s\n", prefix, yellow, reset, formatLabel(sanitizeInline(r.Name)), suffix)
+		// Align with content after "[WARN] ".
+		writeDetails(w, r.Details, pad+"       ", yellow)
+	default:
+		_, _ = fmt.Fprintf(w, "%s%s[FAIL]%s %s%s\n", prefix, red, reset, formatLabel(sanitizeInline(r.Name)), suffix)
+		// Align with content after "[FAIL] ".
+		writeDetails(w, r.Details, pad+"       ", red)
+	}
 }
 
-// printDetails writes each detail under the result line, indenting every line of
+// writeDetails writes each detail under the result line, indenting every line of
 // it. A detail routinely spans several lines — a version banner, a stderr dump,
 // an HTTP body — and indenting the continuation lines is what keeps a checked
 // program from forging a result of its own: those start at column 0.
 //
 // Blank lines are left blank rather than indented, so no line carries trailing
-// whitespace.
-func printDetails(details []string, indent string, ok bool) {
+// whitespace. A problem's details are drawn whole in its status color; a pass,
+// given none, has its labels dimmed instead.
+func writeDetails(w io.Writer, details []string, indent, color string) {
 	for _, d := range details {
 		for i, line := range strings.Split(sanitizeBlock(d), "\n") {
 			switch {
 			case line == "":
-				fmt.Println()
-			case !ok:
-				fmt.Printf("%s%s%s%s\n", indent, red, line, reset)
+				_, _ = fmt.Fprintln(w)
+			case color != "":
+				_, _ = fmt.Fprintf(w, "%s%s%s%s\n", indent, color, line, reset)
 			case i == 0:
 				// Only the opening line carries the "label:" that dimming applies to.
-				fmt.Printf("%s%s\n", indent, formatLabel(line))
+				_, _ = fmt.Fprintf(w, "%s%s\n", indent, formatLabel(line))
 			default:
-				fmt.Printf("%s%s\n", indent, line)
+				_, _ = fmt.Fprintf(w, "%s%s\n", indent, line)
 			}
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/vertti/preflight/pkg/check"
 )
@@ -104,6 +105,108 @@ func TestPrintResultFail(t *testing.T) {
 	}
 }
 
+func TestPrintResultWarn(t *testing.T) {
+	output := captureOutput(func() {
+		oldYellow, oldReset, oldDim := yellow, reset, dim
+		yellow, reset, dim = "", "", ""
+		defer func() { yellow, reset, dim = oldYellow, oldReset, oldDim }()
+
+		PrintResult(check.Result{
+			Name:    "resource",
+			Status:  check.StatusWarn,
+			Details: []string{"disk space 12.0GB < required 20.0GB"},
+		})
+	})
+
+	// "[WARN] " is as wide as "[FAIL] ", so details line up the same way.
+	expected := "[WARN] resource\n       disk space 12.0GB < required 20.0GB\n"
+	if output != expected {
+		t.Errorf("PrintResult output = %q, want %q", output, expected)
+	}
+}
+
+func TestPrintTransition(t *testing.T) {
+	oldRed, oldGreen, oldReset, oldDim := red, green, reset, dim
+	red, green, reset, dim = "", "", "", ""
+	defer func() { red, green, reset, dim = oldRed, oldGreen, oldReset, oldDim }()
+	at := time.Date(2026, 10, 16, 15, 4, 5, 0, time.Local)
+
+	output := captureOutput(func() {
+		PrintTransition(at, "", check.Result{Name: "tcp: db:5432", Status: check.StatusOK})
+		PrintTransition(at, check.StatusOK, check.Result{
+			Name:    "tcp: db:5432",
+			Status:  check.StatusFail,
+			Details: []string{"connection refused"},
+		})
+	})
+
+	// Details line up under the name, past the timestamp.
+	expected := "2026-10-16 15:04:05 [OK] tcp: db:5432\n" +
+		"2026-10-16 15:04:05 [FAIL] tcp: db:5432 (OK -> FAIL)\n" +
+		"                           connection refused\n"
+	if output != expected {
+		t.Errorf("PrintTransition output = %q, want %q", output, expected)
+	}
+}
+
+func TestPrintResultVerbose(t *testing.T) {
+	oldGreen, oldReset, oldDim := green, reset, dim
+	green, reset, dim = "", "", ""
+	defer func() { green, reset, dim = oldGreen, oldReset, oldDim }()
+
+	output := captureOutput(func() {
+		PrintResultVerbose(check.Result{Name: "tcp: db:5432", Status: check.StatusOK, Duration: 1250 * time.Millisecond})
+	})
+
+	if want := "[OK] tcp: db:5432 (1.25s)\n"; output != want {
+		t.Errorf("PrintResultVerbose output = %q, want %q", output, want)
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	oldRed, oldGreen, oldYellow, oldReset := red, green, yellow, reset
+	red, green, yellow, reset = "", "", "", ""
+	defer func() { red, green, yellow, reset = oldRed, oldGreen, oldYellow, oldReset }()
+
+	output := captureOutput(func() {
+		PrintTable([]Record{
+			{Name: "env: HOME", Status: check.StatusOK, DurationMS: 0.2},
+			{Name: "tcp: db:5432", Status: check.StatusFail, DurationMS: 5012},
+			{Name: "cmd: node", Status: check.StatusOK, DurationMS: 48.7},
+			{Name: "env: A", Status: check.StatusWarn, DurationMS: 0.2},
+		})
+	})
+
+	// Slowest first; a tie keeps file order.
+	expected := "\n" +
+		"FAIL  5.01s  tcp: db:5432\n" +
+		"OK     48ms  cmd: node\n" +
+		"OK     <1ms  env: HOME\n" +
+		"WARN   <1ms  env: A\n"
+	if output != expected {
+		t.Errorf("PrintTable output = %q, want %q", output, expected)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "<1ms"},
+		{999 * time.Microsecond, "<1ms"},
+		{time.Millisecond, "1ms"},
+		{999 * time.Millisecond, "999ms"},
+		{time.Second, "1.00s"},
+		{90 * time.Second, "90.00s"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.in); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
 func TestPrintResultIndentation(t *testing.T) {
 	// Test that OK and FAIL have correct indentation for alignment
 	okOutput := captureOutput(func() {
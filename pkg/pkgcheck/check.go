@@ -0,0 +1,157 @@
+// Package pkgcheck checks that a package is installed by reading its package
+// manager's database directly, so a check needs neither a shell nor the
+// package manager itself, which slim images often leave out.
+package pkgcheck
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/version"
+)
+
+// The package managers whose databases Check reads.
+const (
+	ManagerApt = "apt"
+	ManagerApk = "apk"
+	ManagerPip = "pip"
+	ManagerNpm = "npm"
+)
+
+// Managers lists every package manager Check reads.
+var Managers = []string{ManagerApt, ManagerApk, ManagerPip, ManagerNpm}
+
+// Check verifies that a package is installed.
+type Check struct {
+	Name         string // package name; name:arch for one architecture of a Debian package
+	Manager      string // apt, apk, pip or npm; empty detects apt or apk from os-release
+	Path         string // pip: site-packages directory, npm: node_modules directory (default: the usual places)
+	Root         string // directory the system's files are under (default: /)
+	MinVersion   string // minimum version required (inclusive), in the manager's own version syntax
+	MaxVersion   string // maximum version allowed (exclusive)
+	ExactVersion string // exact version required
+	VersionRange string // pip and npm only: semver constraint (e.g., ">=1.0, <2.0", "~>1.5", "^1.0.0")
+}
+
+// Run executes the package check.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. ctx is unused: the check
+// reads a database file or two, which os gives no way to cancel.
+func (c *Check) RunContext(context.Context) check.Result {
+	result := check.Result{
+		Name: "pkg: " + c.Name,
+	}
+
+	manager := c.Manager
+	if manager == "" {
+		var err error
+		if manager, err = detectManager(c.root()); err != nil {
+			return result.Failf("%v", err)
+		}
+		result.AddDetailf("manager: %s", manager)
+	}
+
+	pkg, err := c.lookup(manager)
+	if err != nil {
+		return result.Failf("%v", err)
+	}
+	result.AddDetailf("path: %s", pkg.source)
+
+	if c.MinVersion == "" && c.MaxVersion == "" && c.ExactVersion == "" && c.VersionRange == "" {
+		result.AddDetailf("version: %s", pkg.version)
+		result.Status = check.StatusOK
+		return result
+	}
+
+	// A distribution's version is its own: compared as dpkg or apk compares
+	// it, so that a security revision or a release can be required.
+	if manager == ManagerApt || manager == ManagerApk {
+		result.AddDetailf("version: %s", pkg.version)
+		if err := c.checkSystemVersion(manager, pkg.version); err != nil {
+			var cerr *version.ConstraintError
+			if errors.As(err, &cerr) {
+				return result.Fail(cerr.Detail, cerr.Err)
+			}
+			return result.Failf("%v", err)
+		}
+		result.Status = check.StatusOK
+		return result
+	}
+
+	constraints := version.Constraints{Range: c.VersionRange}
+	for _, f := range []struct {
+		value string
+		into  **version.Version
+	}{{c.MinVersion, &constraints.Min}, {c.MaxVersion, &constraints.Max}, {c.ExactVersion, &constraints.Exact}} {
+		v, err := version.ParseOptional(f.value)
+		if err != nil {
+			return result.Failf("invalid version constraint %q: %v", f.value, err)
+		}
+		*f.into = v
+	}
+
+	v, err := upstreamVersion(pkg.version)
+	if err != nil {
+		return result.Failf("could not parse version %q: %v", pkg.version, err)
+	}
+	if v.String() == pkg.version {
+		result.AddDetailf("version: %s", v)
+	} else {
+		result.AddDetailf("version: %s (compared as %s)", pkg.version, v)
+	}
+	if cerr := constraints.Check(v); cerr != nil {
+		return result.Fail(cerr.Detail, cerr.Err)
+	}
+
+	result.Status = check.StatusOK
+	return result
+}
+
+func (c *Check) root() string {
+	return cmp.Or(c.Root, "/")
+}
+
+// lookup finds the package in manager's database.
+func (c *Check) lookup(manager string) (installed, error) {
+	switch manager {
+	case ManagerApt:
+		return lookupDpkg(c.root(), c.Name)
+	case ManagerApk:
+		return lookupApk(c.root(), c.Name)
+	case ManagerPip:
+		return lookupPip(c.searchPath(pipDirs), c.Name)
+	case ManagerNpm:
+		return lookupNpm(c.searchPath(npmDirs), c.Name)
+	}
+	return installed{}, fmt.Errorf("unknown package manager %q", manager)
+}
+
+// searchPath returns the directories to look for a language package in: Path
+// when it is set, otherwise what defaults finds under the root.
+func (c *Check) searchPath(defaults func(root string) []string) []string {
+	if c.Path != "" {
+		return []string{c.Path}
+	}
+	return defaults(c.root())
+}
+
+// leadingVersion matches the version numbers a version string starts with.
+var leadingVersion = regexp.MustCompile(`^v?\d+(?:\.\d+){0,2}`)
+
+// upstreamVersion returns the version a pip or npm package's version string
+// starts with, for comparing with the version package, which knows nothing
+// of a pre-release such as "rc1" or "-beta.3".
+func upstreamVersion(v string) (version.Version, error) {
+	match := leadingVersion.FindString(v)
+	if match == "" {
+		return version.Version{}, fmt.Errorf("%q does not start with a version number", v)
+	}
+	return version.Parse(match)
+}
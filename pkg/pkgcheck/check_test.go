@@ -0,0 +1,445 @@
+package pkgcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+const dpkgStatusFixture = `Package: git
+Status: install ok installed
+Priority: optional
+Architecture: amd64
+Version: 1:2.39.5-0+deb12u2
+Description: fast, scalable, distributed revision control system
+ Git is popular version control system designed to handle very large
+ projects with speed and efficiency.
+
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.36-9+deb12u10
+
+Package: libc6
+Status: install ok installed
+Architecture: i386
+Version: 2.36-9+deb12u10
+
+Package: nginx
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.22.1-9
+
+Package: ca-certificates
+Status: install ok installed
+Architecture: all
+Version: 20230311
+`
+
+const apkInstalledFixture = `C:Q1abc=
+P:musl
+V:1.2.5-r9
+A:x86_64
+p:so:libc.musl-x86_64.so.1=1
+
+C:Q1def=
+P:busybox
+V:1.37.0-r12
+A:x86_64
+p:cmd:sh=1.37.0-r12
+`
+
+// rootFS writes files under a temporary directory standing in for /.
+func rootFS(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	return root
+}
+
+func TestCheck_Run(t *testing.T) {
+	root := rootFS(t, map[string]string{
+		dpkgStatus:   dpkgStatusFixture,
+		apkInstalled: apkInstalledFixture,
+		"usr/local/lib/python3.12/site-packages/requests-2.32.3.dist-info/METADATA": "Metadata-Version: 2.1\nName: requests\nVersion: 2.32.3\n\nVersion: 9.9.9 in the description\n",
+		"usr/local/lib/python3.12/site-packages/PyYAML-6.0.2.dist-info/METADATA":    "Metadata-Version: 2.1\nName: PyYAML\nVersion: 6.0.2\n",
+		"usr/local/lib/node_modules/npm/package.json":                               `{"name": "npm", "version": "10.9.2"}`,
+		"usr/local/lib/node_modules/@types/node/package.json":                       `{"name": "@types/node", "version": "22.10.1"}`,
+	})
+
+	tests := []struct {
+		name        string
+		check       Check
+		wantStatus  check.Status
+		wantDetails []string
+		wantErr     string
+	}{
+		{
+			name:        "apt installed",
+			check:       Check{Name: "git", Manager: ManagerApt},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"path: " + filepath.Join(root, dpkgStatus), "version: 1:2.39.5-0+deb12u2"},
+		},
+		{
+			name:        "apt compares epoch and revision",
+			check:       Check{Name: "git", Manager: ManagerApt, MinVersion: "1:2.39", MaxVersion: "1:3"},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"path: " + filepath.Join(root, dpkgStatus), "version: 1:2.39.5-0+deb12u2"},
+		},
+		{
+			name:       "apt below minimum",
+			check:      Check{Name: "git", Manager: ManagerApt, MinVersion: "1:2.40"},
+			wantStatus: check.StatusFail,
+			wantErr:    "version 1:2.39.5-0+deb12u2 below minimum 1:2.40",
+		},
+		{
+			name:       "apt epoch outranks the version",
+			check:      Check{Name: "git", Manager: ManagerApt, MinVersion: "3.0"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "apt exact build",
+			check:      Check{Name: "git", Manager: ManagerApt, ExactVersion: "1:2.39.5-0+deb12u2"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "apt exact without the epoch",
+			check:      Check{Name: "git", Manager: ManagerApt, ExactVersion: "2.39.5-0+deb12u2"},
+			wantStatus: check.StatusFail,
+			wantErr:    "version 1:2.39.5-0+deb12u2 does not match required 2.39.5-0+deb12u2",
+		},
+		{
+			name:       "apt security revision",
+			check:      Check{Name: "libc6", Manager: ManagerApt, MinVersion: "2.36-9+deb12u7"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "apt missing security revision",
+			check:      Check{Name: "libc6", Manager: ManagerApt, MinVersion: "2.36-9+deb12u11"},
+			wantStatus: check.StatusFail,
+			wantErr:    "below minimum 2.36-9+deb12u11",
+		},
+		{
+			name:       "apt range is not semver",
+			check:      Check{Name: "libc6", Manager: ManagerApt, VersionRange: "~2.36"},
+			wantStatus: check.StatusFail,
+			wantErr:    "a version range is semver, which apt versions are not",
+		},
+		{
+			name:       "apt invalid constraint",
+			check:      Check{Name: "libc6", Manager: ManagerApt, MinVersion: "two"},
+			wantStatus: check.StatusFail,
+			wantErr:    `invalid version constraint "two"`,
+		},
+		{
+			name:       "apt architecture",
+			check:      Check{Name: "libc6:i386", Manager: ManagerApt},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "apt other architecture",
+			check:      Check{Name: "git:i386", Manager: ManagerApt},
+			wantStatus: check.StatusFail,
+			wantErr:    "not installed (not in " + filepath.Join(root, dpkgStatus) + ")",
+		},
+		{
+			name:       "apt removed, configuration kept",
+			check:      Check{Name: "nginx", Manager: ManagerApt},
+			wantStatus: check.StatusFail,
+			wantErr:    "not installed (dpkg state: config-files)",
+		},
+		{
+			name:       "apt date version",
+			check:      Check{Name: "ca-certificates", Manager: ManagerApt, MinVersion: "20230000"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:        "apk installed",
+			check:       Check{Name: "busybox", Manager: ManagerApk, ExactVersion: "1.37.0-r12"},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"path: " + filepath.Join(root, apkInstalled), "version: 1.37.0-r12"},
+		},
+		{
+			name:       "apk release is part of the version",
+			check:      Check{Name: "busybox", Manager: ManagerApk, ExactVersion: "1.37.0"},
+			wantStatus: check.StatusFail,
+			wantErr:    "version 1.37.0-r12 does not match required 1.37.0",
+		},
+		{
+			name:       "apk release below minimum",
+			check:      Check{Name: "musl", Manager: ManagerApk, MinVersion: "1.2.5-r10"},
+			wantStatus: check.StatusFail,
+			wantErr:    "version 1.2.5-r9 below minimum 1.2.5-r10",
+		},
+		{
+			name:       "apk release below maximum",
+			check:      Check{Name: "musl", Manager: ManagerApk, MaxVersion: "1.2.5-r10"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "apk provides is not the package",
+			check:      Check{Name: "sh", Manager: ManagerApk},
+			wantStatus: check.StatusFail,
+			wantErr:    "not installed",
+		},
+		{
+			name:        "pip installed",
+			check:       Check{Name: "requests", Manager: ManagerPip, VersionRange: ">=2.0"},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"path: " + filepath.Join(root, "usr/local/lib/python3.12/site-packages/requests-2.32.3.dist-info"), "version: 2.32.3"},
+		},
+		{
+			name:       "pip name is normalized",
+			check:      Check{Name: "pyyaml", Manager: ManagerPip, MinVersion: "6"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "pip not installed",
+			check:      Check{Name: "flask", Manager: ManagerPip},
+			wantStatus: check.StatusFail,
+			wantErr:    "not installed (not in " + filepath.Join(root, "usr/local/lib/python3.12/site-packages") + ")",
+		},
+		{
+			name:       "npm global",
+			check:      Check{Name: "npm", Manager: ManagerNpm, VersionRange: "^10"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "npm scoped",
+			check:      Check{Name: "@types/node", Manager: ManagerNpm},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "npm range fails",
+			check:      Check{Name: "npm", Manager: ManagerNpm, VersionRange: "^9"},
+			wantStatus: check.StatusFail,
+			wantErr:    `version 10.9.2 does not satisfy constraint "^9"`,
+		},
+		{
+			name:       "npm not installed",
+			check:      Check{Name: "left-pad", Manager: ManagerNpm},
+			wantStatus: check.StatusFail,
+			wantErr:    "not installed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.check
+			c.Root = root
+			result := c.Run()
+
+			assert.Equal(t, tt.wantStatus, result.Status, "details: %v", result.Details)
+			assert.Equal(t, "pkg: "+tt.check.Name, result.Name)
+			if tt.wantDetails != nil {
+				assert.Equal(t, tt.wantDetails, result.Details)
+			}
+			if tt.wantErr != "" {
+				require.Error(t, result.Err)
+				assert.Contains(t, result.Err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheck_Path(t *testing.T) {
+	venv := rootFS(t, map[string]string{
+		"lib/python3.11/site-packages/Django-5.1.4.dist-info/METADATA": "Name: Django\nVersion: 5.1.4\n",
+		"node_modules/express/package.json":                            `{"version": "4.21.2"}`,
+		"node_modules/broken/package.json":                             `{"version": `,
+	})
+
+	tests := []struct {
+		name       string
+		check      Check
+		wantStatus check.Status
+		wantErr    string
+	}{
+		{"pip virtualenv", Check{Name: "django", Manager: ManagerPip, Path: filepath.Join(venv, "lib/python3.11/site-packages")}, check.StatusOK, ""},
+		{"pip missing directory", Check{Name: "django", Manager: ManagerPip, Path: filepath.Join(venv, "absent")}, check.StatusFail, "failed to read"},
+		{"npm project", Check{Name: "express", Manager: ManagerNpm, Path: filepath.Join(venv, "node_modules")}, check.StatusOK, ""},
+		{"npm broken manifest", Check{Name: "broken", Manager: ManagerNpm, Path: filepath.Join(venv, "node_modules")}, check.StatusFail, "failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.check.Run()
+			assert.Equal(t, tt.wantStatus, result.Status, "details: %v", result.Details)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, result.Err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("no site-packages", func(t *testing.T) {
+		c := &Check{Name: "django", Manager: ManagerPip, Root: t.TempDir()}
+		result := c.Run()
+		assert.Equal(t, check.StatusFail, result.Status)
+		assert.EqualError(t, result.Err, "no Python site-packages directory found")
+	})
+}
+
+func TestCheck_AutoDetect(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		wantStatus  check.Status
+		wantManager string
+		wantErr     string
+	}{
+		{
+			name:        "debian",
+			files:       map[string]string{"etc/os-release": "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\n", dpkgStatus: dpkgStatusFixture},
+			wantStatus:  check.StatusOK,
+			wantManager: "manager: apt",
+		},
+		{
+			name:        "derived from ubuntu",
+			files:       map[string]string{"usr/lib/os-release": "ID=linuxmint\nID_LIKE=\"ubuntu debian\"\n", dpkgStatus: dpkgStatusFixture},
+			wantStatus:  check.StatusOK,
+			wantManager: "manager: apt",
+		},
+		{
+			name:        "alpine",
+			files:       map[string]string{"etc/os-release": "ID=alpine\nVERSION_ID=3.21.0\n", apkInstalled: "P:git\nV:2.47.1-r0\n"},
+			wantStatus:  check.StatusOK,
+			wantManager: "manager: apk",
+		},
+		{
+			name:       "unsupported",
+			files:      map[string]string{"etc/os-release": "PRETTY_NAME=\"Fedora Linux 41\"\nID=fedora\n"},
+			wantStatus: check.StatusFail,
+			wantErr:    "cannot detect the package manager of Fedora Linux 41: only apt and apk systems are recognized",
+		},
+		{
+			name:       "no os-release",
+			files:      map[string]string{},
+			wantStatus: check.StatusFail,
+			wantErr:    "cannot detect the package manager: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Check{Name: "git", Root: rootFS(t, tt.files)}
+			result := c.Run()
+			assert.Equal(t, tt.wantStatus, result.Status, "details: %v", result.Details)
+			if tt.wantManager != "" {
+				require.NotEmpty(t, result.Details)
+				assert.Equal(t, tt.wantManager, result.Details[0])
+			}
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, result.Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpstreamVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"2.0.0rc1", "2.0.0"},
+		{"2024.12.14", "2024.12.14"},
+		{"5.0.0-beta.3", "5.0.0"},
+		{"7.88", "7.88.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := upstreamVersion(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+
+	_, err := upstreamVersion("git20240101")
+	assert.ErrorContains(t, err, "does not start with a version number")
+}
+
+// The orders dpkg --compare-versions and apk version -t give.
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		manager string
+		a, b    string
+		want    int
+	}{
+		{ManagerApt, "1.0", "1.0", 0},
+		{ManagerApt, "0:1.0", "1.0", 0},
+		{ManagerApt, "1.0", "1.00", 0},
+		{ManagerApt, "1:1.0", "2.0", 1},
+		{ManagerApt, "1.0", "1.0-1", -1},
+		{ManagerApt, "1.0~rc1", "1.0", -1},
+		{ManagerApt, "1.0a", "1.0", 1},
+		{ManagerApt, "1.0+dfsg", "1.0", 1},
+		{ManagerApt, "1.2.3.4", "1.2.3", 1},
+		{ManagerApt, "1.9", "1.10", -1},
+		{ManagerApt, "2.36-9+deb12u10", "2.36-9+deb12u9", 1},
+		{ManagerApt, "3.0.15-1~deb12u1", "3.0.15-1", -1},
+		{ManagerApt, "2.39.5-0+deb12u2", "2.39.5-0+deb12u1", 1},
+
+		{ManagerApk, "1.0", "1.0", 0},
+		{ManagerApk, "1.2.5-r9", "1.2.5-r10", -1},
+		{ManagerApk, "1.0", "1.0-r0", -1},
+		{ManagerApk, "1.0_rc1", "1.0", -1},
+		{ManagerApk, "1.0_alpha", "1.0_beta", -1},
+		{ManagerApk, "1.0_p1", "1.0", 1},
+		{ManagerApk, "1.0_p1", "1.0-r5", 1},
+		{ManagerApk, "3.3.2_p1-r0", "3.3.2-r9", 1},
+		{ManagerApk, "1.0a", "1.0", 1},
+		{ManagerApk, "1.9", "1.10", -1},
+		{ManagerApk, "1.2.3.4", "1.2.3", 1},
+		{ManagerApk, "1.01", "1.1", -1},
+		{ManagerApk, "1.001", "1.01", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.manager+" "+tt.a+" "+tt.b, func(t *testing.T) {
+			compare := compareDebian
+			if tt.manager == ManagerApk {
+				compare = compareApk
+			}
+			assert.Equal(t, tt.want, compare(tt.a, tt.b))
+			assert.Equal(t, -tt.want, compare(tt.b, tt.a))
+		})
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	tests := []struct {
+		manager string
+		input   string
+		wantErr string
+	}{
+		{ManagerApt, "1:2.39.5-0+deb12u2", ""},
+		{ManagerApt, "20230311", ""},
+		{ManagerApt, "two", "does not start with a digit"},
+		{ManagerApt, "x:1.0", `epoch "x" is not a number`},
+		{ManagerApt, "1.0-", "revision number is empty"},
+		{ManagerApt, "1.0 beta", "embedded spaces"},
+		{ManagerApk, "3.3.2_p1-r0", ""},
+		{ManagerApk, "1.0-beta", "not an Alpine version"},
+		{ManagerApk, "1.0_foo", "not an Alpine version"},
+		{ManagerApk, "2.4.1_git20240101-r0", ""},
+		{"", "1.37.0-r12", ""},
+		{"", "two", "does not start with a digit"},
+		{ManagerPip, "2.28", ""},
+		{ManagerPip, "2.28-1", "invalid version format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.manager+" "+tt.input, func(t *testing.T) {
+			err := ValidateVersion(tt.manager, tt.input)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package pkgcheck
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Where the system package managers keep their databases, under the root.
+const (
+	dpkgStatus   = "var/lib/dpkg/status"
+	apkInstalled = "lib/apk/db/installed"
+)
+
+// installed is what a database records of an installed package.
+type installed struct {
+	version string
+	source  string // the file or directory it was found in
+}
+
+// lookupDpkg finds name in dpkg's status file, which apt installs through.
+func lookupDpkg(root, name string) (installed, error) {
+	path := filepath.Join(root, dpkgStatus)
+	data, err := os.ReadFile(path) //nolint:gosec // intentional: the database under the root the user named
+	if err != nil {
+		return installed{}, fmt.Errorf("failed to read dpkg database: %w", err)
+	}
+
+	pkgName, arch, _ := strings.Cut(name, ":")
+	state := ""
+	for _, record := range parseRecords(data) {
+		if record["Package"] != pkgName || arch != "" && record["Architecture"] != arch {
+			continue
+		}
+		// Status is "want flag state". A removed package whose configuration
+		// files were kept is still listed, as are half-done installs.
+		fields := strings.Fields(record["Status"])
+		if len(fields) != 3 {
+			continue
+		}
+		switch state = fields[2]; state {
+		case "installed", "triggers-pending", "triggers-awaited":
+			return installed{version: record["Version"], source: path}, nil
+		}
+	}
+	if state != "" {
+		return installed{}, fmt.Errorf("not installed (dpkg state: %s)", state)
+	}
+	return installed{}, fmt.Errorf("not installed (not in %s)", path)
+}
+
+// lookupApk finds name in apk's database of installed packages.
+func lookupApk(root, name string) (installed, error) {
+	path := filepath.Join(root, apkInstalled)
+	data, err := os.ReadFile(path) //nolint:gosec // intentional: the database under the root the user named
+	if err != nil {
+		return installed{}, fmt.Errorf("failed to read apk database: %w", err)
+	}
+	// P is the package's name and V its version; p lists what it provides.
+	for _, record := range parseRecords(data) {
+		if record["P"] == name {
+			return installed{version: record["V"], source: path}, nil
+		}
+	}
+	return installed{}, fmt.Errorf("not installed (not in %s)", path)
+}
+
+// parseRecords splits a dpkg or apk database into its records, which are
+// separated by blank lines. Each maps a field's name to the first value it
+// has in the record; a dpkg field's continuation lines are left out.
+func parseRecords(data []byte) []map[string]string {
+	var records []map[string]string
+	record := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20) // a long Description or Conffiles line
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		switch {
+		case line == "":
+			if len(record) > 0 {
+				records = append(records, record)
+				record = map[string]string{}
+			}
+		case line[0] == ' ' || line[0] == '\t':
+			// A continuation of the field before it.
+		default:
+			key, value, _ := strings.Cut(line, ":")
+			if _, ok := record[key]; !ok {
+				record[key] = strings.TrimSpace(value)
+			}
+		}
+	}
+	if len(record) > 0 {
+		records = append(records, record)
+	}
+	return records
+}
+
+// pipDirs returns the site-packages directories of the Pythons installed under
+// root, the way the official python images and the distributions lay them out.
+func pipDirs(root string) []string {
+	var dirs []string
+	for _, pattern := range []string{
+		"usr/local/lib/python3*/site-packages",
+		"usr/lib/python3*/site-packages",
+		"usr/local/lib/python3*/dist-packages",
+		"usr/lib/python3/dist-packages",
+	} {
+		// Glob only fails on a bad pattern.
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		dirs = append(dirs, matches...)
+	}
+	return dirs
+}
+
+// lookupPip finds name among the distributions installed in dirs, by the
+// .dist-info directory pip leaves for each.
+func lookupPip(dirs []string, name string) (installed, error) {
+	if len(dirs) == 0 {
+		return installed{}, errors.New("no Python site-packages directory found")
+	}
+	want := normalizePip(name)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return installed{}, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		for _, e := range entries {
+			// The directory is named <name>-<version>.dist-info.
+			base, ok := strings.CutSuffix(e.Name(), ".dist-info")
+			if !ok || !e.IsDir() {
+				continue
+			}
+			if dist, _, _ := strings.Cut(base, "-"); normalizePip(dist) != want {
+				continue
+			}
+			metadata := filepath.Join(dir, e.Name(), "METADATA")
+			data, err := os.ReadFile(metadata) //nolint:gosec // intentional: the site-packages the user named
+			if err != nil {
+				return installed{}, fmt.Errorf("failed to read %s: %w", metadata, err)
+			}
+			v := metadataVersion(data)
+			if v == "" {
+				return installed{}, fmt.Errorf("%s has no Version", metadata)
+			}
+			return installed{version: v, source: filepath.Join(dir, e.Name())}, nil
+		}
+	}
+	return installed{}, fmt.Errorf("not installed (not in %s)", strings.Join(dirs, ", "))
+}
+
+// pipSeparators are the characters a Python package name may spell any of.
+var pipSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalizePip returns name as PEP 503 compares it: "PyYAML", "pyyaml" and
+// "zope.interface" against "zope_interface" are the same package.
+func normalizePip(name string) string {
+	return strings.ToLower(pipSeparators.ReplaceAllString(name, "-"))
+}
+
+// metadataVersion returns the Version header of a METADATA file. The headers
+// end at the first blank line, where the description begins.
+func metadataVersion(data []byte) string {
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if v, ok := strings.CutPrefix(line, "Version:"); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// npmDirs returns the node_modules directories to look in: the working
+// directory's, then the global ones under root.
+func npmDirs(root string) []string {
+	return []string{
+		"node_modules",
+		filepath.Join(root, "usr/local/lib/node_modules"),
+		filepath.Join(root, "usr/lib/node_modules"),
+	}
+}
+
+// lookupNpm finds name, which may be scoped like @types/node, in the first of
+// dirs that has it.
+func lookupNpm(dirs []string, name string) (installed, error) {
+	for _, dir := range dirs {
+		path := filepath.Join(dir, filepath.FromSlash(name), "package.json")
+		data, err := os.ReadFile(path) //nolint:gosec // intentional: the node_modules the user named
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return installed{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var manifest struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return installed{}, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if manifest.Version == "" {
+			return installed{}, fmt.Errorf("%s has no version", path)
+		}
+		return installed{version: manifest.Version, source: filepath.Dir(path)}, nil
+	}
+	return installed{}, fmt.Errorf("not installed (not in %s)", strings.Join(dirs, ", "))
+}
+
+// detectManager returns the package manager of the system under root, by the
+// distribution its os-release names or the one that is derived from.
+func detectManager(root string) (string, error) {
+	var data []byte
+	var err error
+	for _, name := range []string{"etc/os-release", "usr/lib/os-release"} {
+		if data, err = os.ReadFile(filepath.Join(root, name)); err == nil { //nolint:gosec // fixed paths under the root
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot detect the package manager: %w", err)
+	}
+
+	release := parseOSRelease(data)
+	for _, id := range append([]string{release["ID"]}, strings.Fields(release["ID_LIKE"])...) {
+		switch id {
+		case "debian", "ubuntu":
+			return ManagerApt, nil
+		case "alpine":
+			return ManagerApk, nil
+		}
+	}
+	return "", fmt.Errorf("cannot detect the package manager of %s: only apt and apk systems are recognized",
+		cmp.Or(release["PRETTY_NAME"], release["ID"], "this system"))
+}
+
+// parseOSRelease returns the variables an os-release file assigns.
+func parseOSRelease(data []byte) map[string]string {
+	vars := map[string]string{}
+	for line := range strings.Lines(string(data)) {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		vars[key] = strings.Trim(value, `"'`)
+	}
+	return vars
+}
@@ -0,0 +1,375 @@
+package pkgcheck
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vertti/preflight/pkg/version"
+)
+
+// ValidateVersion reports whether v is a version manager could have given a
+// package, so a bad constraint is caught before the check runs. An empty
+// manager, for a check that detects apt or apk, accepts what either could
+// have.
+func ValidateVersion(manager, v string) error {
+	switch manager {
+	case ManagerApt:
+		_, err := parseDebian(v)
+		return err
+	case ManagerApk:
+		return validateApk(v)
+	case "":
+		if _, err := parseDebian(v); err != nil && validateApk(v) != nil {
+			return err
+		}
+		return nil
+	}
+	_, err := version.Parse(v)
+	return err
+}
+
+// checkSystemVersion checks an apt or apk version against the check's
+// constraints the way the package manager orders its versions, so an epoch,
+// a revision, an -rN release or a _p suffix counts as it would for dpkg or
+// apk. It reports a failure in the words version.Constraints uses.
+func (c *Check) checkSystemVersion(manager, installed string) error {
+	compare := compareDebian
+	if manager == ManagerApk {
+		compare = compareApk
+	}
+	if c.VersionRange != "" {
+		return fmt.Errorf("a version range is semver, which %s versions are not: use a minimum and maximum", manager)
+	}
+	for _, v := range []string{c.ExactVersion, c.MinVersion, c.MaxVersion} {
+		if v == "" {
+			continue
+		}
+		if err := ValidateVersion(manager, v); err != nil {
+			return fmt.Errorf("invalid version constraint %q: %w", v, err)
+		}
+	}
+	if err := ValidateVersion(manager, installed); err != nil {
+		return fmt.Errorf("could not parse version %q: %w", installed, err)
+	}
+
+	switch {
+	case c.ExactVersion != "" && compare(installed, c.ExactVersion) != 0:
+		return &version.ConstraintError{
+			Detail: fmt.Sprintf("version %s != required %s", installed, c.ExactVersion),
+			Err:    fmt.Errorf("version %s does not match required %s", installed, c.ExactVersion),
+		}
+	case c.MinVersion != "" && compare(installed, c.MinVersion) < 0:
+		return &version.ConstraintError{
+			Detail: fmt.Sprintf("version %s < minimum %s", installed, c.MinVersion),
+			Err:    fmt.Errorf("version %s below minimum %s", installed, c.MinVersion),
+		}
+	case c.MaxVersion != "" && compare(installed, c.MaxVersion) >= 0:
+		return &version.ConstraintError{
+			Detail: fmt.Sprintf("version %s >= maximum %s", installed, c.MaxVersion),
+			Err:    fmt.Errorf("version %s at or above maximum %s", installed, c.MaxVersion),
+		}
+	}
+	return nil
+}
+
+// debianVersion is a Debian version split into its parts,
+// [epoch:]upstream[-revision].
+type debianVersion struct {
+	epoch    int
+	upstream string
+	revision string
+}
+
+// parseDebian splits v as dpkg does, and rejects what dpkg would: an epoch
+// that is not a number, an upstream version not starting with a digit, and
+// characters a version cannot contain.
+func parseDebian(v string) (debianVersion, error) {
+	var d debianVersion
+	if v == "" {
+		return d, errors.New("version string is empty")
+	}
+	if strings.ContainsAny(v, " \t") {
+		return d, errors.New("version string has embedded spaces")
+	}
+	rest := v
+	if epoch, after, ok := strings.Cut(v, ":"); ok {
+		n, err := strconv.Atoi(epoch)
+		if err != nil || n < 0 {
+			return d, fmt.Errorf("epoch %q is not a number", epoch)
+		}
+		d.epoch, rest = n, after
+	}
+	d.upstream = rest
+	if i := strings.LastIndex(rest, "-"); i >= 0 {
+		d.upstream, d.revision = rest[:i], rest[i+1:]
+		if d.revision == "" {
+			return d, errors.New("revision number is empty")
+		}
+	}
+	if d.upstream == "" || !isDigit(d.upstream[0]) {
+		return d, errors.New("version number does not start with a digit")
+	}
+	for _, r := range d.upstream {
+		if !isAlnum(r) && !strings.ContainsRune(".-+~:", r) {
+			return d, fmt.Errorf("invalid character %q in version number", r)
+		}
+	}
+	for _, r := range d.revision {
+		if !isAlnum(r) && !strings.ContainsRune(".+~", r) {
+			return d, fmt.Errorf("invalid character %q in revision number", r)
+		}
+	}
+	return d, nil
+}
+
+// compareDebian orders two Debian versions as dpkg --compare-versions does:
+// by epoch, then upstream version, then revision. It returns -1, 0 or 1.
+// Both must be valid; parseDebian says whether they are.
+func compareDebian(a, b string) int {
+	da, _ := parseDebian(a)
+	db, _ := parseDebian(b)
+	if da.epoch != db.epoch {
+		return sign(da.epoch - db.epoch)
+	}
+	if n := compareDebianPart(da.upstream, db.upstream); n != 0 {
+		return n
+	}
+	return compareDebianPart(da.revision, db.revision)
+}
+
+// compareDebianPart is dpkg's verrevcmp: alternating runs of non-digits,
+// compared character by character with ~ before everything and letters
+// before other symbols, and of digits, compared as numbers.
+func compareDebianPart(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ac, bc := debianOrder(a), debianOrder(b)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			a, b = a[min(1, len(a)):], b[min(1, len(b)):]
+		}
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		firstDiff := 0
+		for a != "" && b != "" && isDigit(a[0]) && isDigit(b[0]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+		if a != "" && isDigit(a[0]) {
+			return 1
+		}
+		if b != "" && isDigit(b[0]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return sign(firstDiff)
+		}
+	}
+	return 0
+}
+
+// debianOrder is the weight of the first character of s in a run of
+// non-digits. The end of s weighs nothing, so "1.0~rc1" sorts before "1.0"
+// and "1.0a" after it.
+func debianOrder(s string) int {
+	switch {
+	case s == "" || isDigit(s[0]):
+		return 0
+	case isAlpha(s[0]):
+		return int(s[0])
+	case s[0] == '~':
+		return -1
+	}
+	return int(s[0]) + 256
+}
+
+// The tokens an Alpine version is made of, in the order they may appear:
+// 1.2.3a_rc1_p2-r4 is digits, a letter, two suffixes with their numbers, and a
+// release.
+const (
+	apkInvalid = iota - 1
+	apkDigitOrZero
+	apkDigit
+	apkLetter
+	apkSuffix
+	apkSuffixNumber
+	apkRelease
+	apkEnd
+)
+
+// Alpine's suffixes: those before a release sort before the version without
+// one, the rest after it.
+var (
+	apkPreSuffixes  = []string{"alpha", "beta", "pre", "rc"}
+	apkPostSuffixes = []string{"cvs", "svn", "git", "hg", "p"}
+)
+
+// validateApk rejects what apk would not accept as a version.
+func validateApk(v string) error {
+	if v == "" {
+		return errors.New("version string is empty")
+	}
+	t := apkDigit
+	for t != apkEnd && t != apkInvalid {
+		apkToken(&t, &v)
+	}
+	if t == apkInvalid {
+		return errors.New("not an Alpine version")
+	}
+	return nil
+}
+
+// compareApk orders two Alpine versions as apk version -t does, returning -1,
+// 0 or 1: _rc1 comes before the release it leads to, _p1 after it, and -r1
+// after -r0.
+func compareApk(a, b string) int {
+	at, bt := apkDigit, apkDigit
+	av, bv := 0, 0
+	for at == bt && at != apkEnd && at != apkInvalid && av == bv {
+		av = apkToken(&at, &a)
+		bv = apkToken(&bt, &b)
+	}
+	if av != bv {
+		return sign(av - bv)
+	}
+	if at == bt {
+		return 0
+	}
+	// Equal so far, and one goes on: the longer is later, unless what follows
+	// is a pre-release suffix.
+	if tt := at; at == apkSuffix && apkToken(&tt, &a) < 0 {
+		return -1
+	}
+	if tt := bt; bt == apkSuffix && apkToken(&tt, &b) < 0 {
+		return 1
+	}
+	if at > bt {
+		return -1
+	}
+	if bt > at {
+		return 1
+	}
+	return 0
+}
+
+// apkToken reads the token of type t from the start of s and returns its
+// value, leaving s after it and t the type of the token that follows.
+func apkToken(t *int, s *string) int {
+	if *s == "" {
+		*t = apkEnd
+		return 0
+	}
+	v, i, next := 0, 0, apkInvalid
+	switch *t {
+	case apkDigitOrZero:
+		// A component after a dot that starts with zeros sorts before any
+		// without, and more zeros sort earlier: 1.001 before 1.01 before 1.1.
+		// The last zero is left to be read as a digit.
+		if (*s)[0] == '0' {
+			for i+1 < len(*s) && (*s)[i+1] == '0' {
+				i++
+			}
+			v, next = -i, apkDigit
+			break
+		}
+		fallthrough
+	case apkDigit, apkSuffixNumber, apkRelease:
+		for i < len(*s) && isDigit((*s)[i]) {
+			v = v*10 + int((*s)[i]-'0')
+			i++
+		}
+		if i >= 18 {
+			*t = apkInvalid
+			return -1
+		}
+	case apkLetter:
+		v, i = int((*s)[0]), 1
+	case apkSuffix:
+		found := false
+		for n, suffix := range apkPreSuffixes {
+			if strings.HasPrefix(*s, suffix) {
+				v, i, found = n-len(apkPreSuffixes), len(suffix), true
+				break
+			}
+		}
+		if !found {
+			for n, suffix := range apkPostSuffixes {
+				if strings.HasPrefix(*s, suffix) {
+					v, i, found = n, len(suffix), true
+					break
+				}
+			}
+		}
+		if !found {
+			*t = apkInvalid
+			return -1
+		}
+	default:
+		*t = apkInvalid
+		return -1
+	}
+	*s = (*s)[i:]
+	switch {
+	case *s == "":
+		*t = apkEnd
+	case next != apkInvalid:
+		*t = next
+	default:
+		apkNextToken(t, s)
+	}
+	return v
+}
+
+// apkNextToken works out the type of the token at the start of s from the
+// character there and the type of the token before it, skipping a separator.
+// A token out of order makes the version invalid.
+func apkNextToken(t *int, s *string) {
+	n := apkInvalid
+	c := (*s)[0]
+	switch {
+	case (*t == apkDigit || *t == apkDigitOrZero) && 'a' <= c && c <= 'z':
+		n = apkLetter
+	case *t == apkLetter && isDigit(c):
+		n = apkDigit
+	case *t == apkSuffix && isDigit(c):
+		n = apkSuffixNumber
+	default:
+		switch c {
+		case '.':
+			n = apkDigitOrZero
+		case '_':
+			n = apkSuffix
+		case '-':
+			if strings.HasPrefix(*s, "-r") {
+				n = apkRelease
+				*s = (*s)[1:]
+			}
+		}
+		*s = (*s)[1:]
+	}
+	if n < *t && (n != apkDigitOrZero || *t != apkDigit) &&
+		(n != apkSuffix || *t != apkSuffixNumber) && (n != apkDigit || *t != apkLetter) {
+		n = apkInvalid
+	}
+	*t = n
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isAlpha(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+func isAlnum(r rune) bool { return r < 0x80 && (isDigit(byte(r)) || isAlpha(byte(r))) }
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,157 @@
+// Package plugincheck runs a check that lives outside preflight: an executable
+// named preflight-<name>, found on PATH the way git and kubectl find theirs.
+//
+// A plugin reports on stdout with a single JSON object, which becomes the
+// check's result:
+//
+//	{"name": "license: acme", "status": "OK", "details": ["seats: 40 of 50"]}
+//
+// status is OK, WARN or FAIL. name defaults to the plugin's, details may be
+// left out, and error, if given, is the reason for a failure. A plugin that
+// exits non-zero fails whatever status it wrote. Whatever else a plugin writes
+// goes to stderr, which is shown when it fails that way or writes no result.
+package plugincheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// Prefix starts the name of every plugin's executable.
+const Prefix = "preflight-"
+
+// DefaultTimeout bounds a plugin that is given no timeout of its own.
+const DefaultTimeout = 30 * time.Second
+
+// Runner runs a plugin. cmdcheck.RealCmdRunner is one.
+type Runner interface {
+	RunCommandContext(ctx context.Context, name string, args ...string) (stdout, stderr string, err error)
+}
+
+// Output is the JSON object a plugin writes to stdout.
+type Output struct {
+	Name    string       `json:"name"`
+	Status  check.Status `json:"status"`
+	Details []string     `json:"details"`
+	Error   string       `json:"error"`
+}
+
+// ValidName reports whether name can name a plugin. A name is one path
+// element: a .preflight line can run any plugin, and a separator in the name
+// would let it reach an executable outside PATH.
+func ValidName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, "-") && !strings.HasPrefix(name, ".")
+}
+
+// Find returns the path of the plugin called name, searching with lookPath,
+// such as exec.LookPath.
+func Find(name string, lookPath func(file string) (string, error)) (string, error) {
+	if !ValidName(name) {
+		return "", fmt.Errorf("invalid plugin name %q", name)
+	}
+	return lookPath(Prefix + name)
+}
+
+// Check runs a plugin and reports the result it writes.
+type Check struct {
+	Name    string        // plugin name, without Prefix
+	Path    string        // the plugin's executable
+	Args    []string      // passed to the plugin as they are
+	Timeout time.Duration // how long the plugin may run (default: 30s)
+	Runner  Runner        // injected for testing
+}
+
+// Run executes the plugin check.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run with the plugin killed once ctx is done.
+func (c *Check) RunContext(ctx context.Context) check.Result {
+	result := check.Result{Name: c.Name}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout, stderr, err := c.Runner.RunCommandContext(runCtx, c.Path, c.Args...)
+	out, decodeErr := decode(stdout)
+	if decodeErr != nil {
+		switch {
+		case ctx.Err() == nil && runCtx.Err() == context.DeadlineExceeded:
+			result.Failf("plugin %s timed out after %s", Prefix+c.Name, timeout)
+		case err != nil:
+			result.Failf("plugin %s failed: %v", Prefix+c.Name, err)
+		default:
+			result.Failf("plugin %s wrote no result: %v", Prefix+c.Name, decodeErr)
+		}
+		addStderr(&result, stderr)
+		return result
+	}
+
+	if out.Name != "" {
+		result.Name = out.Name
+	}
+	result.Details = out.Details
+	switch out.Status {
+	case check.StatusOK, check.StatusWarn:
+		result.Status = out.Status
+	case check.StatusFail:
+		reason := out.Error
+		if reason == "" && len(out.Details) > 0 {
+			reason = out.Details[len(out.Details)-1]
+		}
+		if reason == "" {
+			reason = "failed"
+		}
+		result.Status = check.StatusFail
+		result.Err = errors.New(reason)
+	default:
+		return result.Failf("plugin %s reported unknown status %q (want OK, WARN or FAIL)", Prefix+c.Name, out.Status)
+	}
+
+	// A plugin that crashed after writing OK, or that exits with the answer
+	// as a shell script would, has not passed.
+	if err != nil {
+		if result.Status != check.StatusFail {
+			result.Failf("plugin %s reported %s but failed: %v", Prefix+c.Name, out.Status, err)
+		}
+		addStderr(&result, stderr)
+	}
+	return result
+}
+
+// addStderr adds what a failed plugin wrote to stderr, if anything.
+func addStderr(result *check.Result, stderr string) {
+	if s := strings.TrimSpace(stderr); s != "" {
+		result.AddDetailf("stderr: %s", s)
+	}
+}
+
+// decode reads the one JSON object a plugin writes. Anything after it is an
+// error: a plugin printing two results, or a result and then a stack trace,
+// has not said which of them to believe.
+func decode(stdout string) (Output, error) {
+	var out Output
+	dec := json.NewDecoder(strings.NewReader(stdout))
+	if err := dec.Decode(&out); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Output{}, errors.New("stdout was empty")
+		}
+		return Output{}, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return Output{}, errors.New("stdout has more than one JSON value")
+	}
+	return out, nil
+}
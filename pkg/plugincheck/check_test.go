@@ -0,0 +1,179 @@
+package plugincheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+type mockRunner struct {
+	stdout, stderr string
+	err            error
+	run            func(ctx context.Context) // if set, called before returning
+	gotName        string
+	gotArgs        []string
+}
+
+func (m *mockRunner) RunCommandContext(ctx context.Context, name string, args ...string) (string, string, error) {
+	m.gotName, m.gotArgs = name, args
+	if m.run != nil {
+		m.run(ctx)
+	}
+	return m.stdout, m.stderr, m.err
+}
+
+func TestCheck_Run(t *testing.T) {
+	tests := []struct {
+		name        string
+		runner      mockRunner
+		wantName    string
+		wantStatus  check.Status
+		wantDetails []string
+		wantErr     string
+	}{
+		{
+			name:        "ok",
+			runner:      mockRunner{stdout: `{"name":"license: acme","status":"OK","details":["seats: 40 of 50"]}`},
+			wantName:    "license: acme",
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"seats: 40 of 50"},
+		},
+		{
+			name:       "name defaults to the plugin's",
+			runner:     mockRunner{stdout: "{\"status\":\"WARN\"}\n"},
+			wantName:   "license",
+			wantStatus: check.StatusWarn,
+		},
+		{
+			name:        "failure with an error",
+			runner:      mockRunner{stdout: `{"status":"FAIL","details":["seats: 50 of 50"],"error":"no seats left"}`},
+			wantName:    "license",
+			wantStatus:  check.StatusFail,
+			wantDetails: []string{"seats: 50 of 50"},
+			wantErr:     "no seats left",
+		},
+		{
+			name:        "failure reason falls back to the last detail",
+			runner:      mockRunner{stdout: `{"status":"FAIL","details":["server unreachable"]}`},
+			wantName:    "license",
+			wantStatus:  check.StatusFail,
+			wantDetails: []string{"server unreachable"},
+			wantErr:     "server unreachable",
+		},
+		{
+			name:       "the result wins over the exit status",
+			runner:     mockRunner{stdout: `{"status":"FAIL"}`, err: errors.New("exit status 1")},
+			wantName:   "license",
+			wantStatus: check.StatusFail,
+			wantErr:    "failed",
+		},
+		{
+			name:        "a non-zero exit fails a passing result",
+			runner:      mockRunner{stdout: `{"status":"OK","details":["seats: 40 of 50"]}`, stderr: "lost connection\n", err: errors.New("exit status 3")},
+			wantName:    "license",
+			wantStatus:  check.StatusFail,
+			wantDetails: []string{"seats: 40 of 50", "plugin preflight-license reported OK but failed: exit status 3", "stderr: lost connection"},
+			wantErr:     "exit status 3",
+		},
+		{
+			name:       "a non-zero exit fails a warning",
+			runner:     mockRunner{stdout: `{"status":"WARN"}`, err: errors.New("exit status 1")},
+			wantName:   "license",
+			wantStatus: check.StatusFail,
+			wantErr:    "reported WARN but failed",
+		},
+		{
+			name:        "a crash shows stderr",
+			runner:      mockRunner{stderr: "panic: boom\n", err: errors.New("exit status 2")},
+			wantName:    "license",
+			wantStatus:  check.StatusFail,
+			wantDetails: []string{"plugin preflight-license failed: exit status 2", "stderr: panic: boom"},
+			wantErr:     "plugin preflight-license failed: exit status 2",
+		},
+		{
+			name:        "no output",
+			runner:      mockRunner{},
+			wantName:    "license",
+			wantStatus:  check.StatusFail,
+			wantDetails: []string{"plugin preflight-license wrote no result: stdout was empty"},
+			wantErr:     "stdout was empty",
+		},
+		{
+			name:       "not JSON",
+			runner:     mockRunner{stdout: "usage: preflight-license [--seats N]\n"},
+			wantName:   "license",
+			wantStatus: check.StatusFail,
+			wantErr:    "wrote no result",
+		},
+		{
+			name:       "two results",
+			runner:     mockRunner{stdout: `{"status":"OK"} {"status":"FAIL"}`},
+			wantName:   "license",
+			wantStatus: check.StatusFail,
+			wantErr:    "more than one JSON value",
+		},
+		{
+			name:       "unknown status",
+			runner:     mockRunner{stdout: `{"status":"PASSED"}`},
+			wantName:   "license",
+			wantStatus: check.StatusFail,
+			wantErr:    `unknown status "PASSED"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := tt.runner
+			c := &Check{Name: "license", Path: "/usr/local/bin/preflight-license", Args: []string{"--seats", "10"}, Runner: &runner}
+			result := c.Run()
+
+			assert.Equal(t, tt.wantName, result.Name)
+			assert.Equal(t, tt.wantStatus, result.Status)
+			if tt.wantDetails != nil {
+				assert.Equal(t, tt.wantDetails, result.Details)
+			}
+			if tt.wantErr != "" {
+				require.Error(t, result.Err)
+				assert.Contains(t, result.Err.Error(), tt.wantErr)
+			}
+			assert.Equal(t, "/usr/local/bin/preflight-license", runner.gotName)
+			assert.Equal(t, []string{"--seats", "10"}, runner.gotArgs)
+		})
+	}
+}
+
+func TestCheck_Timeout(t *testing.T) {
+	runner := &mockRunner{
+		run: func(ctx context.Context) { <-ctx.Done() },
+		err: errors.New("signal: killed"),
+	}
+	c := &Check{Name: "queue", Path: "preflight-queue", Timeout: 10 * time.Millisecond, Runner: runner}
+
+	result := c.Run()
+	assert.Equal(t, check.StatusFail, result.Status)
+	assert.Equal(t, []string{"plugin preflight-queue timed out after 10ms"}, result.Details)
+}
+
+func TestFind(t *testing.T) {
+	var looked []string
+	lookPath := func(file string) (string, error) {
+		looked = append(looked, file)
+		return "/usr/local/bin/" + file, nil
+	}
+
+	path, err := Find("license", lookPath)
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/local/bin/preflight-license", path)
+
+	for _, name := range []string{"", "../bin/evil", `sub\dir`, "-flag", ".hidden"} {
+		_, err := Find(name, lookPath)
+		assert.Error(t, err, "%q", name)
+	}
+	assert.Equal(t, []string{"preflight-license"}, looked, "an invalid name is never looked up")
+}
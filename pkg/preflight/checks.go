@@ -0,0 +1,334 @@
+package preflight
+
+import (
+	"github.com/vertti/preflight/pkg/certcheck"
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/cmdcheck"
+	"github.com/vertti/preflight/pkg/envcheck"
+	"github.com/vertti/preflight/pkg/filecheck"
+	"github.com/vertti/preflight/pkg/gitcheck"
+	"github.com/vertti/preflight/pkg/hashcheck"
+	"github.com/vertti/preflight/pkg/httpcheck"
+	"github.com/vertti/preflight/pkg/jsoncheck"
+	"github.com/vertti/preflight/pkg/pkgcheck"
+	"github.com/vertti/preflight/pkg/plugincheck"
+	"github.com/vertti/preflight/pkg/proccheck"
+	"github.com/vertti/preflight/pkg/promcheck"
+	"github.com/vertti/preflight/pkg/resourcecheck"
+	"github.com/vertti/preflight/pkg/retry"
+	"github.com/vertti/preflight/pkg/syscheck"
+	"github.com/vertti/preflight/pkg/tcpcheck"
+	"github.com/vertti/preflight/pkg/templatecheck"
+	"github.com/vertti/preflight/pkg/tlscheck"
+	"github.com/vertti/preflight/pkg/usercheck"
+	"github.com/vertti/preflight/pkg/yamlcheck"
+)
+
+// Cert checks that file holds a certificate, PEM, DER or PKCS#12. Set
+// NotExpired, MinDays, KeyFile, VerifyChain and the rest to check more.
+func Cert(file string) *certcheck.Check {
+	return wire(&certcheck.Check{File: file})
+}
+
+// Cmd checks that a command is on PATH and runs. Set the version fields on
+// the result to check its version too.
+func Cmd(name string) *cmdcheck.Check {
+	return wire(&cmdcheck.Check{Name: name})
+}
+
+// Env checks that an environment variable is set and not empty.
+func Env(name string) *envcheck.Check {
+	return wire(&envcheck.Check{Name: name})
+}
+
+// File checks that a file exists and is readable.
+//
+// Owner is set to -1, which checks no owner. The zero value would require the
+// file to be owned by root, which is rarely what a struct literal meant.
+func File(path string) *filecheck.Check {
+	return wire(&filecheck.Check{Path: path, Owner: -1})
+}
+
+// Git checks the state of the repository in the working directory. It checks
+// nothing until one of its fields is set, such as Clean.
+func Git() *gitcheck.Check {
+	return wire(&gitcheck.Check{})
+}
+
+// Hash checks a file's checksum, telling the algorithm from the length of
+// expected. Set Algorithm and clear AutoDetect to name it instead.
+func Hash(file, expected string) *hashcheck.Check {
+	return wire(&hashcheck.Check{File: file, ExpectedHash: expected, AutoDetect: true})
+}
+
+// HTTP checks that a GET of url answers 200.
+func HTTP(url string) *httpcheck.Check {
+	return wire(&httpcheck.Check{URL: url})
+}
+
+// JSON checks that a file holds valid JSON.
+func JSON(file string) *jsoncheck.Check {
+	return wire(&jsoncheck.Check{File: file})
+}
+
+// Pkg checks that the package name is installed, reading the database of
+// manager (pkgcheck.ManagerApt and so on), or of apt or apk as /etc/os-release
+// tells when manager is "". Set the version fields on the result to check its
+// version too.
+func Pkg(name, manager string) *pkgcheck.Check {
+	return wire(&pkgcheck.Check{Name: name, Manager: manager})
+}
+
+// Proc checks that a process whose name or command line matches the regex
+// running is up. Set PIDFile, User, Listening or the counts to check more.
+func Proc(running string) *proccheck.Check {
+	return wire(&proccheck.Check{Running: running})
+}
+
+// Prometheus checks that a PromQL query against url returns a single value.
+// Set Min, Max or Exact to check the value.
+func Prometheus(url, query string) *promcheck.Check {
+	return wire(&promcheck.Check{URL: url, Query: query})
+}
+
+// Resource checks free disk, available memory and CPUs against the minimums
+// set on the result. It checks nothing until one is set.
+func Resource() *resourcecheck.Check {
+	return wire(&resourcecheck.Check{})
+}
+
+// Sys checks the operating system and architecture against ExpectedOS and
+// ExpectedArch on the result. It fails until one of them is set.
+func Sys() *syscheck.Check {
+	return wire(&syscheck.Check{})
+}
+
+// TCP checks that a connection to address, as host:port, can be opened.
+func TCP(address string) *tcpcheck.Check {
+	return wire(&tcpcheck.Check{Address: address})
+}
+
+// Template renders source to dest from the environment, with mode 0644.
+func Template(source, dest string) *templatecheck.Check {
+	return wire(&templatecheck.Check{Source: source, Dest: dest, Mode: 0o644})
+}
+
+// TLS checks that a TLS handshake with address, as host:port, completes and
+// that the served certificate verifies for its host. Set MinVersion, ALPN,
+// MinDays and the rest to check more.
+func TLS(address string) *tlscheck.Check {
+	return wire(&tlscheck.Check{Address: address})
+}
+
+// User checks that a user exists.
+func User(username string) *usercheck.Check {
+	return wire(&usercheck.Check{Username: username})
+}
+
+// YAML checks that a file holds valid YAML, every document of it.
+func YAML(file string) *yamlcheck.Check {
+	return wire(&yamlcheck.Check{File: file})
+}
+
+// Warn reports c's failure as a warning, which Report.Err does not count.
+func Warn(c Checker) Checker {
+	return check.Advisory{Checker: c}
+}
+
+// Retry reruns c until it passes or p is spent.
+func Retry(c Checker, p retry.Policy) Checker {
+	return retry.Wrap(c, p)
+}
+
+// wire gives c the real implementation of every dependency it was built
+// without, looking through Warn and Retry to the check they wrap. A dependency
+// already set, such as a fake in a test, is kept.
+//
+// http and prometheus are left alone. They build their own client when none
+// is set, from their Timeout and Insecure fields, at the moment they run; one
+// made here would miss a change to those after construction.
+func wire[C Checker](c C) C {
+	switch c := any(c).(type) {
+	case check.Advisory:
+		wire(c.Checker)
+	case *retry.Check:
+		wire(c.Checker)
+	case *certcheck.Check:
+		if c.FS == nil {
+			c.FS = &certcheck.RealFileSystem{}
+		}
+	case *cmdcheck.Check:
+		if c.Runner == nil {
+			c.Runner = &cmdcheck.RealCmdRunner{}
+		}
+	case *envcheck.Check:
+		if c.Getter == nil {
+			c.Getter = &envcheck.RealEnvGetter{}
+		}
+		if c.Stater == nil {
+			c.Stater = &envcheck.RealFileStater{}
+		}
+	case *filecheck.Check:
+		if c.FS == nil {
+			c.FS = &filecheck.RealFileSystem{}
+		}
+	case *gitcheck.Check:
+		if c.Runner == nil {
+			c.Runner = &gitcheck.RealGitRunner{}
+		}
+	case *hashcheck.Check:
+		if c.Opener == nil {
+			c.Opener = &hashcheck.RealHashFileOpener{}
+		}
+	case *jsoncheck.Check:
+		if c.FS == nil {
+			c.FS = &jsoncheck.RealFileSystem{}
+		}
+	case *plugincheck.Check:
+		if c.Runner == nil {
+			c.Runner = &cmdcheck.RealCmdRunner{}
+		}
+	case *proccheck.Check:
+		if c.Proc == nil {
+			c.Proc = &proccheck.RealProcFS{}
+		}
+		if c.Users == nil {
+			c.Users = &usercheck.RealUserLookup{}
+		}
+	case *resourcecheck.Check:
+		if c.Checker == nil {
+			c.Checker = &resourcecheck.RealResourceChecker{}
+		}
+	case *syscheck.Check:
+		if c.Info == nil {
+			c.Info = &syscheck.RealSysInfo{}
+		}
+	case *tcpcheck.Check:
+		if c.Dialer == nil {
+			c.Dialer = &tcpcheck.RealTCPDialer{}
+		}
+	case *templatecheck.Check:
+		if c.Env == nil {
+			c.Env = &templatecheck.RealEnvironment{}
+		}
+		if c.FS == nil {
+			c.FS = &templatecheck.RealFileSystem{}
+		}
+	case *tlscheck.Check:
+		if c.Dialer == nil {
+			c.Dialer = &tcpcheck.RealTCPDialer{}
+		}
+		if c.FS == nil {
+			c.FS = &tlscheck.RealFileSystem{}
+		}
+	case *usercheck.Check:
+		if c.Lookup == nil {
+			c.Lookup = &usercheck.RealUserLookup{}
+		}
+	case *yamlcheck.Check:
+		if c.FS == nil {
+			c.FS = &yamlcheck.RealFileSystem{}
+		}
+	}
+	return c
+}
+
+// typeOf names the preflight command c belongs to, as JSON output records it,
+// or returns "" for a check from outside this module.
+func typeOf(c Checker) string {
+	switch c := c.(type) {
+	case check.Advisory:
+		return typeOf(c.Checker)
+	case *retry.Check:
+		return typeOf(c.Checker)
+	case *certcheck.Check:
+		return "cert"
+	case *cmdcheck.Check:
+		return "cmd"
+	case *envcheck.Check:
+		return "env"
+	case *filecheck.Check:
+		return "file"
+	case *gitcheck.Check:
+		return "git"
+	case *hashcheck.Check:
+		return "hash"
+	case *httpcheck.Check:
+		return "http"
+	case *jsoncheck.Check:
+		return "json"
+	case *pkgcheck.Check:
+		return "pkg"
+	case *plugincheck.Check:
+		return c.Name
+	case *proccheck.Check:
+		return "proc"
+	case *promcheck.Check:
+		return "prometheus"
+	case *resourcecheck.Check:
+		return "resource"
+	case *syscheck.Check:
+		return "sys"
+	case *tcpcheck.Check:
+		return "tcp"
+	case *templatecheck.Check:
+		return "template"
+	case *tlscheck.Check:
+		return "tls"
+	case *usercheck.Check:
+		return "user"
+	case *yamlcheck.Check:
+		return "yaml"
+	}
+	return ""
+}
+
+// nameOf names c as its result would, for a check that is never run. It is
+// "" for a check from outside this module, which is then named by its type.
+func nameOf(c Checker) string {
+	switch c := c.(type) {
+	case check.Advisory:
+		return nameOf(c.Checker)
+	case *retry.Check:
+		return nameOf(c.Checker)
+	case *certcheck.Check:
+		return "cert: " + c.File
+	case *cmdcheck.Check:
+		return "cmd: " + c.Name
+	case *envcheck.Check:
+		return "env: " + c.Name
+	case *filecheck.Check:
+		return "file: " + c.Path
+	case *gitcheck.Check:
+		return "git"
+	case *hashcheck.Check:
+		return "hash: " + c.File
+	case *httpcheck.Check:
+		return "http: " + c.URL
+	case *jsoncheck.Check:
+		return "json: " + c.File
+	case *pkgcheck.Check:
+		return "pkg: " + c.Name
+	case *plugincheck.Check:
+		return c.Name
+	case *proccheck.Check:
+		return "proc: " + c.Subject()
+	case *promcheck.Check:
+		return "prometheus: " + c.URL
+	case *resourcecheck.Check:
+		return "resource"
+	case *syscheck.Check:
+		return "sys"
+	case *tcpcheck.Check:
+		return "tcp: " + c.Address
+	case *templatecheck.Check:
+		return "template: " + c.Source
+	case *tlscheck.Check:
+		return "tls: " + c.Address
+	case *usercheck.Check:
+		return "user: " + c.Username
+	case *yamlcheck.Check:
+		return "yaml: " + c.File
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+// Package preflight runs preflight's checks from a Go program, such as a
+// service verifying its dependencies before it starts serving:
+//
+//	report := preflight.Run(ctx,
+//		preflight.TCP("db:5432"),
+//		preflight.Env("DATABASE_URL"),
+//		preflight.Warn(preflight.HTTP("http://cache:8080/health")),
+//	)
+//	report.WriteText(os.Stderr)
+//	if err := report.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// The check packages leave what a check talks to — the dialer, the command
+// runner, the file system — for the caller to supply, so their tests can
+// replace it, and a check with none supplied panics when run. The constructors
+// here supply the real ones, and Run fills in any a check was built without,
+// so a struct literal works as well:
+//
+//	preflight.Run(ctx, &tcpcheck.Check{Address: "db:5432", Timeout: time.Second})
+package preflight
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/output"
+)
+
+// Checker is a check Run can run. Every check package's *Check is one.
+type Checker = check.Checker
+
+// Run runs checks one after another and reports on all of them. A failure does
+// not stop the run, so the report has everything wrong at once, as `preflight
+// run` does.
+//
+// A check still running when ctx is done fails as interrupted, and the checks
+// after it fail as not run.
+func Run(ctx context.Context, checks ...Checker) Report {
+	report := Report{
+		Results: make([]check.Result, 0, len(checks)),
+		types:   make([]string, 0, len(checks)),
+	}
+	for _, c := range checks {
+		checkType := typeOf(c)
+		var result check.Result
+		if ctx.Err() != nil {
+			result = check.NotRun(cmp.Or(nameOf(c), checkType), context.Cause(ctx))
+		} else {
+			result = check.Run(ctx, wire(c))
+		}
+		report.Results = append(report.Results, result)
+		report.types = append(report.types, checkType)
+	}
+	return report
+}
+
+// Report is the outcome of Run: one result per check, in the order given.
+type Report struct {
+	Results []check.Result
+
+	types []string // the command each check belongs to, for JSON
+}
+
+// Failed reports whether any check failed. Warnings do not count.
+func (r Report) Failed() bool {
+	for _, res := range r.Results {
+		if res.Failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns nil if no check failed, and otherwise an error naming each check
+// that did and why.
+func (r Report) Err() error {
+	var errs []error
+	for _, res := range r.Results {
+		if !res.Failed() {
+			continue
+		}
+		err := res.Err
+		if err == nil {
+			err = errors.New("failed")
+		}
+		errs = append(errs, &CheckError{Name: res.Name, Err: err})
+	}
+	return errors.Join(errs...)
+}
+
+// CheckError is one failed check in the error Err returns.
+type CheckError struct {
+	Name string // the check's name, such as "tcp: db:5432"
+	Err  error
+}
+
+func (e *CheckError) Error() string { return e.Name + ": " + e.Err.Error() }
+
+func (e *CheckError) Unwrap() error { return e.Err }
+
+// WriteText writes every result to w as the preflight command prints it.
+func (r Report) WriteText(w io.Writer) {
+	for _, res := range r.Results {
+		output.WriteResult(w, res)
+	}
+}
+
+// WriteJSON writes the report to w as a single line of JSON, in the form
+// `preflight run --output json` prints.
+func (r Report) WriteJSON(w io.Writer) error {
+	return output.WriteJSON(w, output.NewReport("", r.records()))
+}
+
+func (r Report) records() []output.Record {
+	records := make([]output.Record, len(r.Results))
+	for i, res := range r.Results {
+		checkType := ""
+		if i < len(r.types) {
+			checkType = r.types[i]
+		}
+		records[i] = output.NewRecord(res, checkType)
+	}
+	return records
+}
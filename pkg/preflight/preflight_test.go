@@ -0,0 +1,143 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/envcheck"
+	"github.com/vertti/preflight/pkg/output"
+	"github.com/vertti/preflight/pkg/retry"
+	"github.com/vertti/preflight/pkg/tcpcheck"
+)
+
+func TestRun(t *testing.T) {
+	t.Setenv("PREFLIGHT_LIBRARY_TEST_SET", "yes")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	report := Run(t.Context(),
+		TCP(listener.Addr().String()),
+		Env("PREFLIGHT_LIBRARY_TEST_SET"),
+		Env("PREFLIGHT_LIBRARY_TEST_UNSET"),
+		Warn(File(filepath.Join(t.TempDir(), "absent"))),
+	)
+
+	require.Len(t, report.Results, 4)
+	assert.Equal(t, check.StatusOK, report.Results[0].Status)
+	assert.Equal(t, check.StatusOK, report.Results[1].Status)
+	assert.Equal(t, check.StatusFail, report.Results[2].Status, "a failure does not stop the run")
+	assert.Equal(t, check.StatusWarn, report.Results[3].Status)
+	assert.True(t, report.Failed())
+
+	err = report.Err()
+	require.Error(t, err)
+	assert.Equal(t, "env: PREFLIGHT_LIBRARY_TEST_UNSET: environment variable PREFLIGHT_LIBRARY_TEST_UNSET is not set", err.Error(),
+		"only failures are named")
+	var checkErr *CheckError
+	require.ErrorAs(t, err, &checkErr)
+	assert.Equal(t, "env: PREFLIGHT_LIBRARY_TEST_UNSET", checkErr.Name)
+
+	t.Run("no failures, no error", func(t *testing.T) {
+		report := Run(t.Context(), Env("PREFLIGHT_LIBRARY_TEST_SET"), Warn(Env("PREFLIGHT_LIBRARY_TEST_UNSET")))
+		assert.False(t, report.Failed())
+		require.NoError(t, report.Err())
+	})
+}
+
+// A check built without the constructors used to panic on its nil dependency.
+func TestRun_WiresStructLiterals(t *testing.T) {
+	t.Setenv("PREFLIGHT_LIBRARY_TEST_SET", "yes")
+
+	env := &envcheck.Check{Name: "PREFLIGHT_LIBRARY_TEST_SET"}
+	tcp := &tcpcheck.Check{Address: "127.0.0.1:1", Timeout: 50 * time.Millisecond}
+	report := Run(t.Context(), env, Retry(Warn(tcp), retry.Policy{}))
+
+	assert.Equal(t, check.StatusOK, report.Results[0].Status)
+	assert.Equal(t, check.StatusWarn, report.Results[1].Status)
+	assert.NotNil(t, tcp.Dialer, "wrapped checks are wired too")
+
+	t.Run("a dependency already set is kept", func(t *testing.T) {
+		getter := &fakeGetter{value: "from the fake"}
+		report := Run(t.Context(), &envcheck.Check{Name: "ANY", Getter: getter})
+		assert.Equal(t, check.StatusOK, report.Results[0].Status)
+		assert.Contains(t, report.Results[0].Details, "value: from the fake")
+	})
+}
+
+type fakeGetter struct{ value string }
+
+func (g *fakeGetter) LookupEnv(string) (string, bool) { return g.value, true }
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(t.Context())
+	cancel(errors.New("shutting down"))
+
+	report := Run(ctx, TCP("127.0.0.1:1"), TCP("127.0.0.1:2"), Warn(Env("HOME")))
+	for _, res := range report.Results {
+		assert.Equal(t, check.StatusFail, res.Status)
+		assert.Equal(t, []string{"not run: shutting down"}, res.Details)
+	}
+	var names []string
+	for _, res := range report.Results {
+		names = append(names, res.Name)
+	}
+	assert.Equal(t, []string{"tcp: 127.0.0.1:1", "tcp: 127.0.0.1:2", "env: HOME"}, names)
+}
+
+// A check that is never run is named as running it would have named it.
+func TestNameOf(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0o600))
+	for _, c := range []Checker{Env("HOME"), File(path), JSON(path), YAML(path), Sys(), Resource(), Proc("init")} {
+		assert.Equal(t, Run(t.Context(), c).Results[0].Name, nameOf(c), "%T", c)
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	assert.Equal(t, -1, File("/etc/hosts").Owner, "File checks no owner")
+	assert.Equal(t, os.FileMode(0o644), Template("in.tmpl", "out").Mode)
+	assert.True(t, Hash("app.tar.gz", "abc").AutoDetect)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"port": 8080}`), 0o600))
+	c := JSON(path)
+	c.HasKey = "port"
+	sys := Sys()
+	sys.ExpectedOS = runtime.GOOS
+	report := Run(t.Context(), c, File(path), sys, Resource())
+	require.NoError(t, report.Err())
+}
+
+func TestReport_Write(t *testing.T) {
+	t.Setenv("PREFLIGHT_LIBRARY_TEST_SET", "yes")
+	report := Run(t.Context(), Env("PREFLIGHT_LIBRARY_TEST_SET"), Warn(Env("PREFLIGHT_LIBRARY_TEST_UNSET")))
+
+	var text bytes.Buffer
+	report.WriteText(&text)
+	assert.Contains(t, text.String(), "env: PREFLIGHT_LIBRARY_TEST_SET")
+	assert.Contains(t, text.String(), "env: PREFLIGHT_LIBRARY_TEST_UNSET")
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJSON(&buf))
+	var decoded output.Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, check.StatusWarn, decoded.Status)
+	assert.Equal(t, 2, decoded.Ran)
+	assert.Equal(t, "env", decoded.Checks[1].Type, "the type is found through Warn")
+	assert.NotContains(t, buf.String(), `"file"`)
+}
@@ -2,6 +2,8 @@ package preflightfile
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -18,8 +20,27 @@ import (
 // be pasted in unchanged. Double quotes recognise \" and \\ and leave every
 // other backslash alone, which keeps `"^v2\."` meaning what it looks like.
 // There is no variable expansion, command substitution or globbing: a line is a
-// list of arguments, not a shell command.
+// list of arguments, not a shell command. ExpandFields is the opt-in exception.
 func Fields(line string) ([]string, error) {
+	return splitFields(line, nil)
+}
+
+// ExpandFields is Fields with ${VAR} expansion, for files that opt in to it.
+// lookup resolves a name and reports whether it is set.
+//
+// Expansion happens while splitting, not before it, so a value with a space in
+// it stays one argument and a value with a quote in it cannot end the quoting
+// around it. Single quotes stay literal, as in a shell; "$$" is a literal "$".
+//
+//	${VAR}          the value; an error if VAR is not set
+//	${VAR:-default} default if VAR is unset or empty
+//	${VAR:?message} an error carrying message if VAR is unset or empty
+func ExpandFields(line string, lookup func(name string) (string, bool)) ([]string, error) {
+	return splitFields(line, lookup)
+}
+
+// splitFields is Fields, expanding variables when lookup is not nil.
+func splitFields(line string, lookup func(string) (string, bool)) ([]string, error) {
 	var (
 		fields  []string
 		current strings.Builder
@@ -50,6 +71,14 @@ func Fields(line string) ([]string, error) {
 				quote = 0
 				continue
 			}
+			if c == '$' && lookup != nil {
+				n, err := expandAt(line[i:], lookup, &current)
+				if err != nil {
+					return nil, err
+				}
+				i += n - 1
+				continue
+			}
 			current.WriteByte(c)
 
 		case c == '\'' || c == '"':
@@ -64,6 +93,14 @@ func Fields(line string) ([]string, error) {
 			current.WriteByte(line[i])
 			started = true
 
+		case c == '$' && lookup != nil:
+			n, err := expandAt(line[i:], lookup, &current)
+			if err != nil {
+				return nil, err
+			}
+			i += n - 1
+			started = true
+
 		case c == ' ' || c == '\t':
 			if started || current.Len() > 0 {
 				fields = append(fields, current.String())
@@ -94,3 +131,73 @@ func quoteName(quote byte) string {
 	}
 	return "double"
 }
+
+// expandAt expands the reference at the start of s, which begins with "$",
+// into out, and returns how many bytes of s it consumed. A "$" that starts no
+// reference is kept as it is.
+func expandAt(s string, lookup func(string) (string, bool), out *strings.Builder) (int, error) {
+	switch {
+	case strings.HasPrefix(s, "$$"):
+		out.WriteByte('$')
+		return 2, nil
+	case !strings.HasPrefix(s, "${"):
+		out.WriteByte('$')
+		return 1, nil
+	}
+
+	end := strings.IndexByte(s, '}')
+	if end < 0 {
+		return 0, errors.New("unterminated ${")
+	}
+	expr := s[2:end]
+	name, op, arg := expr, "", ""
+	if i := strings.Index(expr, ":"); i >= 0 {
+		name, op, arg = expr[:i], expr[i:min(i+2, len(expr))], expr[min(i+2, len(expr)):]
+	}
+	if !variableName.MatchString(name) || (op != "" && op != ":-" && op != ":?") {
+		return 0, fmt.Errorf("invalid variable reference ${%s}", expr)
+	}
+
+	value, ok := lookup(name)
+	switch op {
+	case ":-":
+		if !ok || value == "" {
+			value = arg
+		}
+	case ":?":
+		if !ok || value == "" {
+			if arg == "" {
+				arg = "not set"
+			}
+			return 0, fmt.Errorf("%s: %s", name, arg)
+		}
+	default:
+		if !ok {
+			return 0, fmt.Errorf("%s is not set", name)
+		}
+	}
+	out.WriteString(value)
+	return end + 1, nil
+}
+
+// variableName is an environment variable name or a positional parameter.
+var variableName = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*|[0-9]+)$`)
+
+// Join is the inverse of Fields: it quotes args so that Fields returns them
+// unchanged. Arguments that need no quoting are left bare.
+func Join(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == "":
+			quoted[i] = "''"
+		case strings.ContainsAny(arg, " \t'\"\\"):
+			// Inside single quotes nothing is special but the closing quote,
+			// which has to step outside them: ' becomes '\''.
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		default:
+			quoted[i] = arg
+		}
+	}
+	return strings.Join(quoted, " ")
+}
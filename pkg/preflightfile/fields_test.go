@@ -62,3 +62,81 @@ func TestFields_UnterminatedQuote(t *testing.T) {
 		})
 	}
 }
+
+// Without opting in, a "$" is just a character.
+func TestFields_DoesNotExpand(t *testing.T) {
+	got, err := Fields(`tcp ${DB_HOST}:5432`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tcp", "${DB_HOST}:5432"}, got)
+}
+
+func TestExpandFields(t *testing.T) {
+	vars := map[string]string{"HOST": "db", "GREETING": "hello world", "EMPTY": "", "QUOTE": `it's "x"`, "1": "staging"}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"plain reference", `tcp ${HOST}:5432`, []string{"tcp", "db:5432"}},
+		{"default when unset", `tcp ${PORT_HOST:-postgres}:${PORT:-5432}`, []string{"tcp", "postgres:5432"}},
+		{"default when empty", `env X --exact ${EMPTY:-fallback}`, []string{"env", "X", "--exact", "fallback"}},
+		{"set value wins over default", `tcp ${HOST:-postgres}`, []string{"tcp", "db"}},
+		{"positional parameter", `env APP_ENV --exact ${1}`, []string{"env", "APP_ENV", "--exact", "staging"}},
+
+		// A value is one argument however many spaces or quotes it holds.
+		{"value with a space stays one argument", `env G --exact ${GREETING}`, []string{"env", "G", "--exact", "hello world"}},
+		{"value with quotes cannot end the quoting", `env Q --exact "${QUOTE}"`, []string{"env", "Q", "--exact", `it's "x"`}},
+		{"expands inside double quotes", `env G --exact "say ${GREETING}"`, []string{"env", "G", "--exact", "say hello world"}},
+		{"single quotes stay literal", `env G --exact '${GREETING}'`, []string{"env", "G", "--exact", "${GREETING}"}},
+		{"dollar dollar is a dollar", `env P --exact $${HOST}`, []string{"env", "P", "--exact", "${HOST}"}},
+		{"lone dollar is kept", `env P --match ^a$`, []string{"env", "P", "--match", "^a$"}},
+		{"empty value is still an argument", `env X --exact ${EMPTY}`, []string{"env", "X", "--exact", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandFields(tt.line, lookup)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	errTests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"unset variable", `tcp ${DB_HOST}:5432`, "DB_HOST is not set"},
+		{"required with message", `tcp ${DB_HOST:?set DB_HOST to the database host}`, "DB_HOST: set DB_HOST to the database host"},
+		{"required and empty", `env X --exact ${EMPTY:?}`, "EMPTY: not set"},
+		{"missing positional", `env X --exact ${2}`, "2 is not set"},
+		{"unterminated", `tcp ${HOST`, "unterminated ${"},
+		{"bad name", `tcp ${DB-HOST}`, "invalid variable reference ${DB-HOST}"},
+		{"unknown operator", `tcp ${HOST:=x}`, "invalid variable reference"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExpandFields(tt.line, lookup)
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestJoin_RoundTripsThroughFields(t *testing.T) {
+	for _, args := range [][]string{
+		{"env", "HOME"},
+		{"env", "G", "--exact", "hello world"},
+		{"env", "X", "--exact", ""},
+		{"json", "f", "--exact", `{"a": 1}`},
+		{"env", "Q", "--exact", `it's`},
+		{"cmd", "x", "--match", `^v2\.`},
+	} {
+		got, err := Fields(Join(args))
+		require.NoError(t, err)
+		assert.Equal(t, args, got, "Join(%q) = %s", args, Join(args))
+	}
+}
@@ -0,0 +1,126 @@
+package preflightfile
+
+import (
+	"slices"
+	"strings"
+)
+
+// Format returns a .preflight file written the one way fmt writes it: every
+// line trimmed, arguments quoted the way Join quotes them, and the "preflight"
+// a command line may start with left out, since ParseLines adds it back.
+//
+// Only the spelling changes. A line that would read differently written the
+// canonical way, or that cannot be read at all, is kept as it is, so
+// formatting can never change what a file checks; lint reports the lines
+// that cannot be read.
+func Format(data []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = formatLine(line)
+	}
+	// One newline at the end, whatever blank lines or missing newline it had.
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// formatLine returns line in its canonical spelling.
+func formatLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return trimmed
+	}
+	if name, ok := sectionHeader(trimmed); ok {
+		return "[" + name + "]"
+	}
+
+	var tags []string
+	for strings.HasPrefix(trimmed, "@") {
+		tag, rest := trimmed, ""
+		if i := strings.IndexAny(trimmed, " \t"); i >= 0 {
+			tag, rest = trimmed[:i], trimmed[i:]
+		}
+		tags = append(tags, tag)
+		trimmed = strings.TrimSpace(rest)
+	}
+
+	fields, err := Fields(trimmed)
+	if err != nil || len(fields) == 0 {
+		return strings.TrimSpace(line)
+	}
+	fields = stripPreflight(fields)
+	formatted := Join(fields)
+	if !sameArguments(trimmed, formatted) {
+		return strings.TrimSpace(line)
+	}
+	return strings.Join(append(tags, formatted), " ")
+}
+
+// stripPreflight drops a leading "preflight", and the one after a warn
+// modifier, unless what follows would then be read as something other than a
+// command: an include, a tag, a comment or a section.
+func stripPreflight(fields []string) []string {
+	i := 0
+	if fields[0] == WarnModifier {
+		i = 1
+	}
+	if len(fields) < i+2 || fields[i] != "preflight" {
+		return fields
+	}
+	next := fields[i+1]
+	if next == IncludeDirective || strings.HasPrefix(next, "@") ||
+		strings.HasPrefix(next, "#") || strings.HasPrefix(next, "[") {
+		return fields
+	}
+	return slices.Delete(slices.Clone(fields), i, i+1)
+}
+
+// sameArguments reports whether before and after, with any "preflight" they
+// start with set aside, split into the same arguments, with and without
+// ${VAR} expansion. Quoting decides what a "$" means, so a line whose quotes
+// Join would change has to expand the same way too.
+func sameArguments(before, after string) bool {
+	for _, split := range []func(string) ([]string, error){
+		Fields,
+		func(line string) ([]string, error) { return ExpandFields(line, placeholder) },
+	} {
+		b, errB := split(before)
+		a, errA := split(after)
+		if errB != nil || errA != nil {
+			if errB == nil || errA == nil {
+				return false
+			}
+			continue
+		}
+		if !slices.Equal(withoutPreflight(b), withoutPreflight(a)) {
+			return false
+		}
+	}
+	return true
+}
+
+// withoutPreflight returns the arguments of a command line as normalizeCommand
+// would leave them, less the "preflight" it puts first.
+func withoutPreflight(fields []string) []string {
+	if len(fields) > 0 && fields[0] == WarnModifier {
+		if len(fields) > 1 && fields[1] == "preflight" {
+			return slices.Concat(fields[:1], fields[2:])
+		}
+		return fields
+	}
+	if len(fields) > 0 && fields[0] == "preflight" {
+		return fields[1:]
+	}
+	return fields
+}
+
+// placeholder answers every variable with a value naming it, so two
+// expansions are equal only if they reference the same variables in the same
+// places.
+func placeholder(name string) (string, bool) {
+	return "\x00" + name + "\x00", true
+}
@@ -0,0 +1,82 @@
+package preflightfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"already canonical", "env HOME\n", "env HOME\n"},
+		{"preflight prefix", "preflight env HOME\n", "env HOME\n"},
+		{"preflight after warn", "warn preflight tcp db:5432\n", "warn tcp db:5432\n"},
+		{"warn after preflight", "preflight warn tcp db:5432\n", "warn tcp db:5432\n"},
+		{"whitespace", "  env \t HOME  \n\n\t\n", "env HOME\n"},
+		{"double quotes become single", `env G --exact "hello world"` + "\n", "env G --exact 'hello world'\n"},
+		{"needless quotes", `env "HOME"` + "\n", "env HOME\n"},
+		{"escaped space", `env G --exact hello\ world` + "\n", "env G --exact 'hello world'\n"},
+		{"tags", "@slow\t@db   preflight tcp db:5432\n", "@slow @db tcp db:5432\n"},
+		{"comments and sections", "  # checks  \n[ database ]\ntcp db:5432", "# checks\n[database]\ntcp db:5432\n"},
+		{"blank lines between groups are kept", "env A\n\nenv B\n", "env A\n\nenv B\n"},
+		{"include", `include "common/base.preflight"` + "\n", "include common/base.preflight\n"},
+		{"crlf", "env HOME\r\nenv PATH\r\n", "env HOME\nenv PATH\n"},
+		{"empty", "\n\n", ""},
+
+		// Each of these would mean something else with "preflight" taken off.
+		{"a command named include", "preflight include x\n", "preflight include x\n"},
+		{"an argument that looks like a tag", "preflight '@x'\n", "preflight @x\n"},
+		{"an argument that looks like a comment", "preflight '#x'\n", "preflight #x\n"},
+
+		// Quoting decides what a "$" means, so these are left alone.
+		{"single-quoted variable", `env X --exact '${HOME}'` + "\n", `env X --exact '${HOME}'` + "\n"},
+		{"double-quoted variable", `env X --exact "${HOME}"` + "\n", `env X --exact ${HOME}` + "\n"},
+		{"variable with a space in its default", `tcp "${HOST:-a b}"` + "\n", `tcp "${HOST:-a b}"` + "\n"},
+
+		// Left for lint to report.
+		{"unterminated quote", `  env X --exact "hello` + "\n", `env X --exact "hello` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(Format([]byte(tt.in))))
+		})
+	}
+}
+
+// A formatted file reads back as the same lines, and formatting it again
+// changes nothing.
+func TestFormat_KeepsMeaning(t *testing.T) {
+	content := `# preflight: interpolate
+[startup]
+@slow   preflight tcp "${DB_HOST:-db}:5432"
+warn preflight env GREETING --exact "it's \"here\""
+preflight cmd node --match '^v2\.'
+json   config.json --key a --exact '{"b": 1}'
+`
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before")
+	after := filepath.Join(dir, "after")
+	require.NoError(t, os.WriteFile(before, []byte(content), 0o600))
+	formatted := Format([]byte(content))
+	require.NoError(t, os.WriteFile(after, formatted, 0o600))
+	assert.Equal(t, string(formatted), string(Format(formatted)))
+
+	opts := Options{LookupEnv: func(string) (string, bool) { return "", false }}
+	want, err := ParseLines(before, opts)
+	require.NoError(t, err)
+	got, err := ParseLines(after, opts)
+	require.NoError(t, err)
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.Equal(t, want[i].Command, got[i].Command)
+		assert.Equal(t, want[i].Tags, got[i].Tags)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -31,6 +34,12 @@ func FindFile(startDir, explicitPath string) (string, error) {
 		if _, err := os.Stat(preflightPath); err == nil {
 			return preflightPath, nil
 		}
+		// A directory of drop-ins alone is a file too: the baseline a base image
+		// ships needs no .preflight of the service's own to run.
+		dropIn := filepath.Join(currentDir, DropInDir)
+		if info, err := os.Stat(dropIn); err == nil && info.IsDir() {
+			return dropIn, nil
+		}
 
 		if currentDir == homeDir {
 			break
@@ -52,15 +61,230 @@ func FindFile(startDir, explicitPath string) (string, error) {
 	return "", errors.New(".preflight file not found")
 }
 
+// WarnModifier starts a line whose check only warns when it fails. ParseFile
+// moves it after "preflight", so "warn tcp db:5432" and
+// "warn preflight tcp db:5432" both become "preflight warn tcp db:5432".
+const WarnModifier = "warn"
+
+// Line is one command from a .preflight file, with the tags that select it.
+type Line struct {
+	File    string   // the file it came from, which an include makes worth knowing
+	Number  int      // 1-based, in that file
+	Command string   // always starts with the token "preflight"
+	Tags    []string // the enclosing [section], if any, then the line's own @tags
+}
+
+// HasTag reports whether the line carries tag.
+func (l Line) HasTag(tag string) bool {
+	return slices.Contains(l.Tags, tag)
+}
+
+// tagPattern is what a section or tag name may contain. It is narrow on purpose
+// so a tag can never be mistaken for a flag or an argument.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// InterpolateHeader is the comment that opts a file in to ${VAR} expansion. It
+// has to come before the first command, where a reader will see it.
+const InterpolateHeader = "# preflight: interpolate"
+
+// Options are the caller's say in how a file is read.
+type Options struct {
+	// Interpolate expands ${VAR} in a file without InterpolateHeader.
+	Interpolate bool
+	// Vars are consulted before the environment, and Args answer ${1}, ${2}
+	// and so on. Either is an error for a file that is not interpolated,
+	// since they would silently do nothing.
+	Vars map[string]string
+	Args []string
+	// LookupEnv resolves the environment; os.LookupEnv when nil.
+	LookupEnv func(string) (string, bool)
+	// OnLineError, when set, is given every line that cannot be read, and
+	// reading carries on past it. That is how lint reports every mistake in a
+	// file at once. Without it the first one is returned as the error.
+	OnLineError func(*LineError)
+}
+
+// LineError is a line of a .preflight file that cannot be read.
+type LineError struct {
+	File   string
+	Number int // 1-based, in File
+	Err    error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Number, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// lookup resolves a variable from Vars, then Args, then the environment.
+func (o Options) lookup(name string) (string, bool) {
+	if v, ok := o.Vars[name]; ok {
+		return v, true
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if n >= 1 && n <= len(o.Args) {
+			return o.Args[n-1], true
+		}
+		return "", false
+	}
+	lookupEnv := o.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	return lookupEnv(name)
+}
+
+// ParseFile returns the commands in a .preflight file, every one of them
+// starting with "preflight". It ignores sections and tags; ParseLines keeps
+// them.
 func ParseFile(path string) ([]string, error) {
+	lines, err := ParseLines(path, Options{})
+	if err != nil {
+		return nil, err
+	}
+	commands := make([]string, 0, len(lines))
+	for _, l := range lines {
+		commands = append(commands, l.Command)
+	}
+	return commands, nil
+}
+
+// ParseLines reads a .preflight file into its commands.
+//
+// A line of the form "[name]" starts a section, and every command after it
+// until the next one is tagged with the section's name. A command may also
+// carry tags of its own as leading "@name" tokens:
+//
+//	[database]
+//	tcp postgres:5432
+//	@slow http http://postgres-exporter:9187/metrics
+//
+// One file can then serve build, startup and CI, each selecting its lines with
+// `run --tag`, where five near-copies of it used to be kept instead.
+//
+// Variables are expanded only in a file that starts with InterpolateHeader or
+// when opts asks for it, and a variable that is not set fails the parse, so no
+// check ever runs against an empty host name.
+//
+// "include path" reads another file in place of the line, relative to the file
+// naming it; the included lines carry the including line's tags as well as
+// their own. path may also be a directory, whose files are read in lexical
+// order, and a file named .preflight is followed by the files in a .preflight.d
+// directory beside it. That is how a base image ships a baseline of checks for
+// every service to add its own to, without copying it into each repo.
+func ParseLines(path string, opts Options) ([]Line, error) {
+	p := &parser{opts: opts}
+	lines, err := p.parsePath(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !p.interpolated && (len(opts.Vars) > 0 || len(opts.Args) > 0) {
+		return nil, fmt.Errorf("variables were given but %s does not interpolate; add %q or pass --interpolate", path, InterpolateHeader)
+	}
+	return lines, nil
+}
+
+// IncludeDirective reads another file in place of the line it starts.
+const IncludeDirective = "include"
+
+// DropInDir is the directory beside a .preflight file whose files follow it.
+const DropInDir = ".preflight.d"
+
+// parser carries what reading one file needs to know about the files that
+// included it.
+type parser struct {
+	opts         Options
+	stack        []string // absolute paths being read, outermost first
+	interpolated bool     // at least one file expanded variables
+}
+
+// parsePath reads a file or a directory of them. tags are added to every line.
+func (p *parser) parsePath(path string, tags []string) ([]Line, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preflight file: %w", err)
+	}
+	if info.IsDir() {
+		return p.parseDir(path, tags)
+	}
+
+	lines, err := p.parseFile(path, tags)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Base(path) == ".preflight" {
+		dropIn := filepath.Join(filepath.Dir(path), DropInDir)
+		if info, err := os.Stat(dropIn); err == nil && info.IsDir() {
+			more, err := p.parseDir(dropIn, tags)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, more...)
+		}
+	}
+	return lines, nil
+}
+
+// parseDir reads every file in dir in lexical order. Hidden files and editor
+// backups ending in "~" are left out, so a .swp or a README~ never runs.
+func (p *parser) parseDir(dir string, tags []string) ([]Line, error) {
+	entries, err := os.ReadDir(dir) // sorted by name
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preflight directory: %w", err)
+	}
+	lines := []Line{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+		more, err := p.parsePath(filepath.Join(dir, name), tags)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, more...)
+	}
+	return lines, nil
+}
+
+// parseFile reads one file. Its errors name the line; an error from a file it
+// includes names the include line too, so the chain leads to the problem.
+func (p *parser) parseFile(path string, inherited []string) ([]Line, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if i := slices.Index(p.stack, abs); i >= 0 {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(p.stack[i:], abs), " -> "))
+	}
+	p.stack = append(p.stack, abs)
+	defer func() { p.stack = p.stack[:len(p.stack)-1] }()
+
 	data, err := os.ReadFile(path) //nolint:gosec // intentional: reading .preflight file
 	if err != nil {
 		return nil, fmt.Errorf("failed to read preflight file: %w", err)
 	}
 
 	lines := strings.Split(string(data), "\n")
-	commands := []string{}
+	parsed := []Line{}
+	section := ""
+	interpolate, inHeader := p.opts.Interpolate, true
+
+	// lineError ends the parse with err, or hands it to OnLineError and
+	// returns nil, in which case the line is skipped.
+	lineError := func(number int, err error) error {
+		le := &LineError{File: path, Number: number, Err: err}
+		if p.opts.OnLineError == nil {
+			return le
+		}
+		p.opts.OnLineError(le)
+		return nil
+	}
 
+lines:
 	for i, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
@@ -69,23 +293,161 @@ func ParseFile(path string) ([]string, error) {
 		}
 
 		if strings.HasPrefix(trimmed, "#") {
+			if inHeader && isInterpolateHeader(trimmed) {
+				interpolate = true
+			}
+			continue
+		}
+		inHeader = false
+		p.interpolated = p.interpolated || interpolate
+
+		if name, ok := sectionHeader(trimmed); ok {
+			if !tagPattern.MatchString(name) {
+				if err := lineError(i+1, fmt.Errorf("invalid section name %q", name)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			section = name
+			continue
+		}
+
+		tags := slices.Clone(inherited)
+		if section != "" {
+			tags = append(tags, section)
+		}
+		for strings.HasPrefix(trimmed, "@") {
+			tag, rest := trimmed, ""
+			if i := strings.IndexAny(trimmed, " \t"); i >= 0 {
+				tag, rest = trimmed[:i], trimmed[i:]
+			}
+			tag = strings.TrimPrefix(tag, "@")
+			if !tagPattern.MatchString(tag) {
+				if err := lineError(i+1, fmt.Errorf("invalid tag %q", tag)); err != nil {
+					return nil, err
+				}
+				continue lines
+			}
+			tags = append(tags, tag)
+			trimmed = strings.TrimSpace(rest)
+		}
+		if trimmed == "" {
+			if err := lineError(i+1, errors.New("tags need a check to run")); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
-		// Compare the first token, not the prefix. HasPrefix accepted anything
-		// beginning with "preflight" — including preflight/../evil.sh, a path
-		// that cmd_run then executed directly because its substitution tests
-		// for the exact token.
 		fields, err := Fields(trimmed)
+		if interpolate && err == nil {
+			fields, err = ExpandFields(trimmed, p.opts.lookup)
+			trimmed = Join(fields)
+		}
+		if err != nil {
+			if err := lineError(i+1, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if fields[0] == IncludeDirective {
+			if len(fields) != 2 {
+				if err := lineError(i+1, fmt.Errorf("%s takes exactly one path", IncludeDirective)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			target := fields[1]
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(path), target)
+			}
+			included, err := p.parsePath(target, tags)
+			if err != nil {
+				if err := lineError(i+1, fmt.Errorf("%s %s: %w", IncludeDirective, fields[1], err)); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			parsed = append(parsed, included...)
+			continue
+		}
+
+		command, err := normalizeCommand(trimmed)
 		if err != nil {
-			return nil, fmt.Errorf("line %d: %w", i+1, err)
+			if err := lineError(i+1, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		parsed = append(parsed, Line{File: path, Number: i + 1, Command: command, Tags: tags})
+	}
+
+	return parsed, nil
+}
+
+// isInterpolateHeader reports whether a comment is InterpolateHeader, however
+// it is spaced.
+func isInterpolateHeader(comment string) bool {
+	return slices.Equal(strings.Fields(strings.TrimPrefix(comment, "#")), strings.Fields(strings.TrimPrefix(InterpolateHeader, "#")))
+}
+
+// sectionHeader returns the name in a "[name]" line.
+func sectionHeader(line string) (string, bool) {
+	if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+		return "", false
+	}
+	return strings.TrimSpace(line[1 : len(line)-1]), true
+}
+
+// normalizeCommand makes a line start with the token "preflight", moving a warn
+// modifier after it.
+func normalizeCommand(line string) (string, error) {
+	// Compare the first token, not the prefix. HasPrefix accepted anything
+	// beginning with "preflight" — including preflight/../evil.sh, a path
+	// that cmd_run then executed directly because its substitution tests
+	// for the exact token.
+	fields, err := Fields(line)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) > 0 && fields[0] == WarnModifier && strings.HasPrefix(line, WarnModifier) {
+		rest := strings.TrimSpace(line[len(WarnModifier):])
+		if len(fields) == 1 {
+			return "", fmt.Errorf("%s needs a check to run", WarnModifier)
 		}
-		if len(fields) == 0 || fields[0] != "preflight" {
-			trimmed = "preflight " + trimmed
+		if fields[1] == "preflight" && strings.HasPrefix(rest, "preflight") {
+			rest = strings.TrimSpace(rest[len("preflight"):])
 		}
+		return "preflight " + WarnModifier + " " + rest, nil
+	}
+	if len(fields) == 0 || fields[0] != "preflight" {
+		return "preflight " + line, nil
+	}
+	return line, nil
+}
 
-		commands = append(commands, trimmed)
+// Select returns the lines to run: those carrying any of tags, or every line
+// when tags is empty, less those carrying any of skipTags.
+//
+// A tag that appears nowhere in the file is an error rather than a selection of
+// nothing. A typo in --tag would otherwise pass a run that checked nothing, and
+// one in --skip-tag would quietly run the slow checks it meant to leave out.
+func Select(lines []Line, tags, skipTags []string) ([]Line, error) {
+	for _, tag := range slices.Concat(tags, skipTags) {
+		if !slices.ContainsFunc(lines, func(l Line) bool { return l.HasTag(tag) }) {
+			return nil, fmt.Errorf("no line is tagged %q", tag)
+		}
 	}
 
-	return commands, nil
+	selected := []Line{}
+	for _, l := range lines {
+		if len(tags) > 0 && !slices.ContainsFunc(tags, l.HasTag) {
+			continue
+		}
+		if slices.ContainsFunc(skipTags, l.HasTag) {
+			continue
+		}
+		selected = append(selected, l)
+	}
+	return selected, nil
 }
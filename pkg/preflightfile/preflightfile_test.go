@@ -256,3 +256,378 @@ func TestParseFile_OnlyExactPreflightTokenCountsAsPrefixed(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFile_WarnModifier(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"bare command", "warn resource --min-disk 20G", "preflight warn resource --min-disk 20G"},
+		{"explicit preflight", "warn preflight cmd node --min 20", "preflight warn cmd node --min 20"},
+		{"extra spaces", "warn   env HOME", "preflight warn env HOME"},
+		{"already after preflight", "preflight warn env HOME", "preflight warn env HOME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".preflight")
+			require.NoError(t, os.WriteFile(path, []byte(tt.line+"\n"), 0o600))
+
+			got, err := ParseFile(path)
+			require.NoError(t, err)
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("ParseFile(%q) = %q, want [%q]", tt.line, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("warn alone is an error", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".preflight")
+		require.NoError(t, os.WriteFile(path, []byte("env HOME\nwarn\n"), 0o600))
+
+		_, err := ParseFile(path)
+		require.ErrorContains(t, err, "line 2")
+	})
+}
+
+func writePreflight(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".preflight")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestParseLines_SectionsAndTags(t *testing.T) {
+	path := writePreflight(t, `env HOME
+
+[database]
+tcp postgres:5432
+@slow	@network http http://postgres-exporter:9187/metrics
+
+[build]
+@slow warn cmd node --min 20
+`)
+
+	lines, err := ParseLines(path, Options{})
+	require.NoError(t, err)
+
+	want := []Line{
+		{File: path, Number: 1, Command: "preflight env HOME"},
+		{File: path, Number: 4, Command: "preflight tcp postgres:5432", Tags: []string{"database"}},
+		{File: path, Number: 5, Command: "preflight http http://postgres-exporter:9187/metrics", Tags: []string{"database", "slow", "network"}},
+		{File: path, Number: 8, Command: "preflight warn cmd node --min 20", Tags: []string{"build", "slow"}},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("ParseLines() =\n%#v\nwant\n%#v", lines, want)
+	}
+
+	// ParseFile keeps returning just the commands.
+	commands, err := ParseFile(path)
+	require.NoError(t, err)
+	if len(commands) != 4 || commands[3] != "preflight warn cmd node --min 20" {
+		t.Errorf("ParseFile() = %q", commands)
+	}
+}
+
+func TestParseLines_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"empty section", "[]\nenv HOME\n", "line 1: invalid section name"},
+		{"section with a space", "[data base]\n", "invalid section name"},
+		{"tag with a flag in it", "@--all env HOME\n", "line 1: invalid tag"},
+		{"tag alone", "env HOME\n@slow\n", "line 2: tags need a check"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLines(writePreflight(t, tt.content), Options{})
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+// With OnLineError a bad line is skipped and reading carries on, so every
+// mistake in a file, and in the files it includes, is found in one pass.
+func TestParseLines_OnLineError(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.preflight"), []byte("env 'HOME\n"), 0o600))
+	path := filepath.Join(dir, ".preflight")
+	require.NoError(t, os.WriteFile(path, []byte(`env HOME
+[data base]
+@--all env HOME
+include base.preflight
+include missing.preflight
+tcp db:5432
+warn
+`), 0o600))
+
+	var problems []*LineError
+	lines, err := ParseLines(path, Options{OnLineError: func(le *LineError) { problems = append(problems, le) }})
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	require.Equal(t, 6, lines[1].Number)
+
+	var got []string
+	for _, le := range problems {
+		got = append(got, filepath.Base(le.File)+":"+le.Error())
+	}
+	require.Equal(t, []string{
+		`.preflight:line 2: invalid section name "data base"`,
+		`.preflight:line 3: invalid tag "--all"`,
+		"base.preflight:line 1: unterminated single quote",
+		".preflight:line 5: include missing.preflight: failed to read preflight file: stat " + filepath.Join(dir, "missing.preflight") + ": no such file or directory",
+		".preflight:line 7: warn needs a check to run",
+	}, got)
+
+	t.Run("without it the first is the error", func(t *testing.T) {
+		_, err := ParseLines(path, Options{})
+		var le *LineError
+		require.ErrorAs(t, err, &le)
+		require.Equal(t, 2, le.Number)
+	})
+}
+
+func TestSelect(t *testing.T) {
+	lines := []Line{
+		{Number: 1, Command: "preflight env HOME"},
+		{Number: 2, Command: "preflight tcp postgres:5432", Tags: []string{"database"}},
+		{Number: 3, Command: "preflight http http://exporter/metrics", Tags: []string{"database", "slow"}},
+		{Number: 4, Command: "preflight cmd node", Tags: []string{"build"}},
+	}
+	numbers := func(ls []Line) []int {
+		var n []int
+		for _, l := range ls {
+			n = append(n, l.Number)
+		}
+		return n
+	}
+
+	tests := []struct {
+		name        string
+		tags, skips []string
+		want        []int
+	}{
+		{"no selection runs everything", nil, nil, []int{1, 2, 3, 4}},
+		{"a tag selects its lines", []string{"database"}, nil, []int{2, 3}},
+		{"several tags select any of them", []string{"database", "build"}, nil, []int{2, 3, 4}},
+		{"skip alone runs the rest", nil, []string{"slow"}, []int{1, 2, 4}},
+		{"skip wins over tag", []string{"database"}, []string{"slow"}, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Select(lines, tt.tags, tt.skips)
+			require.NoError(t, err)
+			if !reflect.DeepEqual(numbers(got), tt.want) {
+				t.Errorf("Select() = lines %v, want %v", numbers(got), tt.want)
+			}
+		})
+	}
+
+	// A typo would otherwise pass a run that checked nothing.
+	t.Run("an unknown tag is an error", func(t *testing.T) {
+		_, err := Select(lines, []string{"databse"}, nil)
+		require.ErrorContains(t, err, `no line is tagged "databse"`)
+		_, err = Select(lines, nil, []string{"slwo"})
+		require.ErrorContains(t, err, `"slwo"`)
+	})
+}
+
+func TestParseLines_Interpolation(t *testing.T) {
+	env := func(name string) (string, bool) {
+		if name == "DB_HOST" {
+			return "db.internal", true
+		}
+		return "", false
+	}
+
+	t.Run("the header opts in", func(t *testing.T) {
+		path := writePreflight(t, "#!/usr/bin/env preflight\n# preflight: interpolate\ntcp ${DB_HOST}:${DB_PORT:-5432}\n")
+		lines, err := ParseLines(path, Options{LookupEnv: env})
+		require.NoError(t, err)
+		require.Len(t, lines, 1)
+		if lines[0].Command != "preflight tcp db.internal:5432" {
+			t.Errorf("Command = %q", lines[0].Command)
+		}
+	})
+
+	t.Run("without it a file is left alone", func(t *testing.T) {
+		lines, err := ParseLines(writePreflight(t, "tcp ${DB_HOST}:5432\n"), Options{LookupEnv: env})
+		require.NoError(t, err)
+		if lines[0].Command != "preflight tcp ${DB_HOST}:5432" {
+			t.Errorf("Command = %q", lines[0].Command)
+		}
+	})
+
+	// The header is a declaration at the top, not something found halfway.
+	t.Run("the header after a command does not count", func(t *testing.T) {
+		lines, err := ParseLines(writePreflight(t, "env HOME\n# preflight: interpolate\ntcp ${DB_HOST}\n"), Options{LookupEnv: env})
+		require.NoError(t, err)
+		if lines[1].Command != "preflight tcp ${DB_HOST}" {
+			t.Errorf("Command = %q", lines[1].Command)
+		}
+	})
+
+	t.Run("vars and args come before the environment", func(t *testing.T) {
+		path := writePreflight(t, "warn env REGION --exact ${region}\nenv APP_ENV --exact ${1}\ntcp ${DB_HOST}:5432\n")
+		lines, err := ParseLines(path, Options{
+			Interpolate: true,
+			Vars:        map[string]string{"region": "eu", "DB_HOST": "override"},
+			Args:        []string{"staging"},
+			LookupEnv:   env,
+		})
+		require.NoError(t, err)
+		want := []string{"preflight warn env REGION --exact eu", "preflight env APP_ENV --exact staging", "preflight tcp override:5432"}
+		for i, l := range lines {
+			if l.Command != want[i] {
+				t.Errorf("line %d = %q, want %q", i, l.Command, want[i])
+			}
+		}
+	})
+
+	// A value with a space must survive the trip through the command string.
+	t.Run("a value keeps its spaces", func(t *testing.T) {
+		lines, err := ParseLines(writePreflight(t, "env G --exact ${greeting}\n"), Options{Interpolate: true, Vars: map[string]string{"greeting": "hello world"}})
+		require.NoError(t, err)
+		fields, err := Fields(lines[0].Command)
+		require.NoError(t, err)
+		if fields[len(fields)-1] != "hello world" {
+			t.Errorf("fields = %q", fields)
+		}
+	})
+
+	t.Run("an undefined variable fails the parse with its line", func(t *testing.T) {
+		_, err := ParseLines(writePreflight(t, "# preflight: interpolate\nenv HOME\ntcp ${DB_PORT_HOST}:5432\n"), Options{LookupEnv: env})
+		require.ErrorContains(t, err, "line 3: DB_PORT_HOST is not set")
+	})
+
+	t.Run("vars for a file that does not interpolate are an error", func(t *testing.T) {
+		_, err := ParseLines(writePreflight(t, "env HOME\n"), Options{Args: []string{"staging"}})
+		require.ErrorContains(t, err, "does not interpolate")
+	})
+}
+
+func TestParseLines_Include(t *testing.T) {
+	commands := func(lines []Line) []string {
+		var out []string
+		for _, l := range lines {
+			out = append(out, l.Command)
+		}
+		return out
+	}
+
+	t.Run("paths are relative to the including file", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "common"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "common", "base.preflight"), []byte("env HOME\ninclude more.preflight\n"), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "common", "more.preflight"), []byte("cmd sh\n"), 0o600))
+		path := filepath.Join(dir, ".preflight")
+		require.NoError(t, os.WriteFile(path, []byte("include common/base.preflight\ntcp db:5432\n"), 0o600))
+
+		lines, err := ParseLines(path, Options{})
+		require.NoError(t, err)
+		want := []string{"preflight env HOME", "preflight cmd sh", "preflight tcp db:5432"}
+		if !reflect.DeepEqual(commands(lines), want) {
+			t.Errorf("commands = %q, want %q", commands(lines), want)
+		}
+		if lines[1].File != filepath.Join(dir, "common", "more.preflight") || lines[1].Number != 1 {
+			t.Errorf("included line came from %s:%d", lines[1].File, lines[1].Number)
+		}
+	})
+
+	t.Run("included lines inherit the section and tags", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "db.preflight"), []byte("@slow tcp db:5432\n"), 0o600))
+		path := filepath.Join(dir, ".preflight")
+		require.NoError(t, os.WriteFile(path, []byte("[startup]\n@db include db.preflight\n"), 0o600))
+
+		lines, err := ParseLines(path, Options{})
+		require.NoError(t, err)
+		require.Len(t, lines, 1)
+		if want := []string{"startup", "db", "slow"}; !reflect.DeepEqual(lines[0].Tags, want) {
+			t.Errorf("Tags = %q, want %q", lines[0].Tags, want)
+		}
+	})
+
+	t.Run("a cycle is an error naming it", func(t *testing.T) {
+		dir := t.TempDir()
+		a, b := filepath.Join(dir, "a.preflight"), filepath.Join(dir, "b.preflight")
+		require.NoError(t, os.WriteFile(a, []byte("env HOME\ninclude b.preflight\n"), 0o600))
+		require.NoError(t, os.WriteFile(b, []byte("include a.preflight\n"), 0o600))
+
+		_, err := ParseLines(a, Options{})
+		require.ErrorContains(t, err, "line 2: include b.preflight: line 1: include a.preflight: include cycle: "+a+" -> "+b+" -> "+a)
+	})
+
+	t.Run("including the same file twice is not a cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "base.preflight"), []byte("env HOME\n"), 0o600))
+		path := filepath.Join(dir, ".preflight")
+		require.NoError(t, os.WriteFile(path, []byte("include base.preflight\ninclude base.preflight\n"), 0o600))
+
+		lines, err := ParseLines(path, Options{})
+		require.NoError(t, err)
+		require.Len(t, lines, 2)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := ParseLines(writePreflight(t, "env HOME\ninclude missing.preflight\n"), Options{})
+		require.ErrorContains(t, err, "line 2: include missing.preflight")
+
+		_, err = ParseLines(writePreflight(t, "include a b\n"), Options{})
+		require.ErrorContains(t, err, "line 1: include takes exactly one path")
+	})
+}
+
+func TestParseLines_DropInDir(t *testing.T) {
+	dir := t.TempDir()
+	dropIn := filepath.Join(dir, ".preflight.d")
+	require.NoError(t, os.MkdirAll(dropIn, 0o700))
+	for name, content := range map[string]string{
+		"20-service":       "tcp db:5432\n",
+		"10-base":          "env HOME\n",
+		".hidden":          "env HIDDEN\n",
+		"10-base~":         "env BACKUP\n",
+		"30-app.preflight": "cmd app\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dropIn, name), []byte(content), 0o600))
+	}
+
+	commands := func(lines []Line) []string {
+		var out []string
+		for _, l := range lines {
+			out = append(out, l.Command)
+		}
+		return out
+	}
+
+	t.Run("the directory alone runs in lexical order", func(t *testing.T) {
+		found, err := FindFile(dir, "")
+		require.NoError(t, err)
+		require.Equal(t, dropIn, found)
+
+		lines, err := ParseLines(found, Options{})
+		require.NoError(t, err)
+		want := []string{"preflight env HOME", "preflight tcp db:5432", "preflight cmd app"}
+		if !reflect.DeepEqual(commands(lines), want) {
+			t.Errorf("commands = %q, want %q", commands(lines), want)
+		}
+	})
+
+	t.Run("a .preflight beside it runs first", func(t *testing.T) {
+		path := filepath.Join(dir, ".preflight")
+		require.NoError(t, os.WriteFile(path, []byte("cmd git\n"), 0o600))
+
+		found, err := FindFile(dir, "")
+		require.NoError(t, err)
+		require.Equal(t, path, found)
+
+		lines, err := ParseLines(found, Options{})
+		require.NoError(t, err)
+		want := []string{"preflight cmd git", "preflight env HOME", "preflight tcp db:5432", "preflight cmd app"}
+		if !reflect.DeepEqual(commands(lines), want) {
+			t.Errorf("commands = %q, want %q", commands(lines), want)
+		}
+	})
+}
@@ -0,0 +1,252 @@
+// Package proccheck checks for running processes by reading /proc directly, so
+// a HEALTHCHECK no longer needs `ps aux | grep '[f]ilebeat'` and an image no
+// longer needs procps to run one.
+package proccheck
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/usercheck"
+)
+
+// maxListed bounds the matching processes named in a result's details.
+const maxListed = 10
+
+// Check verifies that processes matching its criteria are running.
+type Check struct {
+	Running   string               // --running: regex matched against the process name or its command line
+	PIDFile   string               // --pid-file: file holding the PID of a process that must be running
+	User      string               // --user: processes must run as this user, by name or uid
+	MinCount  *int                 // --min-count: at least this many must match (nil = 1, or 0 when MaxCount is 0)
+	MaxCount  *int                 // --max-count: at most this many may match (nil = no limit)
+	Listening int                  // --listening: a matching process must listen on this TCP port
+	Proc      ProcFS               // injected for testing
+	Users     usercheck.UserLookup // resolves a User given by name
+}
+
+// Run executes the process check.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. ctx is unused: the check
+// reads a few small files per process, which os gives no way to cancel.
+func (c *Check) RunContext(context.Context) check.Result {
+	result := check.Result{
+		Name: "proc: " + c.Subject(),
+	}
+
+	re, err := check.CompileRegex(c.Running)
+	if err != nil {
+		return result.Failf("invalid regex pattern: %v", err)
+	}
+	uid, err := c.uid()
+	if err != nil {
+		return result.Failf("user %s: %v", c.User, err)
+	}
+
+	var pids []int
+	if c.PIDFile != "" {
+		pid, err := readPIDFile(c.PIDFile)
+		if err != nil {
+			return result.Failf("%v", err)
+		}
+		p, err := readProcess(c.Proc, pid)
+		switch {
+		case err != nil && isNotExist(err):
+			return result.Failf("stale PID file %s: process %d is not running", c.PIDFile, pid)
+		case err != nil:
+			return result.Failf("failed to read process %d: %v", pid, err)
+		case p.zombie():
+			return result.Failf("stale PID file %s: process %d has exited", c.PIDFile, pid)
+		}
+		pids = []int{pid}
+	} else {
+		pids, err = listPIDs(c.Proc)
+		if err != nil {
+			return result.Failf("failed to list processes: %v", err)
+		}
+	}
+
+	// preflight's own command line, and that of the shell a HEALTHCHECK runs
+	// it in, hold the pattern too. They are why grep needed '[f]ilebeat'. A
+	// PID file names its process outright, so it is taken at its word.
+	var own map[int]bool
+	if c.PIDFile == "" {
+		own = c.ownProcesses()
+	}
+	var matched []process
+	for _, pid := range pids {
+		if own[pid] {
+			continue
+		}
+		p, err := readProcess(c.Proc, pid)
+		if err != nil || p.zombie() {
+			continue // exited since it was listed
+		}
+		if re != nil && !re.MatchString(p.comm) && !re.MatchString(strings.Join(p.cmdline, " ")) {
+			continue
+		}
+		if uid != "" && p.uid != uid {
+			continue
+		}
+		matched = append(matched, p)
+	}
+
+	unreadable := 0
+	if c.Listening > 0 {
+		inodes, err := listeningInodes(c.Proc, c.Listening)
+		if err != nil {
+			return result.Failf("failed to read TCP sockets: %v", err)
+		}
+		if len(inodes) == 0 {
+			return result.Failf("nothing is listening on port %d", c.Listening)
+		}
+		matched = slices.DeleteFunc(matched, func(p process) bool {
+			sockets, err := socketInodes(c.Proc, p.pid)
+			if err != nil {
+				if !isNotExist(err) {
+					unreadable++
+				}
+				return true
+			}
+			return !slices.ContainsFunc(sockets, func(inode string) bool { return inodes[inode] })
+		})
+	}
+
+	for i, p := range matched {
+		if i == maxListed {
+			result.AddDetailf("and %d more", len(matched)-maxListed)
+			break
+		}
+		result.AddDetail(p.String())
+	}
+
+	minCount, maxCount := c.bounds()
+	switch {
+	case len(matched) == 0 && minCount > 0:
+		result.Failf("no running process %s", c.criteria())
+		if unreadable > 0 {
+			result.AddDetailf("the sockets of %d processes could not be read; run as their user or as root", unreadable)
+		}
+		return result
+	case len(matched) < minCount:
+		return result.Failf("%d processes match, want at least %d", len(matched), minCount)
+	case maxCount >= 0 && len(matched) > maxCount:
+		return result.Failf("%d processes match, want at most %d", len(matched), maxCount)
+	}
+
+	result.Status = check.StatusOK
+	return result
+}
+
+// Subject names the check by the first criterion it was given, as its result
+// is named.
+func (c *Check) Subject() string {
+	switch {
+	case c.Running != "":
+		return c.Running
+	case c.PIDFile != "":
+		return c.PIDFile
+	case c.Listening > 0:
+		return "port " + strconv.Itoa(c.Listening)
+	}
+	return "user " + c.User
+}
+
+// criteria describes what a process has to be to match, for a failure.
+func (c *Check) criteria() string {
+	var parts []string
+	if c.Running != "" {
+		parts = append(parts, fmt.Sprintf("matches %q", c.Running))
+	}
+	if c.PIDFile != "" {
+		parts = append(parts, "is in "+c.PIDFile)
+	}
+	if c.User != "" {
+		parts = append(parts, "runs as "+c.User)
+	}
+	if c.Listening > 0 {
+		parts = append(parts, fmt.Sprintf("listens on port %d", c.Listening))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// bounds returns how many processes may match. maxCount is -1 for no limit.
+func (c *Check) bounds() (minCount, maxCount int) {
+	minCount, maxCount = 1, -1
+	if c.MaxCount != nil {
+		maxCount = *c.MaxCount
+		// --max-count 0 asserts that nothing is running.
+		minCount = min(minCount, maxCount)
+	}
+	if c.MinCount != nil {
+		minCount = *c.MinCount
+	}
+	return minCount, maxCount
+}
+
+// uid returns the uid User names, or "" when there is no User.
+func (c *Check) uid() (string, error) {
+	if c.User == "" {
+		return "", nil
+	}
+	if _, err := strconv.Atoi(c.User); err == nil {
+		return c.User, nil
+	}
+	u, err := c.Users.Lookup(c.User)
+	if err != nil {
+		return "", err
+	}
+	return u.Uid, nil
+}
+
+// ownProcesses returns the processes that are this check rather than what it
+// looks for, or nil when Proc is not the live /proc: under another root, such
+// as a host's /proc mounted into a container, this process's PID names some
+// other process.
+func (c *Check) ownProcesses() map[int]bool {
+	r, ok := c.Proc.(*RealProcFS)
+	if !ok || filepath.Clean(cmp.Or(r.Root, DefaultRoot)) != DefaultRoot {
+		return nil
+	}
+	return ownProcesses(c.Proc, os.Getpid())
+}
+
+// ownProcesses returns self and, when a shell runs it with -c as a HEALTHCHECK
+// does, that shell. Nothing further up is left out: tini, a supervisor or a
+// wrapper script above the check may be the very process it looks for.
+func ownProcesses(procFS ProcFS, self int) map[int]bool {
+	pids := map[int]bool{self: true}
+	p, err := readProcess(procFS, self)
+	if err != nil {
+		return pids
+	}
+	parent, err := readProcess(procFS, p.ppid)
+	if err == nil && parent.shellCommand() {
+		pids[parent.pid] = true
+	}
+	return pids
+}
+
+// readPIDFile returns the PID in path.
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // intentional: the PID file the user named
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PID file: %w", err)
+	}
+	content := strings.TrimSpace(string(data))
+	pid, err := strconv.ParseUint(content, 10, 31) // fits an int on 32-bit platforms too
+	if err != nil || pid == 0 {
+		return 0, fmt.Errorf("PID file %s holds %q, not a PID", path, content)
+	}
+	return int(pid), nil
+}
@@ -0,0 +1,331 @@
+package proccheck
+
+import (
+	"cmp"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// fixture is a process in a fixture tree.
+type fixture struct {
+	pid     int
+	ppid    int
+	comm    string
+	cmdline []string
+	uid     string
+	state   string   // default: S (sleeping)
+	sockets []string // inodes of the sockets it has open
+}
+
+// procTree lays processes out under a temporary directory the way the kernel
+// lays them out under /proc, with net/tcp holding tcp.
+func procTree(t *testing.T, tcp string, procs ...fixture) *RealProcFS {
+	t.Helper()
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	for _, p := range procs {
+		dir := strconv.Itoa(p.pid)
+		state, uid := cmp.Or(p.state, "S"), cmp.Or(p.uid, "0")
+		write(dir+"/status", "Name:\t"+p.comm+"\nState:\t"+state+" (sleeping)\nPPid:\t"+strconv.Itoa(p.ppid)+
+			"\nUid:\t"+uid+"\t"+uid+"\t"+uid+"\t"+uid+"\n")
+		write(dir+"/comm", p.comm+"\n")
+		cmdline := ""
+		for _, arg := range p.cmdline {
+			cmdline += arg + "\x00"
+		}
+		write(dir+"/cmdline", cmdline)
+		require.NoError(t, os.MkdirAll(filepath.Join(root, dir, "fd"), 0o700))
+		for i, inode := range p.sockets {
+			if runtime.GOOS == "windows" {
+				t.Skip("fixture sockets are symlinks")
+			}
+			require.NoError(t, os.Symlink("socket:["+inode+"]", filepath.Join(root, dir, "fd", strconv.Itoa(i+3))))
+		}
+	}
+	write("net/tcp", "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"+tcp)
+	// Not a process.
+	write("uptime", "1.00 2.00\n")
+	return &RealProcFS{Root: root}
+}
+
+func intPtr(n int) *int { return &n }
+
+type mockUserLookup struct{}
+
+func (mockUserLookup) Lookup(name string) (*user.User, error) {
+	if name == "www-data" {
+		return &user.User{Username: name, Uid: "33"}, nil
+	}
+	return nil, user.UnknownUserError(name)
+}
+
+// The usual tree: nginx with a master and two workers listening on port 80, a
+// controller whose name the kernel truncates, a zombie and a kernel thread.
+func standardTree(t *testing.T) *RealProcFS {
+	return procTree(t,
+		"   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 111 1 0000000000000000 100 0 0 10 0\n"+
+			"   1: 0100007F:1F90 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 222 1 0000000000000000 20 4 0 10 -1\n",
+		fixture{pid: 1, comm: "tini", cmdline: []string{"/tini", "--", "start.sh"}},
+		fixture{pid: 10, ppid: 1, comm: "nginx", cmdline: []string{"nginx: master process nginx -g daemon off;"}, sockets: []string{"111"}},
+		fixture{pid: 11, ppid: 10, comm: "nginx", cmdline: []string{"nginx: worker process"}, uid: "33", sockets: []string{"111"}},
+		fixture{pid: 12, ppid: 10, comm: "nginx", cmdline: []string{"nginx: worker process"}, uid: "33", sockets: []string{"111"}},
+		fixture{pid: 20, ppid: 1, comm: "kube-controller", cmdline: []string{"/usr/bin/kube-controller-manager", "--leader-elect"}, sockets: []string{"222"}},
+		fixture{pid: 30, ppid: 1, comm: "filebeat", state: "Z"},
+		fixture{pid: 40, comm: "kthreadd"},
+	)
+}
+
+func TestCheck_Run(t *testing.T) {
+	proc := standardTree(t)
+
+	tests := []struct {
+		name        string
+		check       Check
+		wantStatus  check.Status
+		wantName    string
+		wantDetails []string
+		wantErr     string
+	}{
+		{
+			name:        "running by name",
+			check:       Check{Running: "^nginx$"},
+			wantStatus:  check.StatusOK,
+			wantName:    "proc: ^nginx$",
+			wantDetails: []string{"pid 10: nginx: master process nginx -g daemon off;", "pid 11: nginx: worker process", "pid 12: nginx: worker process"},
+		},
+		{
+			name:        "running by command line, past the name the kernel truncates",
+			check:       Check{Running: "kube-controller-manager"},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"pid 20: /usr/bin/kube-controller-manager --leader-elect"},
+		},
+		{
+			name:        "a kernel thread has only its name",
+			check:       Check{Running: "kthreadd"},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"pid 40: [kthreadd]"},
+		},
+		{
+			name:       "not running",
+			check:      Check{Running: "redis"},
+			wantStatus: check.StatusFail,
+			wantErr:    `no running process matches "redis"`,
+		},
+		{
+			name:       "a zombie is not running",
+			check:      Check{Running: "filebeat"},
+			wantStatus: check.StatusFail,
+			wantErr:    `no running process matches "filebeat"`,
+		},
+		{
+			name:        "user by uid",
+			check:       Check{Running: "nginx", User: "33"},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"pid 11: nginx: worker process", "pid 12: nginx: worker process"},
+		},
+		{
+			name:       "user by name",
+			check:      Check{Running: "nginx: master", User: "www-data"},
+			wantStatus: check.StatusFail,
+			wantErr:    `no running process matches "nginx: master" and runs as www-data`,
+		},
+		{
+			name:       "unknown user",
+			check:      Check{Running: "nginx", User: "nobody-here"},
+			wantStatus: check.StatusFail,
+			wantErr:    "user nobody-here: user: unknown user nobody-here",
+		},
+		{
+			name:       "user alone",
+			check:      Check{User: "www-data"},
+			wantStatus: check.StatusOK,
+			wantName:   "proc: user www-data",
+		},
+		{
+			name:       "min count",
+			check:      Check{Running: "nginx", MinCount: intPtr(4)},
+			wantStatus: check.StatusFail,
+			wantErr:    "3 processes match, want at least 4",
+		},
+		{
+			name:       "max count",
+			check:      Check{Running: "nginx", MaxCount: intPtr(2)},
+			wantStatus: check.StatusFail,
+			wantErr:    "3 processes match, want at most 2",
+		},
+		{
+			name:       "max count 0 asserts nothing runs",
+			check:      Check{Running: "redis", MaxCount: intPtr(0)},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "max count 0 fails when something runs",
+			check:      Check{Running: "kube", MaxCount: intPtr(0)},
+			wantStatus: check.StatusFail,
+			wantErr:    "1 processes match, want at most 0",
+		},
+		{
+			name:        "listening ties the socket to its processes",
+			check:       Check{Running: "nginx", Listening: 80, User: "0"},
+			wantStatus:  check.StatusOK,
+			wantDetails: []string{"pid 10: nginx: master process nginx -g daemon off;"},
+		},
+		{
+			name:        "listening alone",
+			check:       Check{Listening: 80},
+			wantStatus:  check.StatusOK,
+			wantName:    "proc: port 80",
+			wantDetails: []string{"pid 10: nginx: master process nginx -g daemon off;", "pid 11: nginx: worker process", "pid 12: nginx: worker process"},
+		},
+		{
+			name:       "listening by another process",
+			check:      Check{Running: "kube", Listening: 80},
+			wantStatus: check.StatusFail,
+			wantErr:    `no running process matches "kube" and listens on port 80`,
+		},
+		{
+			name:       "a connection is not a listener",
+			check:      Check{Running: "kube", Listening: 8080},
+			wantStatus: check.StatusFail,
+			wantErr:    "nothing is listening on port 8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.check
+			c.Proc, c.Users = proc, mockUserLookup{}
+			result := c.Run()
+
+			assert.Equal(t, tt.wantStatus, result.Status, "details: %v", result.Details)
+			if tt.wantName != "" {
+				assert.Equal(t, tt.wantName, result.Name)
+			}
+			if tt.wantDetails != nil {
+				assert.Equal(t, tt.wantDetails, result.Details)
+			}
+			if tt.wantErr != "" {
+				require.Error(t, result.Err)
+				assert.Equal(t, tt.wantErr, result.Err.Error())
+			}
+		})
+	}
+}
+
+func TestCheck_PIDFile(t *testing.T) {
+	proc := standardTree(t)
+	pidFile := func(content string) string {
+		path := filepath.Join(t.TempDir(), "app.pid")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	tests := []struct {
+		name       string
+		content    string
+		running    string
+		wantStatus check.Status
+		wantErr    string
+	}{
+		{"alive", "10\n", "", check.StatusOK, ""},
+		{"alive and matching", "10", "nginx", check.StatusOK, ""},
+		{"alive as something else", "20", "nginx", check.StatusFail, `no running process matches "nginx" and is in `},
+		{"stale", "4242\n", "", check.StatusFail, "stale PID file "},
+		{"exited but not reaped", "30", "", check.StatusFail, "process 30 has exited"},
+		{"not a PID", "nginx\n", "", check.StatusFail, `holds "nginx", not a PID`},
+		{"negative", "-1", "", check.StatusFail, "not a PID"},
+		{"empty", "", "", check.StatusFail, "not a PID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := pidFile(tt.content)
+			c := &Check{PIDFile: path, Running: tt.running, Proc: proc}
+			result := c.Run()
+			assert.Equal(t, tt.wantStatus, result.Status, "details: %v", result.Details)
+			if tt.wantErr != "" {
+				require.Error(t, result.Err)
+				assert.Contains(t, result.Err.Error(), tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		c := &Check{PIDFile: filepath.Join(t.TempDir(), "absent.pid"), Proc: proc}
+		result := c.Run()
+		assert.Equal(t, check.StatusFail, result.Status)
+		assert.ErrorContains(t, result.Err, "failed to read PID file")
+	})
+}
+
+// preflight's own command line holds the pattern, and so does the shell a
+// HEALTHCHECK runs it from. Neither may count as the process it looks for, but
+// tini or a supervisor above them may be exactly that process.
+func TestOwnProcesses(t *testing.T) {
+	proc := procTree(t, "",
+		fixture{pid: 1, comm: "tini", cmdline: []string{"/sbin/tini", "--", "/app/run.sh"}},
+		fixture{pid: 20, ppid: 1, comm: "sh", cmdline: []string{"/bin/sh", "-c", "preflight proc --running tini"}},
+		fixture{pid: 21, ppid: 20, comm: "preflight", cmdline: []string{"preflight", "proc", "--running", "tini"}},
+		fixture{pid: 30, ppid: 1, comm: "run.sh", cmdline: []string{"/bin/sh", "/app/run.sh"}},
+		fixture{pid: 31, ppid: 30, comm: "preflight", cmdline: []string{"preflight", "proc", "--running", "run.sh"}},
+	)
+
+	assert.Equal(t, map[int]bool{21: true, 20: true}, ownProcesses(proc, 21), "the sh -c parent is left out, tini is not")
+	assert.Equal(t, map[int]bool{31: true}, ownProcesses(proc, 31), "a wrapper script is not a sh -c parent")
+	assert.Equal(t, map[int]bool{99: true}, ownProcesses(proc, 99), "unreadable self")
+}
+
+// Under a root other than /proc, this process's PID names another process.
+func TestCheck_OtherRootExcludesNothing(t *testing.T) {
+	self := os.Getpid()
+	proc := procTree(t, "",
+		fixture{pid: self, ppid: 1, comm: "filebeat", cmdline: []string{"filebeat", "-e"}},
+	)
+
+	c := &Check{Running: "filebeat", Proc: proc}
+	result := c.Run()
+	assert.Equal(t, check.StatusOK, result.Status)
+	assert.Equal(t, []string{"pid " + strconv.Itoa(self) + ": filebeat -e"}, result.Details)
+}
+
+func TestCheck_LiveProcSkipsItself(t *testing.T) {
+	if _, err := os.Stat(DefaultRoot + "/self/status"); err != nil {
+		t.Skip("no /proc on this system")
+	}
+	// The test binary's own command line is the only one holding this.
+	pattern := "^" + regexp.QuoteMeta(strings.Join(os.Args, " ")) + "$"
+	c := &Check{Running: pattern, MaxCount: intPtr(0), Proc: &RealProcFS{}}
+	result := c.Run()
+	assert.Equal(t, check.StatusOK, result.Status, "details: %v", result.Details)
+}
+
+func TestRealProcFS(t *testing.T) {
+	if _, err := os.Stat(DefaultRoot + "/self/status"); err != nil {
+		t.Skip("no /proc on this system")
+	}
+	c := &Check{PIDFile: writePID(t, os.Getpid()), Proc: &RealProcFS{}}
+	result := c.Run()
+	assert.Equal(t, check.StatusOK, result.Status, "details: %v", result.Details)
+}
+
+func writePID(t *testing.T, pid int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "self.pid")
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(pid)+"\n"), 0o600))
+	return path
+}
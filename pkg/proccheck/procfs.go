@@ -0,0 +1,214 @@
+package proccheck
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// DefaultRoot is where the kernel mounts the process filesystem.
+const DefaultRoot = "/proc"
+
+// ProcFS reads the process filesystem. Names are relative to its root, such as
+// "1/comm" or "net/tcp".
+type ProcFS interface {
+	ReadDir(name string) ([]fs.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+	Readlink(name string) (string, error)
+}
+
+// RealProcFS reads the process filesystem mounted at Root, DefaultRoot when
+// empty. Pointing Root at a directory laid out like /proc is how tests, or a
+// container inspecting its host's /proc mounted elsewhere, use a tree of
+// their own.
+type RealProcFS struct {
+	Root string
+}
+
+func (r *RealProcFS) path(name string) string {
+	root := r.Root
+	if root == "" {
+		root = DefaultRoot
+	}
+	return filepath.Join(root, filepath.FromSlash(name))
+}
+
+func (r *RealProcFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(r.path(name))
+}
+
+func (r *RealProcFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(r.path(name))
+}
+
+func (r *RealProcFS) Readlink(name string) (string, error) {
+	return os.Readlink(r.path(name))
+}
+
+// process is what the check needs to know about one process.
+type process struct {
+	pid     int
+	ppid    int
+	comm    string   // the kernel's name for it, at most 15 bytes
+	cmdline []string // empty for a kernel thread
+	uid     string   // effective
+	state   byte     // R, S, Z and so on
+}
+
+// String describes p the way ps would list it.
+func (p process) String() string {
+	if len(p.cmdline) == 0 {
+		return fmt.Sprintf("pid %d: [%s]", p.pid, p.comm)
+	}
+	return fmt.Sprintf("pid %d: %s", p.pid, strings.Join(p.cmdline, " "))
+}
+
+// shells run a command line given with -c, the way Docker runs a HEALTHCHECK.
+var shells = []string{"sh", "ash", "bash", "dash", "ksh", "zsh"}
+
+// shellCommand reports whether p is a shell running a command line given with
+// -c.
+func (p process) shellCommand() bool {
+	return len(p.cmdline) > 1 && slices.Contains(shells, filepath.Base(p.cmdline[0])) && slices.Contains(p.cmdline[1:], "-c")
+}
+
+// zombie reports whether p has exited and only waits to be reaped.
+func (p process) zombie() bool {
+	return p.state == 'Z' || p.state == 'X'
+}
+
+// listPIDs returns the PID of every process in procFS.
+func listPIDs(procFS ProcFS) ([]int, error) {
+	entries, err := procFS.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil && pid > 0 {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// readProcess reads the process pid from procFS. An error wrapping
+// fs.ErrNotExist means there is no such process, which for a process listed a
+// moment ago means it has since exited.
+func readProcess(procFS ProcFS, pid int) (process, error) {
+	p := process{pid: pid}
+	dir := strconv.Itoa(pid)
+
+	status, err := procFS.ReadFile(dir + "/status")
+	if err != nil {
+		return p, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), ":")
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		switch key {
+		case "Name":
+			p.comm = strings.TrimSpace(value)
+		case "State":
+			p.state = fields[0][0]
+		case "PPid":
+			p.ppid, _ = strconv.Atoi(fields[0])
+		case "Uid":
+			// Real, effective, saved and filesystem; ps shows the effective one.
+			if len(fields) > 1 {
+				p.uid = fields[1]
+			}
+		}
+	}
+
+	// comm is exact where status escapes some characters; status still names
+	// the process if comm cannot be read.
+	if comm, err := procFS.ReadFile(dir + "/comm"); err == nil {
+		p.comm = strings.TrimSuffix(string(comm), "\n")
+	}
+	// The arguments are NUL-terminated. A process may hide them, and reading
+	// another user's can be denied, so their absence is not an error.
+	if cmdline, err := procFS.ReadFile(dir + "/cmdline"); err == nil {
+		cmdline = bytes.TrimRight(cmdline, "\x00")
+		if len(cmdline) > 0 {
+			p.cmdline = strings.Split(string(cmdline), "\x00")
+		}
+	}
+	return p, nil
+}
+
+// tcpListenState is TCP_LISTEN in the st column of /proc/net/tcp.
+const tcpListenState = "0A"
+
+// listeningInodes returns the inodes of the TCP sockets listening on port, over
+// IPv4 and IPv6. A kernel without IPv6 has no net/tcp6, which is no error.
+func listeningInodes(procFS ProcFS, port int) (map[string]bool, error) {
+	inodes := map[string]bool{}
+	for _, name := range []string{"net/tcp", "net/tcp6"} {
+		data, err := procFS.ReadFile(name)
+		if err != nil {
+			if name == "net/tcp6" && isNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Scan() // the header
+		for scanner.Scan() {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != tcpListenState {
+				continue
+			}
+			_, hexPort, ok := strings.Cut(fields[1], ":")
+			if !ok {
+				continue
+			}
+			if p, err := strconv.ParseUint(hexPort, 16, 16); err == nil && int(p) == port {
+				inodes[fields[9]] = true
+			}
+		}
+	}
+	return inodes, nil
+}
+
+// socketInodes returns the inodes of the sockets pid has open. Another user's
+// file descriptors cannot be read without privileges; that error is returned
+// for the caller to weigh.
+func socketInodes(procFS ProcFS, pid int) ([]string, error) {
+	dir := strconv.Itoa(pid) + "/fd"
+	entries, err := procFS.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var inodes []string
+	for _, e := range entries {
+		target, err := procFS.Readlink(dir + "/" + e.Name())
+		if err != nil {
+			continue // closed since the directory was read
+		}
+		if inode, ok := strings.CutPrefix(target, "socket:["); ok {
+			inodes = append(inodes, strings.TrimSuffix(inode, "]"))
+		}
+	}
+	return inodes, nil
+}
+
+// isNotExist reports whether err means a file is not there. Reading from a
+// process that has just exited can also fail with ESRCH, which os does not map
+// to fs.ErrNotExist.
+func isNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ESRCH)
+}
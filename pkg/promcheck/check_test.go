@@ -11,6 +11,7 @@ import (
 
 	"github.com/vertti/preflight/pkg/check"
 	"github.com/vertti/preflight/pkg/httpclient"
+	"github.com/vertti/preflight/pkg/retry"
 	"github.com/vertti/preflight/pkg/testutil"
 )
 
@@ -99,7 +100,7 @@ func TestPrometheusCheck(t *testing.T) {
 func TestPrometheusCheckRetry(t *testing.T) {
 	t.Run("succeeds after retries", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://prom:9090", Query: "up", Retry: 2, RetryDelay: 1, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://prom:9090", Query: "up", Retry: retry.Policy{Retries: 2, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			if attempts < 3 {
 				return nil, errors.New("connection refused")
@@ -113,7 +114,7 @@ func TestPrometheusCheckRetry(t *testing.T) {
 
 	t.Run("fails after max retries", func(t *testing.T) {
 		attempts := 0
-		c := Check{URL: "http://prom:9090", Query: "up", Retry: 2, RetryDelay: 1, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		c := Check{URL: "http://prom:9090", Query: "up", Retry: retry.Policy{Retries: 2, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
 			attempts++
 			return testutil.MockResponse(500, ""), nil
 		}}}
@@ -124,14 +125,14 @@ func TestPrometheusCheckRetry(t *testing.T) {
 	})
 
 	t.Run("connection failure exhausts retries", func(t *testing.T) {
-		c := Check{URL: "http://prom:9090", Query: "up", Retry: 1, RetryDelay: 1, Client: &testutil.MockHTTPClient{DoFunc: respErr("connection refused")}}
+		c := Check{URL: "http://prom:9090", Query: "up", Retry: retry.Policy{Retries: 1, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: respErr("connection refused")}}
 		result := c.Run()
 		assert.Equal(t, check.StatusFail, result.Status)
 		assert.True(t, testutil.ContainsDetail(result.Details, "after 2 attempts"))
 	})
 
 	t.Run("empty result exhausts retries", func(t *testing.T) {
-		c := Check{URL: "http://prom:9090", Query: "up", Retry: 1, RetryDelay: 1, Client: &testutil.MockHTTPClient{DoFunc: resp(promEmptyResult)}}
+		c := Check{URL: "http://prom:9090", Query: "up", Retry: retry.Policy{Retries: 1, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: resp(promEmptyResult)}}
 		result := c.Run()
 		assert.Equal(t, check.StatusFail, result.Status)
 		assert.True(t, testutil.ContainsDetail(result.Details, "after 2 attempts"))
@@ -142,9 +143,9 @@ func TestPrometheusCheckRetry(t *testing.T) {
 			name  string
 			check Check
 		}{
-			{"exact", Check{URL: "http://prom:9090", Query: "up", Exact: testutil.Ptr(0.0), Retry: 1, RetryDelay: 1, Client: &testutil.MockHTTPClient{DoFunc: resp(promSuccessVector)}}},
-			{"min", Check{URL: "http://prom:9090", Query: "up", Min: testutil.Ptr(10.0), Retry: 1, RetryDelay: 1, Client: &testutil.MockHTTPClient{DoFunc: resp(promSuccessVector)}}},
-			{"max", Check{URL: "http://prom:9090", Query: "up", Max: testutil.Ptr(0.5), Retry: 1, RetryDelay: 1, Client: &testutil.MockHTTPClient{DoFunc: resp(promSuccessVector)}}},
+			{"exact", Check{URL: "http://prom:9090", Query: "up", Exact: testutil.Ptr(0.0), Retry: retry.Policy{Retries: 1, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: resp(promSuccessVector)}}},
+			{"min", Check{URL: "http://prom:9090", Query: "up", Min: testutil.Ptr(10.0), Retry: retry.Policy{Retries: 1, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: resp(promSuccessVector)}}},
+			{"max", Check{URL: "http://prom:9090", Query: "up", Max: testutil.Ptr(0.5), Retry: retry.Policy{Retries: 1, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: resp(promSuccessVector)}}},
 		} {
 			t.Run(tc.name, func(t *testing.T) {
 				result := tc.check.Run()
@@ -189,3 +190,16 @@ func TestRealHTTPClient_DoesNotFollowRedirects(t *testing.T) {
 	assert.Nil(t, leaked, "credentials must not reach the redirect destination")
 	assert.Equal(t, check.StatusFail, result.Status, "a 302 is not a successful query")
 }
+
+// An error about the query itself will not go away by asking again.
+func TestPrometheusCheckRetry_QueryErrorIsNotRetried(t *testing.T) {
+	attempts := 0
+	c := Check{URL: "http://prom:9090", Query: "up{", Retry: retry.Policy{Retries: 3, Delay: 1}, Client: &testutil.MockHTTPClient{DoFunc: func(*http.Request) (*http.Response, error) {
+		attempts++
+		return testutil.MockResponse(200, `{"status":"error","error":"parse error"}`), nil
+	}}}
+	result := c.Run()
+	assert.Equal(t, check.StatusFail, result.Status)
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, []string{"prometheus error: parse error"}, result.Details)
+}
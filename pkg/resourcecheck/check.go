@@ -1,6 +1,8 @@
 package resourcecheck
 
 import (
+	"context"
+
 	"github.com/vertti/preflight/pkg/check"
 )
 
@@ -15,6 +17,12 @@ type Check struct {
 
 // Run executes the resource check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. Resource figures come
+// from the kernel at once, so ctx is unused.
+func (c *Check) RunContext(context.Context) check.Result {
 	result := check.Result{
 		Name: "resource",
 	}
@@ -0,0 +1,187 @@
+// Package retry reruns a failing check until it passes or a policy is spent.
+//
+// The http and prometheus checks used to carry a copy each of the same loop,
+// and nothing else could retry at all, so waiting for a socket file to appear
+// or a port to open still took a shell loop around preflight.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// DefaultDelay is the pause before the first retry when Policy.Delay is unset.
+const DefaultDelay = time.Second
+
+// Clock abstracts time for testability.
+type Clock interface {
+	Now() time.Time
+	// Sleep pauses for d, or until ctx is done, in which case it returns
+	// ctx's error.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// RealClock uses the real time package.
+type RealClock struct{}
+
+// Now returns the current time.
+func (RealClock) Now() time.Time { return time.Now() }
+
+// Sleep pauses the current goroutine for d, or until ctx is done.
+func (RealClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Policy describes how often, and for how long, a failing check is tried again.
+// The zero Policy makes a single attempt.
+//
+// Retries and Wait bound a run independently and whichever is spent first ends
+// it. Wait alone keeps trying for as long as it allows, which is what "wait for
+// the database" means; Retries alone is a fixed number of tries.
+type Policy struct {
+	Retries  int           // attempts after the first
+	Delay    time.Duration // pause before the first retry (default: 1s)
+	Wait     time.Duration // keep retrying until this much time has passed
+	Backoff  float64       // multiply the delay by this after every retry; 0 or 1 keeps it constant
+	MaxDelay time.Duration // cap on any single pause; 0 means no cap
+	Jitter   float64       // spread each pause randomly by up to this fraction of it, 0 to 1
+	Clock    Clock         // injected for testing
+}
+
+// Enabled reports whether p allows more than one attempt.
+func (p Policy) Enabled() bool {
+	return p.Retries > 0 || p.Wait > 0
+}
+
+// Do calls attempt until it passes or the policy is spent, and returns the
+// last result. attempt says, alongside its result, whether a failure is worth
+// trying again; a misconfigured check fails the same way every time, and
+// waiting out a policy on it only delays the report.
+//
+// A failure after more than one attempt says how many were made, and a pass
+// after a retry says which attempt it was.
+//
+// Once ctx is done no further attempt is made, and a pause in progress is cut
+// short: the last result is returned as it stands.
+func (p Policy) Do(ctx context.Context, attempt func() (result check.Result, retryable bool)) check.Result {
+	clock := p.Clock
+	if clock == nil {
+		clock = RealClock{}
+	}
+	var deadline time.Time
+	if p.Wait > 0 {
+		deadline = clock.Now().Add(p.Wait)
+	}
+	delay := p.Delay
+	if delay <= 0 {
+		delay = DefaultDelay
+	}
+
+	for n := 1; ; n++ {
+		result, retryable := attempt()
+		if result.OK() {
+			if n > 1 {
+				p.noteSuccess(&result, n)
+			}
+			return result
+		}
+
+		if !retryable || ctx.Err() != nil || (p.Retries > 0 && n > p.Retries) || (p.Wait == 0 && p.Retries == 0) {
+			return result.NoteAttempts(n)
+		}
+		pause := p.jitter(delay)
+		if p.Wait > 0 {
+			remaining := deadline.Sub(clock.Now())
+			if remaining <= 0 {
+				return result.NoteAttempts(n)
+			}
+			// One last try right at the deadline is worth more than sleeping
+			// past it and giving up.
+			pause = min(pause, remaining)
+		}
+		if err := clock.Sleep(ctx, pause); err != nil {
+			return result.NoteAttempts(n)
+		}
+		delay = p.next(delay)
+	}
+}
+
+// noteSuccess records which attempt passed. The total is only known when the
+// number of retries, not the clock, is what bounds the run.
+func (p Policy) noteSuccess(result *check.Result, n int) {
+	if p.Wait == 0 {
+		result.AddDetailf("succeeded on attempt %d of %d", n, p.Retries+1)
+		return
+	}
+	result.AddDetailf("succeeded on attempt %d", n)
+}
+
+// next returns the delay that follows d.
+func (p Policy) next(d time.Duration) time.Duration {
+	if p.Backoff > 1 {
+		d = time.Duration(float64(d) * p.Backoff)
+	}
+	if p.MaxDelay > 0 {
+		d = min(d, p.MaxDelay)
+	}
+	return d
+}
+
+// jitter spreads d by up to p.Jitter of itself in either direction, so a fleet
+// of containers started together does not retry in lockstep.
+func (p Policy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	spread := float64(d) * min(p.Jitter, 1)
+	return d + time.Duration((rand.Float64()*2-1)*spread) //nolint:gosec // jitter needs no cryptographic randomness
+}
+
+// Check reruns any check under a policy. Every failure counts as worth trying
+// again, because a wrapped check has no way to say otherwise; a check that can
+// tell the difference implements Retrier instead.
+type Check struct {
+	Checker check.Checker
+	Policy  Policy
+}
+
+// Run executes the wrapped check until it passes or the policy is spent.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run with ctx passed to every attempt and every pause.
+func (c *Check) RunContext(ctx context.Context) check.Result {
+	return c.Policy.Do(ctx, func() (check.Result, bool) {
+		return c.Checker.RunContext(ctx), true
+	})
+}
+
+// Retrier is implemented by checks that apply a policy themselves, so that a
+// failure no retry can fix — a bad URL, a malformed query — is reported at once.
+type Retrier interface {
+	check.Checker
+	WithRetry(p Policy) check.Checker
+}
+
+// Wrap applies p to c: a Retrier is handed the policy, any other check is
+// rerun whole. A policy that allows only one attempt leaves c as it is.
+func Wrap(c check.Checker, p Policy) check.Checker {
+	if r, ok := c.(Retrier); ok {
+		return r.WithRetry(p)
+	}
+	if !p.Enabled() {
+		return c
+	}
+	return &Check{Checker: c, Policy: p}
+}
@@ -0,0 +1,201 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// fakeClock advances only when slept on, and remembers every pause.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+// failingUntil returns an attempt func that fails until its pass'th call.
+func failingUntil(pass int, calls *int) func() (check.Result, bool) {
+	return func() (check.Result, bool) {
+		*calls++
+		r := check.Result{Name: "tcp: db:5432"}
+		if *calls >= pass {
+			r.Status = check.StatusOK
+			r.AddDetail("connected")
+			return r, true
+		}
+		return r.Failf("connection refused"), true
+	}
+}
+
+func TestPolicyDo(t *testing.T) {
+	t.Run("zero policy makes one attempt", func(t *testing.T) {
+		calls := 0
+		clock := &fakeClock{}
+		result := Policy{Clock: clock}.Do(t.Context(), failingUntil(2, &calls))
+		assert.Equal(t, check.StatusFail, result.Status)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, []string{"connection refused"}, result.Details)
+		assert.Empty(t, clock.sleeps)
+	})
+
+	t.Run("retries until a pass", func(t *testing.T) {
+		calls := 0
+		clock := &fakeClock{}
+		result := Policy{Retries: 5, Delay: time.Second, Clock: clock}.Do(t.Context(), failingUntil(3, &calls))
+		assert.Equal(t, check.StatusOK, result.Status)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, []string{"connected", "succeeded on attempt 3 of 6"}, result.Details)
+		assert.Equal(t, []time.Duration{time.Second, time.Second}, clock.sleeps)
+	})
+
+	t.Run("a spent policy reports the count", func(t *testing.T) {
+		calls := 0
+		result := Policy{Retries: 2, Delay: time.Millisecond, Clock: &fakeClock{}}.Do(t.Context(), failingUntil(10, &calls))
+		assert.Equal(t, check.StatusFail, result.Status)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, []string{"connection refused (after 3 attempts)"}, result.Details)
+		require.Error(t, result.Err)
+		assert.Equal(t, "connection refused (after 3 attempts)", result.Err.Error())
+	})
+
+	t.Run("a failure that is not retryable ends the run", func(t *testing.T) {
+		calls := 0
+		result := Policy{Retries: 5, Clock: &fakeClock{}}.Do(t.Context(), func() (check.Result, bool) {
+			calls++
+			r := check.Result{Name: "http"}
+			return r.Failf("invalid URL"), false
+		})
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, []string{"invalid URL"}, result.Details)
+	})
+
+	t.Run("defaults to a one second delay", func(t *testing.T) {
+		calls := 0
+		clock := &fakeClock{}
+		Policy{Retries: 1, Clock: clock}.Do(t.Context(), failingUntil(10, &calls))
+		assert.Equal(t, []time.Duration{DefaultDelay}, clock.sleeps)
+	})
+
+	t.Run("backoff grows the delay up to the cap", func(t *testing.T) {
+		calls := 0
+		clock := &fakeClock{}
+		Policy{Retries: 5, Delay: time.Second, Backoff: 2, MaxDelay: 5 * time.Second, Clock: clock}.Do(t.Context(), failingUntil(10, &calls))
+		assert.Equal(t, []time.Duration{
+			time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second,
+		}, clock.sleeps)
+	})
+}
+
+func TestPolicyDo_Wait(t *testing.T) {
+	t.Run("keeps trying until the time is up", func(t *testing.T) {
+		calls := 0
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		result := Policy{Wait: 10 * time.Second, Delay: 3 * time.Second, Clock: clock}.Do(t.Context(), failingUntil(100, &calls))
+		assert.Equal(t, check.StatusFail, result.Status)
+		// Tries at 0s, 3s, 6s, 9s, and once more right at the deadline.
+		assert.Equal(t, 5, calls)
+		assert.Equal(t, []time.Duration{3 * time.Second, 3 * time.Second, 3 * time.Second, time.Second}, clock.sleeps)
+		assert.Equal(t, []string{"connection refused (after 5 attempts)"}, result.Details)
+	})
+
+	t.Run("a pass does not claim a total", func(t *testing.T) {
+		calls := 0
+		result := Policy{Wait: time.Minute, Clock: &fakeClock{}}.Do(t.Context(), failingUntil(2, &calls))
+		assert.Equal(t, check.StatusOK, result.Status)
+		assert.Equal(t, []string{"connected", "succeeded on attempt 2"}, result.Details)
+	})
+
+	t.Run("retries bound the run even when time remains", func(t *testing.T) {
+		calls := 0
+		Policy{Wait: time.Hour, Retries: 2, Clock: &fakeClock{}}.Do(t.Context(), failingUntil(100, &calls))
+		assert.Equal(t, 3, calls)
+	})
+}
+
+func TestPolicyDo_Cancelled(t *testing.T) {
+	t.Run("no attempt after cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		calls := 0
+		result := Policy{Retries: 5, Clock: &fakeClock{}}.Do(ctx, func() (check.Result, bool) {
+			calls++
+			cancel()
+			r := check.Result{Name: "tcp: db:5432"}
+			return r.Failf("connection failed: operation was canceled"), true
+		})
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, check.StatusFail, result.Status)
+	})
+
+	t.Run("a pause is cut short", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		calls := 0
+		start := time.Now()
+		result := Policy{Wait: time.Hour, Delay: time.Hour}.Do(ctx, failingUntil(100, &calls))
+		assert.Less(t, time.Since(start), time.Minute)
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, check.StatusFail, result.Status)
+	})
+}
+
+func TestPolicyJitter(t *testing.T) {
+	p := Policy{Jitter: 0.5}
+	for range 100 {
+		d := p.jitter(time.Second)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, 1500*time.Millisecond)
+	}
+	assert.Equal(t, time.Second, Policy{}.jitter(time.Second))
+}
+
+type countingChecker struct{ calls int }
+
+func (c *countingChecker) RunContext(context.Context) check.Result {
+	c.calls++
+	r := check.Result{Name: "file: /run/app.sock"}
+	return r.Failf("not found")
+}
+
+type selfRetrying struct {
+	countingChecker
+	policy Policy
+}
+
+func (s *selfRetrying) WithRetry(p Policy) check.Checker {
+	s.policy = p
+	return s
+}
+
+func TestWrap(t *testing.T) {
+	t.Run("any check is rerun whole", func(t *testing.T) {
+		inner := &countingChecker{}
+		c := Wrap(inner, Policy{Retries: 2, Delay: time.Millisecond, Clock: &fakeClock{}})
+		result := c.RunContext(t.Context())
+		assert.Equal(t, 3, inner.calls)
+		assert.Equal(t, []string{"not found (after 3 attempts)"}, result.Details)
+	})
+
+	t.Run("a single attempt leaves the check alone", func(t *testing.T) {
+		inner := &countingChecker{}
+		assert.Same(t, inner, Wrap(inner, Policy{}))
+	})
+
+	t.Run("a Retrier is handed the policy", func(t *testing.T) {
+		inner := &selfRetrying{}
+		p := Policy{Retries: 4}
+		assert.Same(t, inner, Wrap(inner, p))
+		assert.Equal(t, p, inner.policy)
+	})
+}
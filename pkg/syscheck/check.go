@@ -1,6 +1,7 @@
 package syscheck
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 
@@ -26,7 +27,14 @@ type Check struct {
 	Info         SysInfo // injected for testing
 }
 
+// Run executes the system check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface; comparing the build's OS
+// and architecture cannot block, so ctx is unused.
+func (c *Check) RunContext(context.Context) check.Result {
 	result := check.Result{
 		Name: "sys",
 	}
@@ -1,6 +1,7 @@
 package tcpcheck
 
 import (
+	"context"
 	"net"
 	"time"
 
@@ -9,15 +10,17 @@ import (
 
 // TCPDialer abstracts network dialing for testability.
 type TCPDialer interface {
-	DialTimeout(network, address string, timeout time.Duration) (net.Conn, error)
+	DialContext(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error)
 }
 
 // RealTCPDialer uses the real net package.
 type RealTCPDialer struct{}
 
-// DialTimeout dials the network address with a timeout.
-func (d *RealTCPDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
-	return net.DialTimeout(network, address, timeout)
+// DialContext dials the network address with a timeout, giving up early if
+// ctx is done first.
+func (d *RealTCPDialer) DialContext(ctx context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	return dialer.DialContext(ctx, network, address)
 }
 
 // Check verifies TCP connectivity to a host:port.
@@ -29,6 +32,11 @@ type Check struct {
 
 // Run executes the TCP connectivity check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run with the connection attempt abandoned once ctx is done.
+func (c *Check) RunContext(ctx context.Context) check.Result {
 	result := check.Result{
 		Name: "tcp: " + c.Address,
 	}
@@ -38,7 +46,7 @@ func (c *Check) Run() check.Result {
 		timeout = 5 * time.Second
 	}
 
-	conn, err := c.Dialer.DialTimeout("tcp", c.Address, timeout)
+	conn, err := c.Dialer.DialContext(ctx, "tcp", c.Address, timeout)
 	if err != nil {
 		return result.Failf("connection failed: %v", err)
 	}
@@ -1,6 +1,7 @@
 package tcpcheck
 
 import (
+	"context"
 	"errors"
 	"net"
 	"testing"
@@ -15,7 +16,7 @@ type mockTCPDialer struct {
 	DialFunc func(network, address string, timeout time.Duration) (net.Conn, error)
 }
 
-func (m *mockTCPDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+func (m *mockTCPDialer) DialContext(_ context.Context, network, address string, timeout time.Duration) (net.Conn, error) {
 	return m.DialFunc(network, address, timeout)
 }
 
@@ -118,3 +119,14 @@ func TestTCPCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestTCPCheck_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	c := &Check{Address: "127.0.0.1:1", Timeout: time.Minute, Dialer: &RealTCPDialer{}}
+	result := c.RunContext(ctx)
+
+	assert.Equal(t, check.StatusFail, result.Status)
+	assert.ErrorContains(t, result.Err, "canceled")
+}
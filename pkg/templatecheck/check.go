@@ -0,0 +1,161 @@
+package templatecheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/vertti/preflight/pkg/check"
+)
+
+// Check renders a text/template file from the environment to a destination.
+type Check struct {
+	Source string      // template file
+	Dest   string      // where the rendered file is written
+	Mode   fs.FileMode // --mode: permissions of the written file
+	Env    Environment // injected for testing
+	FS     FileSystem  // injected for testing
+}
+
+// Data is what a template sees as dot: {{ .Env.NAME }} is a variable's value,
+// or empty when it is not set.
+type Data struct {
+	Env map[string]string
+}
+
+// Run executes the template check.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. Rendering is local and
+// quick, so ctx is unused.
+func (c *Check) RunContext(context.Context) check.Result {
+	result := check.Result{
+		Name: "template: " + c.Source,
+	}
+
+	text, err := c.FS.ReadFile(c.Source)
+	if err != nil {
+		return result.Failf("failed to read template: %v", err)
+	}
+
+	env := environMap(c.Env.Environ())
+	r := &renderer{env: env}
+	// Without missingkey=zero, an unset .Env.NAME would print "<no value>", and
+	// could not be piped into default at all.
+	tmpl, err := template.New(filepath.Base(c.Source)).
+		Option("missingkey=zero").
+		Funcs(r.funcs()).
+		Parse(string(text))
+	if err != nil {
+		return result.Failf("invalid template: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, Data{Env: env}); err != nil {
+		return result.Failf("failed to render: %v", err)
+	}
+
+	// Every missing variable is reported at once, so a misconfigured container
+	// is fixed in one round rather than one variable per restart. Nothing is
+	// written: a config with a hole in it would only fail later, and less clearly.
+	if len(r.missing) > 0 {
+		errs := make([]error, 0, len(r.missing))
+		for _, name := range r.missing {
+			if _, set := env[name]; set {
+				result.AddDetailf("%s: empty value", name)
+				errs = append(errs, fmt.Errorf("environment variable %s is empty", name))
+			} else {
+				result.AddDetailf("%s: not set", name)
+				errs = append(errs, fmt.Errorf("environment variable %s is not set", name))
+			}
+		}
+		return result.Fail(c.Dest+" not written", errors.Join(errs...))
+	}
+
+	if err := c.FS.WriteFile(c.Dest, out.Bytes(), c.Mode); err != nil {
+		return result.Failf("failed to write %s: %v", c.Dest, err)
+	}
+
+	result.Status = check.StatusOK
+	result.AddDetailf("wrote %s (%d bytes)", c.Dest, out.Len())
+	return result
+}
+
+// renderer holds the state of one rendering that its template functions share.
+type renderer struct {
+	env     map[string]string
+	missing []string // required variables unset or empty, in order of first use
+}
+
+func (r *renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"env":      func(name string) string { return r.env[name] },
+		"required": r.required,
+		"default":  defaultValue,
+		"split":    split,
+		"toJSON":   toJSON,
+		"b64":      func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	}
+}
+
+// required returns a variable's value, noting it as missing when it is unset or
+// empty — the same line envcheck draws without --allow-empty. Rendering goes on,
+// so that every missing variable is found in one pass.
+func (r *renderer) required(name string) string {
+	value := r.env[name]
+	if value == "" && !slices.Contains(r.missing, name) {
+		r.missing = append(r.missing, name)
+	}
+	return value
+}
+
+// defaultValue returns value, or fallback when value is empty. The fallback
+// comes first so that it reads well at the end of a pipeline:
+// {{ .Env.PORT | default "8080" }}.
+func defaultValue(fallback, value string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// split splits s around sep, for ranging over a list in one variable:
+// {{ range split "," .Env.HOSTS }}. An empty s has no elements, not one empty one.
+func split(sep, s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, sep)
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// environMap turns KEY=value pairs into a map. On Windows, os.Environ holds
+// entries like "=C:=C:\dir" that name no variable, and are skipped.
+func environMap(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			continue
+		}
+		env[name] = value
+	}
+	return env
+}
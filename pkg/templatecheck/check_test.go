@@ -0,0 +1,118 @@
+package templatecheck
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/testutil"
+)
+
+type mockFS struct {
+	Content  string
+	ReadErr  error
+	WriteErr error
+
+	written map[string]string
+	perm    fs.FileMode
+}
+
+func (m *mockFS) ReadFile(string) ([]byte, error) {
+	if m.ReadErr != nil {
+		return nil, m.ReadErr
+	}
+	return []byte(m.Content), nil
+}
+
+func (m *mockFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if m.WriteErr != nil {
+		return m.WriteErr
+	}
+	if m.written == nil {
+		m.written = map[string]string{}
+	}
+	m.written[name] = string(data)
+	m.perm = perm
+	return nil
+}
+
+type mockEnv []string
+
+func (m mockEnv) Environ() []string { return m }
+
+func render(t *testing.T, tmpl string, env ...string) (check.Result, *mockFS) {
+	t.Helper()
+	fsys := &mockFS{Content: tmpl}
+	c := &Check{Source: "app.conf.tmpl", Dest: "app.conf", Mode: 0o640, Env: mockEnv(env), FS: fsys}
+	return c.Run(), fsys
+}
+
+func TestTemplateCheck_Render(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		env  []string
+		want string
+	}{
+		{"env field", "host={{ .Env.DB_HOST }}", []string{"DB_HOST=db"}, "host=db"},
+		{"unset field is empty", "host={{ .Env.DB_HOST }}", nil, "host="},
+		{"env function", `{{ env "DB_HOST" }}`, []string{"DB_HOST=db"}, "db"},
+		{"value with equals sign", "{{ .Env.OPTS }}", []string{"OPTS=a=b"}, "a=b"},
+		{"default used", `{{ .Env.PORT | default "8080" }}`, nil, "8080"},
+		{"default for empty", `{{ .Env.PORT | default "8080" }}`, []string{"PORT="}, "8080"},
+		{"default not used", `{{ .Env.PORT | default "8080" }}`, []string{"PORT=9090"}, "9090"},
+		{"required set", `{{ required "DB_HOST" }}`, []string{"DB_HOST=db"}, "db"},
+		{"split", `{{ range split "," .Env.HOSTS }}[{{ . }}]{{ end }}`, []string{"HOSTS=a,b,c"}, "[a][b][c]"},
+		{"split empty has no elements", `{{ range split "," .Env.HOSTS }}[{{ . }}]{{ end }}`, nil, ""},
+		{"toJSON string", `{{ .Env.NAME | toJSON }}`, []string{`NAME=say "hi"`}, `"say \"hi\""`},
+		{"toJSON list", `{{ split "," .Env.HOSTS | toJSON }}`, []string{"HOSTS=a,b"}, `["a","b"]`},
+		{"b64", `{{ b64 .Env.SECRET }}`, []string{"SECRET=hunter2"}, "aHVudGVyMg=="},
+		{"entries without a name are skipped", "{{ len .Env }}", []string{"=C:=C:\\dir", "A=1"}, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, fsys := render(t, tt.tmpl, tt.env...)
+			require.Equal(t, check.StatusOK, result.Status, result.Details)
+			assert.Equal(t, tt.want, fsys.written["app.conf"])
+			assert.Equal(t, fs.FileMode(0o640), fsys.perm)
+		})
+	}
+}
+
+func TestTemplateCheck_RequiredRefusesToWrite(t *testing.T) {
+	tmpl := `{{ required "DB_URL" }} {{ required "API_KEY" }} {{ required "DB_URL" }} {{ required "EMPTY" }}`
+	result, fsys := render(t, tmpl, "API_KEY=x", "EMPTY=")
+
+	assert.Equal(t, check.StatusFail, result.Status)
+	assert.Equal(t, []string{"DB_URL: not set", "EMPTY: empty value", "app.conf not written"}, result.Details)
+	assert.EqualError(t, result.Err, "environment variable DB_URL is not set\nenvironment variable EMPTY is empty")
+	assert.Empty(t, fsys.written)
+}
+
+func TestTemplateCheck_Failures(t *testing.T) {
+	tests := []struct {
+		name       string
+		fsys       *mockFS
+		wantDetail string
+	}{
+		{"unreadable template", &mockFS{ReadErr: errors.New("permission denied")}, "failed to read template: permission denied"},
+		{"invalid template", &mockFS{Content: "{{ .Env.X "}, "invalid template"},
+		{"unknown function", &mockFS{Content: `{{ upper "x" }}`}, `function "upper" not defined`},
+		{"render error", &mockFS{Content: `{{ index .Env.X 5 }}`}, "failed to render"},
+		{"write error", &mockFS{Content: "x", WriteErr: errors.New("read-only file system")}, "failed to write app.conf: read-only file system"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Check{Source: "app.conf.tmpl", Dest: "app.conf", Env: mockEnv{"X=ab"}, FS: tt.fsys}
+			result := c.Run()
+			assert.Equal(t, check.StatusFail, result.Status)
+			assert.True(t, testutil.ContainsDetail(result.Details, tt.wantDetail), "details %v should contain %q", result.Details, tt.wantDetail)
+		})
+	}
+}
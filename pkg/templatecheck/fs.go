@@ -0,0 +1,55 @@
+package templatecheck
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileSystem abstracts file operations for testing.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// RealFileSystem implements FileSystem using the real file system.
+type RealFileSystem struct{}
+
+// ReadFile reads the entire file contents.
+func (r *RealFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name) //nolint:gosec // intentional: file path from user config
+}
+
+// WriteFile replaces name with data by renaming a complete file over it, so the
+// program the file is rendered for never reads half of one.
+func (r *RealFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Environment provides the variables a template can read.
+type Environment interface {
+	Environ() []string
+}
+
+// RealEnvironment uses the process environment.
+type RealEnvironment struct{}
+
+func (r *RealEnvironment) Environ() []string {
+	return os.Environ()
+}
@@ -0,0 +1,242 @@
+// Package tlscheck dials an endpoint and completes a TLS handshake, then
+// checks what was negotiated and the certificate the server served: the
+// protocol version, the ALPN protocol, the chain, the names and the days left.
+// It answers what `openssl s_client` is usually run for.
+package tlscheck
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/vertti/preflight/pkg/certcheck"
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/tcpcheck"
+)
+
+// versions maps the names --min-version takes to protocol versions.
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// rejectionWait is how long a TLS 1.3 handshake with a client certificate
+// waits for the server to reject it.
+const rejectionWait = 250 * time.Millisecond
+
+// ParseVersion returns the protocol version named by s: 1.0, 1.1, 1.2 or 1.3.
+func ParseVersion(s string) (uint16, error) {
+	v, ok := versions[strings.TrimPrefix(strings.ToLower(s), "tls")]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q: expected 1.0, 1.1, 1.2 or 1.3", s)
+	}
+	return v, nil
+}
+
+// Check verifies a TLS handshake with host:port.
+type Check struct {
+	Address        string             // host:port to connect to
+	SNI            string             // --sni: server name to send (default: the host in Address)
+	MinVersion     uint16             // --min-version: lowest protocol version to accept, such as tls.VersionTLS12
+	ALPN           []string           // --alpn: protocols to offer; the server must pick one of them
+	CAFile         string             // --ca-file: roots to trust instead of the system's
+	ClientCert     string             // --client-cert: certificate to present for mTLS
+	ClientKey      string             // --client-key: private key of ClientCert
+	MinDays        int                // --min-days: served certificate must stay valid at least this many more days
+	ExpectHostname string             // --expect-hostname: served certificate must be valid for this name (default: the server name)
+	Insecure       bool               // --insecure: skip verifying the chain and the name
+	Timeout        time.Duration      // connection and handshake timeout (default 5s)
+	Dialer         tcpcheck.TCPDialer // injected for testing
+	FS             FileSystem         // injected for testing
+}
+
+// Run executes the TLS handshake check.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run with the connection and handshake abandoned once ctx is
+// done.
+func (c *Check) RunContext(ctx context.Context) check.Result {
+	result := check.Result{
+		Name: "tls: " + c.Address,
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	config, err := c.config()
+	if err != nil {
+		return result.Failf("%v", err)
+	}
+	roots, err := c.roots()
+	if err != nil {
+		return result.Failf("%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := c.Dialer.DialContext(ctx, "tcp", c.Address, timeout)
+	if err != nil {
+		return result.Failf("connection failed: %v", err)
+	}
+	tlsConn := tls.Client(conn, config)
+	defer func() { _ = tlsConn.Close() }()
+
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return result.Failf("handshake failed: %v", err)
+	}
+	state := tlsConn.ConnectionState()
+	if len(config.Certificates) > 0 && state.Version >= tls.VersionTLS13 {
+		if err := awaitRejection(tlsConn); err != nil {
+			return result.Failf("server rejected the client certificate: %v", err)
+		}
+	}
+
+	result.AddDetailf("protocol: %s", tls.VersionName(state.Version))
+	result.AddDetailf("cipher: %s", tls.CipherSuiteName(state.CipherSuite))
+	if state.NegotiatedProtocol != "" {
+		result.AddDetailf("ALPN: %s", state.NegotiatedProtocol)
+	}
+	leaf := state.PeerCertificates[0]
+	now := time.Now()
+	for _, detail := range certcheck.Details(leaf, now) {
+		result.AddDetail(detail)
+	}
+
+	if c.MinVersion != 0 && state.Version < c.MinVersion {
+		return result.Failf("negotiated %s, want at least %s", tls.VersionName(state.Version), tls.VersionName(c.MinVersion))
+	}
+	if len(c.ALPN) > 0 && state.NegotiatedProtocol == "" {
+		return result.Failf("server negotiated no ALPN protocol, want one of %s", strings.Join(c.ALPN, ", "))
+	}
+
+	if !c.Insecure {
+		if err := c.verify(state.PeerCertificates, roots, now); err != nil {
+			return result.Failf("%v", err)
+		}
+		result.AddDetailf("verified for: %s", c.hostname())
+	}
+	if c.MinDays > 0 {
+		if err := certcheck.CheckValidity(leaf, now, c.MinDays); err != nil {
+			return result.Failf("%v", err)
+		}
+	}
+
+	result.Status = check.StatusOK
+	return result
+}
+
+// config returns the handshake's configuration. The served certificate is
+// verified after the handshake rather than during it, so that a check that
+// fails verification still lists what the server sent.
+func (c *Check) config() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: c.serverName(),
+		NextProtos: c.ALPN,
+		// The lowest version Go speaks: an old server should fail on
+		// MinVersion with the version it offered, not on a protocol alert.
+		MinVersion:         tls.VersionTLS10,
+		InsecureSkipVerify: true, //nolint:gosec // verified after the handshake
+	}
+	if c.ClientCert != "" || c.ClientKey != "" {
+		certPEM, err := c.FS.ReadFile(c.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate: %w", err)
+		}
+		keyPEM, err := c.FS.ReadFile(c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key: %w", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// awaitRejection waits briefly for the server to reject the client's
+// certificate. In TLS 1.3 the client's handshake is done before the server
+// has checked it, so a rejection only arrives as an alert afterwards.
+func awaitRejection(conn *tls.Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(rejectionWait)); err != nil {
+		return err
+	}
+	_, err := conn.Read(make([]byte, 1))
+	var netErr net.Error
+	if err == nil || errors.As(err, &netErr) && netErr.Timeout() {
+		return nil
+	}
+	return err
+}
+
+// roots returns the certificates in CAFile, or nil for the system's roots.
+func (c *Check) roots() (*x509.CertPool, error) {
+	if c.CAFile == "" {
+		return nil, nil
+	}
+	data, err := c.FS.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no CERTIFICATE block in PEM file", c.CAFile)
+	}
+	return roots, nil
+}
+
+// verify checks that the served certificates chain to roots, the first of them
+// as the leaf and the rest as intermediates, and that the leaf is valid for
+// the expected hostname.
+func (c *Check) verify(certs []*x509.Certificate, roots *x509.CertPool, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       c.hostname(),
+		CurrentTime:   now,
+	})
+	var hostErr x509.HostnameError
+	if errors.As(err, &hostErr) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("chain does not verify: %w", err)
+	}
+	return nil
+}
+
+// serverName returns the name sent in the handshake: SNI, or the host in
+// Address. Go sends no SNI when it is an IP address.
+func (c *Check) serverName() string {
+	if c.SNI != "" {
+		return c.SNI
+	}
+	host, _, err := net.SplitHostPort(c.Address)
+	if err != nil {
+		return c.Address
+	}
+	return host
+}
+
+// hostname returns the name the served certificate must be valid for.
+func (c *Check) hostname() string {
+	return cmp.Or(c.ExpectHostname, c.serverName())
+}
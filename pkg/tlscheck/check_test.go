@@ -0,0 +1,320 @@
+package tlscheck
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/tcpcheck"
+	"github.com/vertti/preflight/pkg/testutil"
+)
+
+type mockFS map[string][]byte
+
+func (m mockFS) ReadFile(name string) ([]byte, error) {
+	if data, ok := m[name]; ok {
+		return data, nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+// serverDialer dials server whatever address it is asked for, so a check can
+// name the host its certificate is for.
+type serverDialer struct {
+	server *httptest.Server
+}
+
+func (d *serverDialer) DialContext(ctx context.Context, network, _ string, timeout time.Duration) (net.Conn, error) {
+	return (&tcpcheck.RealTCPDialer{}).DialContext(ctx, network, d.server.Listener.Addr().String(), timeout)
+}
+
+type failingDialer struct{}
+
+func (failingDialer) DialContext(context.Context, string, string, time.Duration) (net.Conn, error) {
+	return nil, errors.New("connection refused")
+}
+
+// newServer starts a TLS server. httptest's certificate is for example.com
+// and 127.0.0.1, and valid until 2084.
+func newServer(t *testing.T, configure func(*httptest.Server)) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	// Handshakes the tests mean to fail would be logged as errors.
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	if configure != nil {
+		configure(server)
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func certPEM(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// issued is a certificate with its key.
+type issued struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue signs a certificate for template with parent's key, or its own when
+// parent is nil.
+func issue(t *testing.T, template *x509.Certificate, parent *issued) *issued {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, key.Public(), signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &issued{cert: cert, key: key}
+}
+
+func keyPEM(t *testing.T, c *issued) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(c.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestCheck_Run(t *testing.T) {
+	server := newServer(t, nil)
+	tls12 := newServer(t, func(s *httptest.Server) {
+		s.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	})
+	h2 := newServer(t, func(s *httptest.Server) {
+		s.EnableHTTP2 = true
+	})
+	files := mockFS{"ca.crt": certPEM(server.Certificate())}
+
+	tests := []struct {
+		name       string
+		server     *httptest.Server
+		check      Check
+		wantStatus check.Status
+		wantDetail string
+	}{
+		{
+			name:       "verified with CA file",
+			check:      Check{Address: "example.com:443", CAFile: "ca.crt"},
+			wantStatus: check.StatusOK,
+			wantDetail: "verified for: example.com",
+		},
+		{
+			name:       "verified by IP address",
+			check:      Check{Address: "127.0.0.1:443", CAFile: "ca.crt"},
+			wantStatus: check.StatusOK,
+			wantDetail: "verified for: 127.0.0.1",
+		},
+		{
+			name:       "untrusted by system roots",
+			check:      Check{Address: "example.com:443"},
+			wantStatus: check.StatusFail,
+			wantDetail: "chain does not verify",
+		},
+		{
+			name:       "insecure skips verification",
+			check:      Check{Address: "example.com:443", Insecure: true},
+			wantStatus: check.StatusOK,
+			wantDetail: "subject: O=Acme Co",
+		},
+		{
+			name:       "SNI names the host",
+			check:      Check{Address: "10.0.0.1:443", SNI: "example.com", CAFile: "ca.crt"},
+			wantStatus: check.StatusOK,
+			wantDetail: "verified for: example.com",
+		},
+		{
+			name:       "expected hostname matches",
+			check:      Check{Address: "127.0.0.1:443", ExpectHostname: "example.com", CAFile: "ca.crt"},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "expected hostname does not match",
+			check:      Check{Address: "127.0.0.1:443", ExpectHostname: "api.example.org", CAFile: "ca.crt"},
+			wantStatus: check.StatusFail,
+			wantDetail: "not api.example.org",
+		},
+		{
+			name:       "enough days left",
+			check:      Check{Address: "example.com:443", CAFile: "ca.crt", MinDays: 30},
+			wantStatus: check.StatusOK,
+		},
+		{
+			name:       "too few days left",
+			check:      Check{Address: "example.com:443", CAFile: "ca.crt", MinDays: 100000},
+			wantStatus: check.StatusFail,
+			wantDetail: "want at least 100000",
+		},
+		{
+			name:       "minimum version met",
+			check:      Check{Address: "example.com:443", Insecure: true, MinVersion: tls.VersionTLS13},
+			wantStatus: check.StatusOK,
+			wantDetail: "protocol: TLS 1.3",
+		},
+		{
+			name:       "minimum version not met",
+			server:     tls12,
+			check:      Check{Address: "example.com:443", Insecure: true, MinVersion: tls.VersionTLS13},
+			wantStatus: check.StatusFail,
+			wantDetail: "negotiated TLS 1.2, want at least TLS 1.3",
+		},
+		{
+			name:       "ALPN negotiated",
+			server:     h2,
+			check:      Check{Address: "example.com:443", Insecure: true, ALPN: []string{"h2", "http/1.1"}},
+			wantStatus: check.StatusOK,
+			wantDetail: "ALPN: h2",
+		},
+		{
+			name:       "ALPN not supported",
+			check:      Check{Address: "example.com:443", Insecure: true, ALPN: []string{"h2"}},
+			wantStatus: check.StatusFail,
+			wantDetail: "handshake failed",
+		},
+		{
+			name:       "missing CA file",
+			check:      Check{Address: "example.com:443", CAFile: "missing.crt"},
+			wantStatus: check.StatusFail,
+			wantDetail: "failed to read CA file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.server
+			if target == nil {
+				target = server
+			}
+			c := tt.check
+			c.Dialer = &serverDialer{server: target}
+			c.FS = files
+
+			result := c.Run()
+
+			assert.Equal(t, tt.wantStatus, result.Status, "details: %v, err: %v", result.Details, result.Err)
+			assert.Equal(t, "tls: "+c.Address, result.Name)
+			if tt.wantDetail != "" {
+				assert.True(t, testutil.ContainsDetail(result.Details, tt.wantDetail), "details: %v", result.Details)
+			}
+		})
+	}
+}
+
+func TestCheck_ClientCertificate(t *testing.T) {
+	ca := issue(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Example Client CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+	clientTemplate := func() *x509.Certificate {
+		return &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "client"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+	}
+	trusted := issue(t, clientTemplate(), ca)
+	untrusted := issue(t, clientTemplate(), nil)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server := newServer(t, func(s *httptest.Server) {
+		s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	})
+	files := mockFS{
+		"trusted.crt":   certPEM(trusted.cert),
+		"trusted.key":   keyPEM(t, trusted),
+		"untrusted.crt": certPEM(untrusted.cert),
+		"untrusted.key": keyPEM(t, untrusted),
+	}
+
+	for _, version := range []uint16{tls.VersionTLS12, tls.VersionTLS13} {
+		server.TLS.MaxVersion = version
+		t.Run(tls.VersionName(version), func(t *testing.T) {
+			run := func(cert, key string) check.Result {
+				c := &Check{
+					Address:    "example.com:443",
+					Insecure:   true,
+					ClientCert: cert,
+					ClientKey:  key,
+					Dialer:     &serverDialer{server: server},
+					FS:         files,
+				}
+				return c.Run()
+			}
+
+			result := run("trusted.crt", "trusted.key")
+			assert.Equal(t, check.StatusOK, result.Status, "err: %v", result.Err)
+
+			result = run("untrusted.crt", "untrusted.key")
+			assert.Equal(t, check.StatusFail, result.Status)
+
+			result = run("trusted.crt", "untrusted.key")
+			assert.Equal(t, check.StatusFail, result.Status)
+			assert.True(t, testutil.ContainsDetail(result.Details, "invalid client certificate"), "details: %v", result.Details)
+		})
+	}
+}
+
+func TestCheck_DialFailure(t *testing.T) {
+	c := &Check{Address: "db:5432", Dialer: failingDialer{}, FS: mockFS{}}
+
+	result := c.Run()
+
+	assert.Equal(t, check.StatusFail, result.Status)
+	assert.True(t, testutil.ContainsDetail(result.Details, "connection failed: connection refused"))
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint16
+		wantErr bool
+	}{
+		{"1.2", tls.VersionTLS12, false},
+		{"1.3", tls.VersionTLS13, false},
+		{"TLS1.0", tls.VersionTLS10, false},
+		{"1.4", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseVersion(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
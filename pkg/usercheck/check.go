@@ -1,6 +1,7 @@
 package usercheck
 
 import (
+	"context"
 	"fmt"
 	"os/user"
 
@@ -20,6 +21,21 @@ func (r *RealUserLookup) Lookup(username string) (*user.User, error) {
 	return user.Lookup(username)
 }
 
+// LookupID looks up a user by numeric ID.
+func (r *RealUserLookup) LookupID(uid string) (*user.User, error) {
+	return user.LookupId(uid)
+}
+
+// LookupGroup looks up a group by name.
+func (r *RealUserLookup) LookupGroup(name string) (*user.Group, error) {
+	return user.LookupGroup(name)
+}
+
+// GroupIDs lists the IDs of the groups u is a member of.
+func (r *RealUserLookup) GroupIDs(u *user.User) ([]string, error) {
+	return u.GroupIds()
+}
+
 // Check verifies a user exists and optionally validates uid/gid/home.
 type Check struct {
 	Username string     // username to check
@@ -31,6 +47,12 @@ type Check struct {
 
 // Run executes the user check.
 func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. os/user lookups cannot be
+// cancelled, so ctx is unused.
+func (c *Check) RunContext(context.Context) check.Result {
 	result := check.Result{
 		Name: "user: " + c.Username,
 	}
@@ -0,0 +1,79 @@
+package version
+
+import (
+	"fmt"
+
+	semver "github.com/Masterminds/semver/v3"
+)
+
+// Constraints are the requirements a check can place on a version it found.
+// The zero value accepts any version.
+type Constraints struct {
+	Min   *Version // minimum version required (inclusive)
+	Max   *Version // maximum version allowed (exclusive)
+	Exact *Version // exact version required
+	Range string   // semver constraint (e.g., ">=1.0, <2.0", "~>1.5", "^1.0.0")
+}
+
+// IsZero reports whether c places no requirement at all.
+func (c Constraints) IsZero() bool {
+	return c.Min == nil && c.Max == nil && c.Exact == nil && c.Range == ""
+}
+
+// ConstraintError is the first of a Constraints that a version does not meet.
+type ConstraintError struct {
+	Detail string // short form for a check result's details, e.g. "version 1.0.0 < minimum 2.0.0"
+	Err    error  // the full sentence
+}
+
+func (e *ConstraintError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
+
+// Check returns the first requirement of c that v does not meet, or nil. An
+// invalid Range is reported the same way, since no version can meet it.
+func (c Constraints) Check(v Version) *ConstraintError {
+	if c.Exact != nil && v.Compare(*c.Exact) != 0 {
+		return &ConstraintError{
+			Detail: fmt.Sprintf("version %s != required %s", v, c.Exact),
+			Err:    fmt.Errorf("version %s does not match required %s", v, c.Exact),
+		}
+	}
+	if c.Min != nil && !v.GreaterThanOrEqual(*c.Min) {
+		return &ConstraintError{
+			Detail: fmt.Sprintf("version %s < minimum %s", v, c.Min),
+			Err:    fmt.Errorf("version %s below minimum %s", v, c.Min),
+		}
+	}
+	if c.Max != nil && !v.LessThan(*c.Max) {
+		return &ConstraintError{
+			Detail: fmt.Sprintf("version %s >= maximum %s", v, c.Max),
+			Err:    fmt.Errorf("version %s at or above maximum %s", v, c.Max),
+		}
+	}
+
+	if c.Range == "" {
+		return nil
+	}
+	constraint, err := semver.NewConstraint(c.Range)
+	if err != nil {
+		err = fmt.Errorf("invalid version range %q: %w", c.Range, err)
+		return &ConstraintError{Detail: err.Error(), Err: err}
+	}
+	sv, err := semver.NewVersion(v.String())
+	if err != nil {
+		err = fmt.Errorf("could not convert version to semver: %w", err)
+		return &ConstraintError{Detail: err.Error(), Err: err}
+	}
+	if !constraint.Check(sv) {
+		return &ConstraintError{
+			Detail: fmt.Sprintf("version %s does not satisfy %q", v, c.Range),
+			Err:    fmt.Errorf("version %s does not satisfy constraint %q", v, c.Range),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package version
+
+import "testing"
+
+func TestConstraints_Check(t *testing.T) {
+	v := func(major, minor, patch int) *Version {
+		return &Version{major, minor, patch}
+	}
+
+	tests := []struct {
+		name       string
+		c          Constraints
+		version    Version
+		wantDetail string // empty: the version meets c
+	}{
+		{"zero accepts anything", Constraints{}, Version{0, 0, 1}, ""},
+		{"min inclusive", Constraints{Min: v(1, 2, 0)}, Version{1, 2, 0}, ""},
+		{"below min", Constraints{Min: v(2, 0, 0)}, Version{1, 9, 9}, "version 1.9.9 < minimum 2.0.0"},
+		{"max exclusive", Constraints{Max: v(2, 0, 0)}, Version{2, 0, 0}, "version 2.0.0 >= maximum 2.0.0"},
+		{"exact", Constraints{Exact: v(1, 2, 3)}, Version{1, 2, 3}, ""},
+		{"not exact", Constraints{Exact: v(1, 2, 3)}, Version{1, 2, 4}, "version 1.2.4 != required 1.2.3"},
+		{"range", Constraints{Range: ">=1.0, <2.0"}, Version{1, 5, 0}, ""},
+		{"outside range", Constraints{Range: "^1.0"}, Version{2, 0, 0}, `version 2.0.0 does not satisfy "^1.0"`},
+		{"invalid range", Constraints{Range: "one point oh"}, Version{1, 0, 0}, `invalid version range "one point oh": improper constraint: "one point oh"`},
+		{"exact is checked first", Constraints{Min: v(3, 0, 0), Exact: v(1, 0, 0)}, Version{2, 0, 0}, "version 2.0.0 != required 1.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.c.Check(tt.version)
+			switch {
+			case tt.wantDetail == "" && err != nil:
+				t.Errorf("Check(%v) = %q, want nil", tt.version, err.Detail)
+			case tt.wantDetail != "" && err == nil:
+				t.Errorf("Check(%v) = nil, want %q", tt.version, tt.wantDetail)
+			case err != nil && err.Detail != tt.wantDetail:
+				t.Errorf("Check(%v).Detail = %q, want %q", tt.version, err.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
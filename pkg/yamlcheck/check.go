@@ -0,0 +1,211 @@
+// Package yamlcheck checks that a YAML file parses and asserts on its keys,
+// as jsoncheck does for JSON. A file may hold a stream of documents separated
+// by ---, as Kubernetes manifests do; every one of them must parse, and the
+// key assertions apply to one document or to each.
+package yamlcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/jsonpath"
+)
+
+// Check verifies that a YAML file is valid and optionally checks key/value assertions.
+type Check struct {
+	File         string     // path to YAML file
+	HasKey       string     // --has-key: check key exists (dot notation)
+	Key          string     // --key: key to check value of
+	Exact        *string    // --exact: expected exact value (nil = flag not given, so "" is assertable)
+	Match        string     // --match: regex pattern for value (requires --key)
+	Document     *int       // --document: check keys in this document of the stream, from 0 (nil = flag not given)
+	AllDocuments bool       // --all-documents: check keys in every document of the stream
+	FS           FileSystem // injected for testing
+}
+
+// Run executes the YAML check.
+func (c *Check) Run() check.Result {
+	return c.RunContext(context.Background())
+}
+
+// RunContext is Run for the check.Checker interface. ctx is unused: the file
+// is read and parsed in one go.
+func (c *Check) RunContext(context.Context) check.Result {
+	result := check.Result{
+		Name: "yaml: " + c.File,
+	}
+
+	content, err := c.FS.ReadFile(c.File)
+	if err != nil {
+		return result.Failf("failed to read file: %v", err)
+	}
+
+	docs, err := decodeAll(content)
+	if err != nil {
+		return result.Failf("invalid YAML: %v", err)
+	}
+	if len(docs) == 1 {
+		result.AddDetail("syntax: valid")
+	} else {
+		result.AddDetailf("syntax: valid (%d documents)", len(docs))
+	}
+
+	indices, err := c.documents(len(docs))
+	if err != nil {
+		return result.Failf("%v", err)
+	}
+	for _, i := range indices {
+		// Which document a detail is about only needs saying when there is
+		// more than one.
+		prefix := ""
+		if len(docs) > 1 {
+			prefix = fmt.Sprintf("document %d: ", i)
+		}
+		if err := c.checkKeys(&result, docs[i], prefix); err != nil {
+			return result.Failf("%s%v", prefix, err)
+		}
+	}
+
+	result.Status = check.StatusOK
+	return result
+}
+
+// documents returns the indices of the documents the key assertions apply to,
+// out of n.
+func (c *Check) documents(n int) ([]int, error) {
+	if c.Document != nil {
+		if *c.Document < 0 || *c.Document >= n {
+			return nil, fmt.Errorf("document %d not found: file has %d documents", *c.Document, n)
+		}
+		return []int{*c.Document}, nil
+	}
+	if c.HasKey == "" && c.Key == "" {
+		return nil, nil
+	}
+	if c.AllDocuments {
+		indices := make([]int, n)
+		for i := range indices {
+			indices[i] = i
+		}
+		return indices, nil
+	}
+	switch n {
+	case 0:
+		return nil, errors.New("file has no documents")
+	case 1:
+		return []int{0}, nil
+	}
+	// Quietly checking the first would pass a Deployment whose Service lacks
+	// the key, or the other way round.
+	return nil, fmt.Errorf("file has %d documents: choose one with --document or check each with --all-documents", n)
+}
+
+// checkKeys runs the --has-key and --key assertions against one document,
+// given as JSON.
+func (c *Check) checkKeys(result *check.Result, doc, prefix string) error {
+	if c.HasKey != "" {
+		if !jsonpath.Get(doc, c.HasKey).Exists() {
+			return fmt.Errorf("key %q not found", c.HasKey)
+		}
+		result.AddDetailf("%shas key: %s", prefix, c.HasKey)
+	}
+
+	if c.Key != "" {
+		value := jsonpath.Get(doc, c.Key)
+		if !value.Exists() {
+			return fmt.Errorf("key %q not found", c.Key)
+		}
+
+		valueStr := value.String()
+
+		if c.Exact != nil && valueStr != *c.Exact {
+			return fmt.Errorf("value %q does not equal %q", valueStr, *c.Exact)
+		}
+
+		if c.Match != "" {
+			re, err := check.CompileRegex(c.Match)
+			if err != nil {
+				return fmt.Errorf("invalid regex pattern: %w", err)
+			}
+			if !re.MatchString(valueStr) {
+				return fmt.Errorf("value %q does not match pattern %q", valueStr, c.Match)
+			}
+		}
+
+		result.AddDetailf("%skey %s: %s", prefix, c.Key, valueStr)
+	}
+	return nil
+}
+
+// decodeAll parses every document in content and returns each as JSON, which
+// is what jsonpath queries.
+func decodeAll(content []byte) ([]string, error) {
+	var docs []string
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var v any
+		err := decoder.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(toJSON(v))
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(data))
+	}
+}
+
+// toJSON converts a decoded YAML value to one encoding/json can marshal the
+// way the YAML reads: mapping keys that are not strings become strings, and
+// timestamps and infinities stay as they were written rather than failing or
+// turning into something else.
+func toJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = toJSON(value)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = toJSON(value)
+		}
+		return m
+	case []any:
+		for i, value := range v {
+			v[i] = toJSON(value)
+		}
+		return v
+	case time.Time:
+		if v.Equal(v.Truncate(24*time.Hour)) && v.Location() == time.UTC {
+			return v.Format(time.DateOnly)
+		}
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return ".inf"
+		case math.IsInf(v, -1):
+			return "-.inf"
+		case math.IsNaN(v):
+			return ".nan"
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,96 @@
+package yamlcheck
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/vertti/preflight/pkg/check"
+	"github.com/vertti/preflight/pkg/testutil"
+)
+
+type mockFS struct {
+	Content []byte
+	Err     error
+}
+
+func (m *mockFS) ReadFile(string) ([]byte, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.Content, nil
+}
+
+func fs(content string) *mockFS { return &mockFS{Content: []byte(content)} }
+
+const manifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 3
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+`
+
+func TestYAMLCheck_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		check      Check
+		wantStatus check.Status
+		wantDetail string
+	}{
+		// Syntax validation
+		{"valid mapping", Check{File: "values.yaml", FS: fs("name: test\n")}, check.StatusOK, "syntax: valid"},
+		{"valid sequence", Check{File: "list.yaml", FS: fs("- 1\n- 2\n")}, check.StatusOK, "syntax: valid"},
+		{"invalid YAML", Check{File: "bad.yaml", FS: fs("name: [unclosed\n")}, check.StatusFail, "invalid YAML: yaml: line 1"},
+		{"bad indentation", Check{File: "bad.yaml", FS: fs("a:\n  b: 1\n c: 2\n")}, check.StatusFail, "invalid YAML"},
+		{"duplicate key", Check{File: "bad.yaml", FS: fs("a: 1\na: 2\n")}, check.StatusFail, `mapping key "a" already defined`},
+		{"empty file valid", Check{File: "empty.yaml", FS: fs("")}, check.StatusOK, "syntax: valid (0 documents)"},
+		{"multiple documents", Check{File: "k8s.yaml", FS: fs(manifests)}, check.StatusOK, "syntax: valid (2 documents)"},
+		{"invalid later document", Check{File: "k8s.yaml", FS: fs(manifests + "---\nkind: [\n")}, check.StatusFail, "invalid YAML"},
+
+		// --has-key, --key, --exact and --match, as for JSON
+		{"has-key nested exists", Check{File: "f.yaml", HasKey: "database.host", FS: fs("database:\n  host: localhost\n")}, check.StatusOK, "has key: database.host"},
+		{"has-key missing", Check{File: "f.yaml", HasKey: "database.port", FS: fs("database:\n  host: localhost\n")}, check.StatusFail, `key "database.port" not found`},
+		{"has-key array index", Check{File: "f.yaml", HasKey: "hosts.1", FS: fs("hosts: [a, b]\n")}, check.StatusOK, "has key: hosts.1"},
+		{"key exact match", Check{File: "f.yaml", Key: "env", Exact: testutil.Ptr("production"), FS: fs("env: production\n")}, check.StatusOK, "key env: production"},
+		{"key exact mismatch", Check{File: "f.yaml", Key: "env", Exact: testutil.Ptr("production"), FS: fs("env: development\n")}, check.StatusFail, `value "development" does not equal "production"`},
+		{"key match pattern", Check{File: "f.yaml", Key: "image.tag", Match: `^1\.`, FS: fs("image:\n  tag: \"1.2.3\"\n")}, check.StatusOK, "key image.tag: 1.2.3"},
+		{"key match fails", Check{File: "f.yaml", Key: "image.tag", Match: `^1\.`, FS: fs("image:\n  tag: latest\n")}, check.StatusFail, "does not match pattern"},
+		{"key match invalid regex", Check{File: "f.yaml", Key: "v", Match: `[invalid`, FS: fs("v: 1\n")}, check.StatusFail, "invalid regex pattern"},
+		{"key exact empty string", Check{File: "f.yaml", Key: "name", Exact: testutil.Ptr(""), FS: fs("name: \"\"\n")}, check.StatusOK, "key name: "},
+
+		// Scalars read as they are written
+		{"key exact number", Check{File: "f.yaml", Key: "port", Exact: testutil.Ptr("8080"), FS: fs("port: 8080\n")}, check.StatusOK, "key port: 8080"},
+		{"key exact boolean", Check{File: "f.yaml", Key: "enabled", Exact: testutil.Ptr("true"), FS: fs("enabled: true\n")}, check.StatusOK, "key enabled: true"},
+		{"key exact null", Check{File: "f.yaml", Key: "value", Exact: testutil.Ptr("null"), FS: fs("value: ~\n")}, check.StatusOK, "key value: null"},
+		{"key exact date", Check{File: "f.yaml", Key: "since", Exact: testutil.Ptr("2024-01-02"), FS: fs("since: 2024-01-02\n")}, check.StatusOK, "key since: 2024-01-02"},
+		{"key exact infinity", Check{File: "f.yaml", Key: "limit", Exact: testutil.Ptr(".inf"), FS: fs("limit: .inf\n")}, check.StatusOK, "key limit: .inf"},
+		{"non-string mapping key", Check{File: "f.yaml", Key: "codes.404", Exact: testutil.Ptr("not found"), FS: fs("codes:\n  404: not found\n")}, check.StatusOK, "key codes.404: not found"},
+		{"anchors and merge keys", Check{File: "f.yaml", Key: "prod.region", Exact: testutil.Ptr("eu"), FS: fs("base: &base\n  region: eu\nprod:\n  <<: *base\n")}, check.StatusOK, "key prod.region: eu"},
+
+		// Multi-document streams
+		{"keys need a document chosen", Check{File: "k8s.yaml", HasKey: "kind", FS: fs(manifests)}, check.StatusFail, "file has 2 documents: choose one with --document"},
+		{"document chosen", Check{File: "k8s.yaml", Key: "spec.replicas", Exact: testutil.Ptr("3"), Document: testutil.Ptr(0), FS: fs(manifests)}, check.StatusOK, "document 0: key spec.replicas: 3"},
+		{"document chosen lacks key", Check{File: "k8s.yaml", HasKey: "spec.replicas", Document: testutil.Ptr(1), FS: fs(manifests)}, check.StatusFail, `document 1: key "spec.replicas" not found`},
+		{"document out of range", Check{File: "k8s.yaml", Document: testutil.Ptr(2), FS: fs(manifests)}, check.StatusFail, "document 2 not found: file has 2 documents"},
+		{"every document has key", Check{File: "k8s.yaml", HasKey: "metadata.name", AllDocuments: true, FS: fs(manifests)}, check.StatusOK, "document 1: has key: metadata.name"},
+		{"one document lacks key", Check{File: "k8s.yaml", HasKey: "spec", AllDocuments: true, FS: fs(manifests)}, check.StatusFail, `document 1: key "spec" not found`},
+		{"keys in an empty stream", Check{File: "empty.yaml", HasKey: "kind", FS: fs("")}, check.StatusFail, "file has no documents"},
+		{"single document needs no choice", Check{File: "f.yaml", HasKey: "kind", AllDocuments: true, FS: fs("kind: Pod\n")}, check.StatusOK, "has key: kind"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.check.Run()
+			assert.Equal(t, tt.wantStatus, result.Status, "details: %v", result.Details)
+			if tt.wantDetail != "" {
+				assert.True(t, testutil.ContainsDetail(result.Details, tt.wantDetail), "details %v should contain %q", result.Details, tt.wantDetail)
+			}
+		})
+	}
+}